@@ -0,0 +1,36 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for the custom media elements of AMP documents.
+
+package microformats
+
+import "golang.org/x/net/html"
+
+// AMP pages use custom elements in place of the standard media elements,
+// such as <amp-img> for <img>.  u-* properties on these elements take their
+// value from the same attributes as the elements they replace: src for
+// images, and src or poster for audio and video.  Their URLs are also
+// resolved in the html of e-* properties.  Since they are not part of the
+// parsing specification, they are not used for implied photos, and AMP images
+// are not replaced with their alt text in text values.
+var (
+	// ampImages are the AMP elements that replace <img>.
+	ampImages = []string{"amp-img", "amp-anim"}
+
+	// ampMedia are the AMP elements that replace <audio> and <video>.
+	ampMedia = []string{"amp-audio", "amp-video"}
+)
+
+// isAMPElement returns whether node is one of the AMP custom elements names.
+func isAMPElement(node *html.Node, names []string) bool {
+	if node == nil || node.Type != html.ElementNode || node.DataAtom != 0 || node.Namespace != "" {
+		return false
+	}
+	for _, name := range names {
+		if node.Data == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Parse_AMP(t *testing.T) {
+	doc := `<!doctype html><html ⚡><body>
+	<article class="h-entry">
+		<h1 class="p-name">Hello</h1>
+		<amp-img class="u-photo" src="/photo.jpg" alt="A photo" width="800" height="600" layout="responsive"></amp-img>
+		<amp-anim class="u-photo" src="/animation.gif" width="400" height="300"></amp-anim>
+		<amp-video class="u-video" src="/video.mp4" poster="/poster.jpg" width="640" height="360"></amp-video>
+		<amp-video class="u-featured" poster="/poster.jpg" width="640" height="360"><source src="/video.webm"></amp-video>
+		<amp-audio class="u-audio" src="/audio.mp3"></amp-audio>
+		<div class="e-content"><p>Hi</p><amp-img src="/inline.jpg" alt="inline" width="1" height="1"></amp-img></div>
+		<amp-carousel class="u-x-carousel" src="/ignored">carousel</amp-carousel>
+	</article></body></html>`
+
+	want := map[string][]any{
+		"name": {"Hello"},
+		"photo": {
+			map[string]string{"value": "http://example.com/photo.jpg", "alt": "A photo"},
+			"http://example.com/animation.gif",
+		},
+		"video":    {"http://example.com/video.mp4"},
+		"featured": {"http://example.com/poster.jpg"},
+		"audio":    {"http://example.com/audio.mp3"},
+		"content": {map[string]string{
+			"value": "Hi",
+			"html":  `<p>Hi</p><amp-img src="http://example.com/inline.jpg" alt="inline" width="1" height="1"></amp-img>`,
+		}},
+		"x-carousel": {"http://example.com/carousel"},
+	}
+	items := parseItems(doc)
+	if diff := cmp.Diff(want, items[0].Properties); diff != "" {
+		t.Errorf("Parse mismatch (-want +got):\n%s", diff)
+	}
+}
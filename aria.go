@@ -0,0 +1,186 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for converting WAI-ARIA feed and article roles
+// into microformats.
+
+package microformats
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// WithARIAFeed converts elements with the WAI-ARIA "feed" and "article" roles
+// into microformats, if the document contains no microformats of its own
+// (and no JSON-LD or microdata converted by WithJSONLDFallback or
+// WithMicrodataFallback).  This is not part of the
+// microformats2 parsing specification, and is intended for lenient readers
+// of sites that mark up their content for assistive technology rather than
+// with microformats.
+//
+// Each role="feed" element becomes an h-feed, and each role="article"
+// element becomes an h-entry, as a child of the enclosing h-feed or h-entry
+// if there is one, or else as a top-level item.  Properties are taken from:
+//
+//   - name: the accessible name of the element, from aria-labelledby or
+//     aria-label, or else (for articles) the text of its first heading
+//   - url: the first link in that heading
+//   - summary: the text of the elements referenced by aria-describedby
+//   - published: the datetime attribute of the first <time> element
+//   - content: the content of the article, like an e-content property
+//
+// No other properties are implied.
+func WithARIAFeed() Option {
+	return func(p *parser) {
+		p.ariaFeed = true
+	}
+}
+
+// WithAriaLabelFallback uses the aria-label attribute of an element as the
+// value of a p-* property on it when the element has no other value, such as
+// an icon-only link:
+//
+//	<a class="p-name" href="/" aria-label="Jane Doe"><svg>...</svg></a>
+//
+// The label is only used if the value from the parsing specification (from
+// the text content or attributes of the element) is empty, and
+// aria-labelledby is not followed.  This is not part of the microformats2
+// parsing specification, and is off by default.
+func WithAriaLabelFallback() Option {
+	return func(p *parser) {
+		p.ariaLabelFallback = true
+	}
+}
+
+// ariaItems returns the microformats converted from the ARIA feeds and
+// articles in doc, in document order.
+func (p *parser) ariaItems(doc *html.Node) []*Microformat {
+	var items []*Microformat
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		items = append(items, p.ariaNode(c)...)
+	}
+	return items
+}
+
+// ariaNode returns the microformats converted from the tree rooted at node.
+func (p *parser) ariaNode(node *html.Node) []*Microformat {
+	if node.Type != html.ElementNode || isAtom(node, atom.Template, atom.Script, atom.Style) {
+		return nil
+	}
+
+	var m *Microformat
+	switch {
+	case hasRole(node, "feed"):
+		m = &Microformat{Type: []string{"h-feed"}, Properties: make(map[string][]any)}
+		if name := p.ariaLabel(node); name != "" {
+			m.Properties["name"] = []any{name}
+		}
+	case hasRole(node, "article"):
+		m = p.ariaEntry(node)
+	}
+
+	var children []*Microformat
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, p.ariaNode(c)...)
+	}
+	if m == nil {
+		return children
+	}
+	m.ID = getAttr(node, "id")
+	m.Children = children
+	return []*Microformat{m}
+}
+
+// ariaEntry converts node, an element with the article role, into an
+// h-entry.
+func (p *parser) ariaEntry(node *html.Node) *Microformat {
+	m := &Microformat{Type: []string{"h-entry"}, Properties: make(map[string][]any)}
+
+	heading := findARIADescendant(node, func(n *html.Node) bool {
+		return isAtom(n, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6)
+	})
+	name := p.ariaLabel(node)
+	if name == "" && heading != nil {
+		name = strings.TrimSpace(getTextContent(heading, imageAltValue))
+	}
+	if name != "" {
+		m.Properties["name"] = []any{name}
+	}
+	if heading != nil {
+		link := findARIADescendant(heading, func(n *html.Node) bool {
+			return isAtom(n, atom.A) && hasAttr(n, "href")
+		})
+		if link != nil {
+			m.Properties["url"] = []any{expandURL(getAttr(link, "href"), p.base)}
+		}
+	}
+	if summary := p.ariaReferencedText(getAttr(node, "aria-describedby")); summary != "" {
+		m.Properties["summary"] = []any{summary}
+	}
+	published := findARIADescendant(node, func(n *html.Node) bool {
+		return isAtom(n, atom.Time) && hasAttr(n, "datetime")
+	})
+	if published != nil {
+		m.Properties["published"] = []any{getAttr(published, "datetime")}
+	}
+	m.Properties["content"] = []any{map[string]string{
+		"value": strings.TrimSpace(textContent(node, p.imageAltSrcValue, p.skipFunc())),
+		"html":  p.innerHTML(node),
+	}}
+	return m
+}
+
+// ariaLabel returns the accessible name of node from its aria-labelledby or
+// aria-label attributes, or an empty string if it has neither.
+func (p *parser) ariaLabel(node *html.Node) string {
+	if label := p.ariaReferencedText(getAttr(node, "aria-labelledby")); label != "" {
+		return label
+	}
+	return strings.TrimSpace(getAttr(node, "aria-label"))
+}
+
+// ariaReferencedText returns the text of the elements whose ids are listed
+// in refs, separated by spaces.
+func (p *parser) ariaReferencedText(refs string) string {
+	var text []string
+	for _, id := range splitTokens(refs) {
+		if ref := findNodeByID(p.root, id); ref != nil {
+			if s := strings.TrimSpace(getTextContent(ref, imageAltValue)); s != "" {
+				text = append(text, s)
+			}
+		}
+	}
+	return strings.Join(text, " ")
+}
+
+// hasRole returns whether role is one of the WAI-ARIA roles listed in the
+// role attribute of node.
+func hasRole(node *html.Node, role string) bool {
+	for _, r := range splitTokens(getAttr(node, "role")) {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// findARIADescendant returns the first descendant of node, in document
+// order, for which match returns true, ignoring any nested feeds and
+// articles.
+func findARIADescendant(node *html.Node, match func(*html.Node) bool) *html.Node {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || hasRole(c, "feed") || hasRole(c, "article") {
+			continue
+		}
+		if match(c) {
+			return c
+		}
+		if n := findARIADescendant(c, match); n != nil {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_WithARIAFeed(t *testing.T) {
+	doc := `<main>
+		<h2 id="feed-title">Latest posts</h2>
+		<section role="feed" aria-labelledby="feed-title" aria-busy="false">
+			<article role="article" id="one" aria-describedby="one-desc">
+				<h3><a href="/one">First post</a></h3>
+				<p id="one-desc">About the first post.</p>
+				<time datetime="2024-01-02">January 2</time>
+				<div role="article" aria-label="A comment"><p>Nice <b>post</b></p></div>
+			</article>
+			<div role="article" aria-label="Second post"><p>Hello</p></div>
+		</section>
+	</main>`
+
+	want := []*Microformat{{
+		Type:       []string{"h-feed"},
+		Properties: map[string][]any{"name": {"Latest posts"}},
+		Children: []*Microformat{
+			{
+				ID:   "one",
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"name":      {"First post"},
+					"url":       {"http://example.com/one"},
+					"summary":   {"About the first post."},
+					"published": {"2024-01-02"},
+					"content": {map[string]string{
+						"value": "First post\n\t\t\t\tAbout the first post.\n\t\t\t\tJanuary 2\n\t\t\t\tNice post",
+						"html": `<h3><a href="http://example.com/one">First post</a></h3>
+				<p id="one-desc">About the first post.</p>
+				<time datetime="2024-01-02">January 2</time>
+				<div role="article" aria-label="A comment"><p>Nice <b>post</b></p></div>`,
+					}},
+				},
+				Children: []*Microformat{{
+					Type: []string{"h-entry"},
+					Properties: map[string][]any{
+						"name":    {"A comment"},
+						"content": {map[string]string{"value": "Nice post", "html": "<p>Nice <b>post</b></p>"}},
+					},
+				}},
+			},
+			{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"name":    {"Second post"},
+					"content": {map[string]string{"value": "Hello", "html": "<p>Hello</p>"}},
+				},
+			},
+		},
+	}}
+
+	if items := parseItems(doc); len(items) != 0 {
+		t.Errorf("Parse returned %d items without WithARIAFeed, want 0", len(items))
+	}
+	if diff := cmp.Diff(want, parseItemsWith(doc, WithARIAFeed()), ignoreParseState); diff != "" {
+		t.Errorf("Parse with WithARIAFeed mismatch (-want +got):\n%s", diff)
+	}
+
+	// microformats on the page take precedence
+	mf := `<div class="h-card">Jane</div>` + doc
+	items := parseItemsWith(mf, WithARIAFeed())
+	if len(items) != 1 || !items[0].hasType("h-card") {
+		t.Errorf("Parse with WithARIAFeed and microformats returned %v, want only the h-card", items)
+	}
+}
+
+func Test_WithAriaLabelFallback(t *testing.T) {
+	tests := []struct {
+		html string
+		opts []Option
+		want map[string][]any
+	}{
+		{
+			`<div class="h-card"><a class="p-name u-url" href="/" aria-label="Jane Doe"><svg><path d="M0 0"/></svg></a></div>`,
+			nil,
+			map[string][]any{"name": {""}, "url": {"http://example.com/"}},
+		},
+		{
+			`<div class="h-card"><a class="p-name u-url" href="/" aria-label=" Jane Doe "><svg><path d="M0 0"/></svg></a></div>`,
+			[]Option{WithAriaLabelFallback()},
+			map[string][]any{"name": {"Jane Doe"}, "url": {"http://example.com/"}},
+		},
+		{
+			// empty attribute values are replaced too
+			`<div class="h-card"><img class="p-name" src="/icon.png" alt="" aria-label="Jane"></div>`,
+			[]Option{WithAriaLabelFallback()},
+			map[string][]any{"name": {"Jane"}, "photo": {"http://example.com/icon.png"}},
+		},
+		{
+			// text takes precedence over the label
+			`<div class="h-card"><span class="p-name" aria-label="Label">Jane</span></div>`,
+			[]Option{WithAriaLabelFallback()},
+			map[string][]any{"name": {"Jane"}},
+		},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(tt.html, tt.opts...)
+		if diff := cmp.Diff(tt.want, items[0].Properties); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
@@ -199,6 +199,27 @@ var (
 	}
 )
 
+// WithCaseInsensitiveV1 matches microformats v1 root and property class
+// names case insensitively, so that legacy markup such as class="vCard" or
+// class="HCARD" is parsed as if it were written in lowercase.  HTML class
+// names are case sensitive, and the parsing specification matches v1 class
+// names exactly, but some older parsers did not.  Microformats2 class names
+// are always matched exactly.
+func WithCaseInsensitiveV1() Option {
+	return func(p *parser) {
+		p.caseInsensitiveV1 = true
+	}
+}
+
+// lowercaseAll returns a copy of s with each string lowercased.
+func lowercaseAll(s []string) []string {
+	lower := make([]string, len(s))
+	for i, v := range s {
+		lower[i] = strings.ToLower(v)
+	}
+	return lower
+}
+
 // backcompatRootClasses returns the v2 root classes for the backcompat v1
 // roots in the provided classes. parent identifies the parent microformat, if
 // present, since some root mappings are context-specific.
@@ -302,15 +323,17 @@ func (p *parser) backcompatIncludeRefs(node *html.Node) (refs []string, replace
 	}
 
 	if node.DataAtom == atom.Td {
-		refs = append(refs, strings.Fields(getAttr(node, "headers"))...)
+		refs = append(refs, splitTokens(getAttr(node, "headers"))...)
 	}
-	refs = append(refs, strings.Fields(getAttr(node, "itemref"))...)
+	refs = append(refs, splitTokens(getAttr(node, "itemref"))...)
 
 	return refs, false
 }
 
 // backcompatIncludeNode includes referenced notes following the include
-// pattern.
+// pattern.  If several elements have the referenced id, which is invalid
+// HTML, the first one in document order is included and a warning is
+// recorded.
 //
 // see backcompatIncludeRefs for information on refs and replace parameters.
 func (p *parser) backcompatIncludeNode(node *html.Node, refs []string, replace bool) *html.Node {
@@ -319,6 +342,7 @@ func (p *parser) backcompatIncludeNode(node *html.Node, refs []string, replace b
 	}
 
 	for _, ref := range refs {
+		p.checkDuplicateID(node, ref)
 		if n := findNodeByID(p.root, ref); n != nil {
 			if node != n && !isAncestorNode(node, n) {
 				if replace {
@@ -332,6 +356,32 @@ func (p *parser) backcompatIncludeNode(node *html.Node, refs []string, replace b
 	return node
 }
 
+// checkDuplicateID records a warning, once per id, if more than one element
+// in the document has the id ref, referenced by node.  Ids are counted the
+// first time this is called, before any included nodes are added to the
+// document.
+func (p *parser) checkDuplicateID(node *html.Node, ref string) {
+	if p.idCounts == nil {
+		p.idCounts = make(map[string]int)
+		countIDs(p.root, p.idCounts)
+	}
+	if n := p.idCounts[ref]; n > 1 {
+		p.warn(node, "%d elements have id %q referenced by the include pattern; using the first", n, ref)
+		p.idCounts[ref] = 1 // only warn once
+	}
+}
+
+// countIDs adds the number of elements with each id in the tree rooted at
+// node to counts.
+func countIDs(node *html.Node, counts map[string]int) {
+	if id := getAttr(node, "id"); id != "" {
+		counts[id]++
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		countIDs(c, counts)
+	}
+}
+
 // findNodeByID searches node and its children, returning the node with the
 // specified id value.
 func findNodeByID(node *html.Node, id string) *html.Node {
@@ -364,3 +414,43 @@ func cloneNode(node *html.Node) *html.Node {
 	clone.NextSibling = nil
 	return &clone
 }
+
+// addTypeLabels records the vCard type labels of node, the element of the
+// last value of the property name of the current microformat, in its
+// TypeLabels.  Labels are the text of descendants of node with the "type"
+// class, or their title if they are <abbr> elements, lowercased.
+func (p *parser) addTypeLabels(name string, node *html.Node) {
+	var labels []string
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || hasMatchingClass(c, rootClassNames) {
+				continue
+			}
+			if containsString(getClasses(c), "type") {
+				label := getTextContent(c, nil)
+				if isAtom(c, atom.Abbr) && hasAttr(c, "title") {
+					label = getAttr(c, "title")
+				}
+				if label = strings.ToLower(strings.TrimSpace(label)); label != "" {
+					labels = append(labels, label)
+				}
+				continue
+			}
+			find(c)
+		}
+	}
+	find(node)
+	if len(labels) == 0 {
+		return
+	}
+
+	if p.curItem.TypeLabels == nil {
+		p.curItem.TypeLabels = make(map[string][][]string)
+	}
+	all := p.curItem.TypeLabels[name]
+	for len(all) < len(p.curItem.Properties[name])-1 {
+		all = append(all, nil)
+	}
+	p.curItem.TypeLabels[name] = append(all, labels)
+}
@@ -5,6 +5,7 @@ package microformats
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -69,6 +70,52 @@ func Test_BackcompatPropertyClasses(t *testing.T) {
 	}
 }
 
+func Test_WithCaseInsensitiveV1(t *testing.T) {
+	doc := `<div class="vCard"><span class="FN">Jane</span><a class="Url" href="/jane">home</a></div>
+		<div class="HENTRY"><span class="entry-Title">Post</span></div>
+		<div class="h-card"><span class="P-name">v2 is exact</span></div>`
+
+	tests := []struct {
+		opts []Option
+		want []*Microformat
+	}{
+		{nil, []*Microformat{{
+			Type:       []string{"h-card"},
+			Properties: map[string][]any{"name": {"v2 is exact"}},
+		}}},
+		{[]Option{WithCaseInsensitiveV1()}, []*Microformat{
+			{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"url":  {"http://example.com/jane"},
+				},
+			},
+			{
+				Type:       []string{"h-entry"},
+				Properties: map[string][]any{"name": {"Post"}},
+			},
+			{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"v2 is exact"}},
+			},
+		}},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		if diff := cmp.Diff(tt.want, items, ignoreParseState); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+
+		p := NewParser(nil, tt.opts...)
+		_, _ = p.Write([]byte(doc))
+		if got, want := len(p.Items()), len(tt.want); got != want {
+			t.Errorf("Parser with %d options returned %d incremental items, want %d", len(tt.opts), got, want)
+		}
+	}
+}
+
 func Test_BackcompatURLCategory(t *testing.T) {
 	tests := []struct {
 		url  string
@@ -157,6 +204,27 @@ func Test_BackcompatIncludeNode(t *testing.T) {
 	}
 }
 
+func Test_Parse_IncludeDuplicateIDs(t *testing.T) {
+	doc := `<div class="vcard"><span class="fn">Jane</span>
+		<a class="include" href="#org"></a>
+	</div>
+	<div class="vcard"><span class="fn">John</span><object class="include" data="#org"></object></div>
+	<div id="org"><span class="org">First</span></div>
+	<div id="org"><span class="org">Second</span></div>
+	<div id="unique"></div>`
+
+	data, warnings := ParseWithWarnings(strings.NewReader(doc), nil)
+	for _, item := range data.Items {
+		if got, want := item.Properties["org"], []any{"First"}; !cmp.Equal(got, want) {
+			t.Errorf("Parse returned org %v for %v, want %v", got, item.Properties["name"], want)
+		}
+	}
+	want := []Warning{{Message: `2 elements have id "org" referenced by the include pattern; using the first`}}
+	if diff := cmp.Diff(want, warnings); diff != "" {
+		t.Errorf("ParseWithWarnings warnings mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func Test_IsAncestorNode(t *testing.T) {
 	a1, _ := parseNode("<p><b></b></p>")
 	a2 := a1.FirstChild
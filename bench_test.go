@@ -0,0 +1,144 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// relHeavyDocument returns an HTML document with n navigation links without
+// rel values, n links with rel values, and a single h-card.
+func relHeavyDocument(n int) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><link rel="stylesheet" href="/style.css"><link rel="me" href="https://social.example/@jane"></head><body><nav><ul>`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<li><a href="/page/%d" class="nav-link">Page %d</a></li>`, i, i)
+	}
+	b.WriteString(`</ul></nav><div class="h-card"><a class="p-name u-url" rel="author" href="/jane">Jane</a></div><footer>`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<a href="/footer/%d" rel="nofollow noopener">Footer %d</a> `, i, i)
+	}
+	b.WriteString(`</footer></body></html>`)
+	return b.String()
+}
+
+func BenchmarkParseRelHeavy(b *testing.B) {
+	doc := relHeavyDocument(500)
+	base, _ := url.Parse("http://example.com/")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Parse(strings.NewReader(doc), base)
+	}
+}
+
+func BenchmarkParseNodeRelHeavy(b *testing.B) {
+	doc, err := html.Parse(strings.NewReader(relHeavyDocument(500)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	base, _ := url.Parse("http://example.com/")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ParseNode(doc, base)
+	}
+}
+
+// feedDocument returns an HTML document with an h-feed of n h-entries, each
+// with typical properties and content.
+func feedDocument(n int) string {
+	var b strings.Builder
+	b.WriteString(`<html><body><div class="h-feed"><h1 class="p-name">Posts</h1>`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<article class="h-entry">
+			<h2 class="p-name"><a class="u-url" href="/post/%d">Post %d</a></h2>
+			<a class="p-author h-card" href="/jane"><img class="u-photo" src="/jane.jpg" alt="">Jane Doe</a>
+			<time class="dt-published" datetime="2024-01-02T03:04:05Z">January 2</time>
+			<div class="e-content"><p>Some <em>text</em> for post %d, with <a href="/link/%d">a link</a>.</p>
+			<p>Another paragraph, with some more text in it.</p></div>
+			<a class="p-category" href="/tag/go">go</a> <a class="p-category" href="/tag/mf">mf</a>
+		</article>`, i, i, i, i)
+	}
+	b.WriteString(`</div></body></html>`)
+	return b.String()
+}
+
+func BenchmarkParseNodeFeed(b *testing.B) {
+	doc, err := html.Parse(strings.NewReader(feedDocument(100)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	base, _ := url.Parse("http://example.com/")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ParseNode(doc, base)
+	}
+}
+
+// headHeavyDocument returns an HTML document with n <meta> and n <link>
+// elements in its head, and a single h-card in its body.
+func headHeavyDocument(n int) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><title>Jane</title>`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<meta name="meta-%d" content="value %d"><link rel="preload" as="script" href="/js/%d.js">`, i, i, i)
+	}
+	b.WriteString(`</head><body><div class="h-card"><a class="p-name u-url" href="/jane">Jane</a></div></body></html>`)
+	return b.String()
+}
+
+func BenchmarkParseNodeHeadHeavy(b *testing.B) {
+	doc, err := html.Parse(strings.NewReader(headHeavyDocument(500)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	base, _ := url.Parse("http://example.com/")
+	for _, bb := range []struct {
+		name string
+		opts []Option
+	}{
+		{"Head", nil},
+		{"WithoutHead", []Option{WithoutHead()}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ParseNode(doc, base, bb.opts...)
+			}
+		})
+	}
+}
+
+// nestedDocument returns an HTML document with a thread of n replies, each
+// an h-cite nested in the comment property of the one before.
+func nestedDocument(n int) string {
+	var b strings.Builder
+	b.WriteString(`<html><body><div class="h-entry"><span class="p-name">Post</span>`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<div class="p-comment h-cite"><a class="p-author h-card" href="/user/%d">User %d</a><p class="p-content">Reply %d</p>`, i, i, i)
+	}
+	b.WriteString(strings.Repeat(`</div>`, n))
+	b.WriteString(`</div></body></html>`)
+	return b.String()
+}
+
+func BenchmarkParseNodeNested(b *testing.B) {
+	doc, err := html.Parse(strings.NewReader(nestedDocument(1000)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	base, _ := url.Parse("http://example.com/")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ParseNode(doc, base)
+	}
+}
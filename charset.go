@@ -0,0 +1,120 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for detecting the character encoding of
+// documents.
+
+package microformats
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// prescanLength is the number of bytes of a document scanned for a <meta>
+// element declaring its character encoding, as specified by HTML.
+const prescanLength = 1024
+
+// WithCharsetDetection decodes documents that are not encoded as UTF-8, such
+// as those encoded as windows-1252 or Shift_JIS, by detecting their character
+// encoding.  Documents are otherwise assumed to be UTF-8.  The encoding is
+// determined by, in order of precedence:
+//
+//   - a byte order mark
+//   - the charset of the Content-Type header, for documents fetched with
+//     ParseURL
+//   - a <meta charset> or <meta http-equiv="Content-Type"> element within
+//     the first 1024 bytes of the document, as scanned by browsers.  Elements
+//     declared later in the document are ignored.
+//
+// If no encoding is declared, or the declared encoding is unknown, UTF-8 is
+// used.  Unlike browsers, the encoding is never guessed from the content.
+// ParseURL always detects the encoding of documents.  This option has no
+// effect on Parser.
+func WithCharsetDetection() Option {
+	return func(p *parser) {
+		p.charsetDetection = true
+	}
+}
+
+// withContentType detects the character encoding of documents, as described
+// in WithCharsetDetection, using the Content-Type header contentType.
+func withContentType(contentType string) Option {
+	return func(p *parser) {
+		p.charsetDetection = true
+		p.contentType = contentType
+	}
+}
+
+// decodeCharset returns a reader that decodes r to UTF-8 from its detected
+// character encoding, as described in WithCharsetDetection.
+func decodeCharset(r io.Reader, contentType string) io.Reader {
+	br := bufio.NewReaderSize(r, prescanLength)
+	prefix, _ := br.Peek(prescanLength)
+
+	e, _, certain := charset.DetermineEncoding(prefix, contentType)
+	if !certain {
+		e = nil
+		if label := prescanCharset(prefix); label != "" {
+			var name string
+			e, name = charset.Lookup(label)
+			if strings.HasPrefix(name, "utf-16") {
+				// a document declaring itself UTF-16 in ASCII is not
+				e = nil
+			}
+		}
+	}
+	if e == nil || e == encoding.Nop {
+		return br
+	}
+	return transform.NewReader(br, e.NewDecoder())
+}
+
+// prescanCharset returns the character encoding label declared by the first
+// <meta> element in b with a charset attribute, or an http-equiv attribute of
+// "Content-Type" and a content attribute with a charset.  An empty string is
+// returned if there is none.
+func prescanCharset(b []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(b))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) != "meta" || !hasAttr {
+				continue
+			}
+			var cs, content string
+			var httpEquiv bool
+			for more := true; more; {
+				var key, val []byte
+				key, val, more = z.TagAttr()
+				switch string(key) {
+				case "charset":
+					cs = strings.TrimSpace(string(val))
+				case "http-equiv":
+					httpEquiv = strings.EqualFold(strings.TrimSpace(string(val)), "content-type")
+				case "content":
+					content = string(val)
+				}
+			}
+			if cs != "" {
+				return cs
+			}
+			if httpEquiv {
+				if _, params, err := mime.ParseMediaType(content); err == nil && params["charset"] != "" {
+					return params["charset"]
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_WithCharsetDetection(t *testing.T) {
+	// "José" encoded as windows-1252 and as UTF-8
+	latin1 := `<div class="h-card">Jos` + "\xe9" + `</div>`
+	utf8 := `<div class="h-card">José</div>`
+	padding := "<!--" + strings.Repeat("x", prescanLength) + "-->"
+
+	tests := []struct {
+		description string
+		html        string
+		opts        []Option
+		want        string
+	}{
+		{"utf-8 without detection", utf8, nil, "José"},
+		{"utf-8 without declaration", utf8, []Option{WithCharsetDetection()}, "José"},
+		{"meta charset ignored without detection", `<meta charset="windows-1252">` + latin1, nil, "Jos\xe9"},
+		{"meta charset", `<meta charset="windows-1252">` + latin1, []Option{WithCharsetDetection()}, "José"},
+		{"meta charset label", `<meta charset=" Latin1 ">` + latin1, []Option{WithCharsetDetection()}, "José"},
+		{
+			"http-equiv",
+			`<meta http-equiv="Content-Type" content="text/html; charset=ISO-8859-1">` + latin1,
+			[]Option{WithCharsetDetection()},
+			"José",
+		},
+		{"http-equiv without charset", `<meta http-equiv="Content-Type" content="text/html">` + utf8, []Option{WithCharsetDetection()}, "José"},
+		{"late meta charset", padding + `<meta charset="windows-1252">` + utf8, []Option{WithCharsetDetection()}, "José"},
+		{"unknown charset", `<meta charset="x-unknown">` + utf8, []Option{WithCharsetDetection()}, "José"},
+		{"utf-16 declared in ascii", `<meta charset="utf-16">` + utf8, []Option{WithCharsetDetection()}, "José"},
+		{"byte order mark", "\xef\xbb\xbf" + `<meta charset="windows-1252">` + utf8, []Option{WithCharsetDetection()}, "José"},
+		{"content type", `<meta charset="utf-8">` + latin1, []Option{withContentType("text/html; charset=windows-1252")}, "José"},
+		{"content type without charset", `<meta charset="windows-1252">` + latin1, []Option{withContentType("text/html")}, "José"},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(tt.html, tt.opts...)
+		if len(items) != 1 {
+			t.Errorf("Parse %s returned %d items, want 1", tt.description, len(items))
+			continue
+		}
+		if got := firstString(items[0], "name"); got != tt.want {
+			t.Errorf("Parse %s returned name %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
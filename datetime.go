@@ -4,6 +4,7 @@
 package microformats
 
 import (
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -107,6 +108,20 @@ var (
 	// converted to uppercase before comparison.  Contains two capture
 	// groups, one for each letter matched.
 	reAMPM = regexp.MustCompile(`(A|P)\.?(M)\.?$`)
+
+	// regex to match ISO 8601 durations of weeks, days, hours, minutes, and
+	// seconds, such as "P1DT2H30M".  This assumes that the string has been
+	// converted to uppercase.  Contains a capture group for the number of
+	// each unit, in the order of durationUnits.
+	reDuration = regexp.MustCompile(`^P(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?` +
+		`(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+)
+
+// durationUnits are the units of the capture groups of reDuration, and
+// durationDesignators the letters that designate them.
+var (
+	durationUnits       = []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	durationDesignators = "WDHMS"
 )
 
 // various date time format strings
@@ -153,11 +168,16 @@ var (
 	}
 )
 
-func (d *datetime) Parse(s string) {
-	// normalize datetime value
+// normalizeDatetime returns the datetime value s in the form expected by the
+// datetime formats.
+func normalizeDatetime(s string) string {
 	s = strings.ToUpper(s)
 	s = strings.Replace(s, " ", "T", 1)
-	s = reAMPM.ReplaceAllString(s, "$1$2")
+	return reAMPM.ReplaceAllString(s, "$1$2")
+}
+
+func (d *datetime) Parse(s string) {
+	s = normalizeDatetime(s)
 
 	// datetime formats
 	for _, f := range datetimeFormats {
@@ -206,7 +226,7 @@ func (d *datetime) Parse(s string) {
 }
 
 func getDateTimeValue(node *html.Node) *string {
-	values := parseValueClassPattern(node, true)
+	values := parseValueClassPattern(node, vcpDateTime)
 	var d datetime
 	for _, v := range values {
 		d.Parse(strings.TrimSpace(v))
@@ -217,22 +237,70 @@ func getDateTimeValue(node *html.Node) *string {
 	return nil
 }
 
+// getDateTimeTitle returns the datetime in the title attribute of node, a
+// dt-* property element that has the value-title class itself, rather than on
+// a child as the value class pattern specifies, such as
+// <span class="dt-published value-title" title="2024-01-02T03:04:05Z">Jan 2</span>.
+// The value-title class has no other meaning on a property element, so
+// authors using it this way can only have intended the title to be its
+// value.  The datetime is normalized the same as with the value class
+// pattern.  If the title is not a datetime with a date, nil is returned.
+func getDateTimeTitle(node *html.Node) *string {
+	var d datetime
+	d.Parse(strings.TrimSpace(getAttr(node, "title")))
+	if value := d.String(); value != "" {
+		return &value
+	}
+	return nil
+}
+
+// WithUnixTimestamps converts dt-* property values that are Unix timestamps,
+// a number of seconds since 1970-01-01 00:00:00 UTC, into datetimes like
+// "2023-11-14 22:13:20Z", as are used by some machine-generated markup such
+// as <data class="dt-published" value="1700000000">.  Only values of more
+// than 8 digits are converted, so that years and compact dates like
+// "20240102" are not mistaken for timestamps.  This is not part of the
+// microformats2 parsing specification, and other values are left unchanged.
+func WithUnixTimestamps() Option {
+	return func(p *parser) {
+		p.unixTimestamps = true
+	}
+}
+
+// unixTimestamp returns s converted from a Unix timestamp to a datetime, as
+// described in WithUnixTimestamps, or s unchanged if it is not a timestamp.
+func unixTimestamp(s string) string {
+	digits := strings.TrimSpace(s)
+	if len(digits) <= 8 || strings.Trim(digits, "0123456789") != "" {
+		return s
+	}
+	secs, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return s
+	}
+	t := time.Unix(secs, 0).UTC()
+	if t.Year() > 9999 {
+		return s
+	}
+	return t.Format(formatDateTimeSeconds) + "Z"
+}
+
 // Process implied date for 'end' property.  This is technically part of the value class pattern
 // parsing rules, and at this point, we don't know if these were specified using VCP, but we
-// imply date all the same anyway.
+// imply date all the same anyway.  Any 'end' value that has a time but no date takes the date
+// of the first 'start' value that has one.
 func implyEndDate(item *Microformat) {
-	var startDate time.Time
+	var start datetime
 	for _, v := range item.Properties["start"] {
-		if start, ok := v.(string); ok {
-			var dt datetime
-			dt.Parse(start)
-			if dt.hasDate {
-				startDate = dt.t
+		if s, ok := v.(string); ok {
+			start = datetime{}
+			start.Parse(s)
+			if start.hasDate {
 				break
 			}
 		}
 	}
-	if startDate.IsZero() {
+	if !start.hasDate {
 		return
 	}
 
@@ -240,10 +308,157 @@ func implyEndDate(item *Microformat) {
 		if end, ok := v.(string); ok {
 			var dt datetime
 			dt.Parse(end)
-			if !dt.t.IsZero() && !dt.hasDate {
-				dt.setDate(startDate.Year(), startDate.Month(), startDate.Day())
+			if dt.hasTime && !dt.hasDate {
+				dt.setDate(start.t.Year(), start.t.Month(), start.t.Day())
 				item.Properties["end"][i] = dt.String()
 			}
 		}
 	}
 }
+
+// checkEventDates warns if the first end date of item, an h-event parsed from
+// node, is before its first start date.  Dates are only compared by time of
+// day if both include a time, and both or neither include a timezone.
+func (p *parser) checkEventDates(node *html.Node, item *Microformat) {
+	start, startValue := firstDatetime(item, "start")
+	end, endValue := firstDatetime(item, "end")
+	if startValue == "" || endValue == "" {
+		return
+	}
+	inverted := end.t.Before(start.t)
+	if !start.hasTime || !end.hasTime || start.hasTZ != end.hasTZ {
+		sy, sm, sd := start.t.Date()
+		ey, em, ed := end.t.Date()
+		inverted = time.Date(ey, em, ed, 0, 0, 0, 0, time.UTC).Before(time.Date(sy, sm, sd, 0, 0, 0, 0, time.UTC))
+	}
+	if inverted {
+		p.warn(node, "h-event end %q is before start %q", endValue, startValue)
+	}
+}
+
+// firstDatetime returns the first value of the property prop of item that is
+// a datetime with a date, both parsed and as a string.
+func firstDatetime(item *Microformat, prop string) (datetime, string) {
+	for _, v := range item.Properties[prop] {
+		if s, ok := v.(string); ok {
+			var dt datetime
+			dt.Parse(s)
+			if dt.hasDate {
+				return dt, s
+			}
+		}
+	}
+	return datetime{}, ""
+}
+
+// PublishedIn returns the first published property value of m, such as the
+// publish time of an h-entry, parsed as a time.  A value with no timezone
+// offset, such as "2024-01-02 15:04", is interpreted in loc, such as the
+// timezone of the site it was published on, while a value with an offset is
+// returned in that offset.  A value with only a date is midnight in loc.  If
+// loc is nil, UTC is used.
+//
+// If m has no published value with a date, PublishedIn returns false.
+func (m *Microformat) PublishedIn(loc *time.Location) (time.Time, bool) {
+	if m == nil {
+		return time.Time{}, false
+	}
+	return timeIn(m, "published", loc)
+}
+
+// hasOffset returns whether the datetime value s includes a timezone offset.
+// This can't be told from the parsed datetime, which is given a UTC offset
+// when it has a time but no offset, as the value class pattern does.
+func hasOffset(s string) bool {
+	s = normalizeDatetime(s)
+	for _, f := range datetimeFormats {
+		if _, err := time.Parse(f.format, s); err == nil {
+			return strings.Contains(f.format, "07")
+		}
+	}
+	return false
+}
+
+// timeIn returns the first value of prop in m with a date parsed as a time,
+// as described in PublishedIn.
+func timeIn(m *Microformat, prop string, loc *time.Location) (time.Time, bool) {
+	d, s := firstDatetime(m, prop)
+	if !d.hasDate {
+		return time.Time{}, false
+	}
+	if !hasOffset(s) {
+		if loc == nil {
+			loc = time.UTC
+		}
+		d.t = time.Date(d.t.Year(), d.t.Month(), d.t.Day(), d.t.Hour(), d.t.Minute(), d.t.Second(), 0, loc)
+	}
+	return d.t, true
+}
+
+// FormatDate returns the first value of the dt-* property prop of m with a
+// date, such as the start of an h-event, formatted for display with the time
+// layout, such as "Jan 2, 2006 3:04 PM".  Values in any of the datetime
+// formats supported by the parser are accepted.  A value with a timezone
+// offset is converted to loc, and a value without one is interpreted in loc,
+// as in PublishedIn.  If loc is nil, values with an offset keep it, and
+// values without one are UTC.  A value with only a date is midnight.
+//
+// If m has no value of prop with a date, FormatDate returns false.
+func (m *Microformat) FormatDate(prop, layout string, loc *time.Location) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	t, ok := timeIn(m, prop, loc)
+	if !ok {
+		return "", false
+	}
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return t.Format(layout), true
+}
+
+// GetDuration returns the first value of the property prop of m, such as the
+// duration of an h-recipe, parsed as an ISO 8601 duration.  Durations may
+// include weeks, days, hours, minutes, and seconds, such as "PT1H30M" or
+// "P1DT12H", and the last unit may have a decimal fraction, such as "PT1.5H".
+// Years and months are not supported, since their length varies.  Days are
+// always 24 hours long.  The property value itself is not modified.
+//
+// If m has no value for prop, or it is not a supported duration, GetDuration
+// returns false.
+func (m *Microformat) GetDuration(prop string) (time.Duration, bool) {
+	if m == nil {
+		return 0, false
+	}
+	return parseDuration(firstString(m, prop))
+}
+
+// parseDuration parses s as an ISO 8601 duration, as described in
+// GetDuration.
+func parseDuration(s string) (time.Duration, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	match := reDuration.FindStringSubmatch(s)
+	if match == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, false
+	}
+	var d float64
+	for i, n := range match[1:] {
+		if n == "" {
+			continue
+		}
+		if strings.ContainsAny(n, ".,") && s[len(s)-1] != durationDesignators[i] {
+			// only the last unit may have a fraction
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(strings.Replace(n, ",", ".", 1), 64)
+		if err != nil {
+			return 0, false
+		}
+		d += f * float64(durationUnits[i])
+	}
+	if d >= math.MaxInt64 {
+		return 0, false
+	}
+	return time.Duration(d), true
+}
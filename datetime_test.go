@@ -4,6 +4,7 @@
 package microformats
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -208,6 +209,8 @@ func Test_GetDateTimeValue(t *testing.T) {
 		    <time class="value" datetime="21:15:00"></time>
 		    <time class="value" datetime="-08:00"></time>
 		  </p>`, ptr("2015-02-03 21:15:00-0800")},
+		// a datetime without an offset is given a UTC offset
+		{`<p><time class="value" datetime="2015-02-03T21:15"><time></p>`, ptr("2015-02-03 21:15Z")},
 	}
 
 	for _, tt := range tests {
@@ -222,6 +225,69 @@ func Test_GetDateTimeValue(t *testing.T) {
 	}
 }
 
+func Test_Parse_DateTimeValueTitle(t *testing.T) {
+	tests := []struct {
+		html string
+		want []any
+	}{
+		{
+			`<span class="dt-published value-title" title="2024-01-02T03:04:05Z">Jan 2</span>`,
+			[]any{"2024-01-02 03:04:05Z"},
+		},
+		{
+			`<span class="dt-published"><span class="value-title" title="2024-01-02T03:04:05Z"></span>Jan 2</span>`,
+			[]any{"2024-01-02 03:04:05Z"},
+		},
+		{
+			`<span class="dt-published value-title" title="2024-01-02">Jan 2</span>`,
+			[]any{"2024-01-02"},
+		},
+		{
+			// the value class pattern takes precedence
+			`<span class="dt-published value-title" title="2024-01-02"><span class="value">2024-03-04</span></span>`,
+			[]any{"2024-03-04"},
+		},
+		{
+			// titles that are not datetimes are ignored
+			`<span class="dt-published value-title" title="tomorrow">Jan 2</span>`,
+			[]any{"Jan 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		doc := `<div class="h-entry">` + tt.html + `</div>`
+		items := parseItems(doc)
+		if got := items[0].Properties["published"]; !cmp.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) published returned %v, want %v", doc, got, tt.want)
+		}
+	}
+}
+
+func Test_WithUnixTimestamps(t *testing.T) {
+	tests := []struct {
+		html       string
+		want, unix []any
+	}{
+		{`<data class="dt-published" value="1700000000">Nov 14</data>`, []any{"1700000000"}, []any{"2023-11-14 22:13:20Z"}},
+		{`<time class="dt-published"> 1700000000 </time>`, []any{"1700000000"}, []any{"2023-11-14 22:13:20Z"}},
+		{`<data class="dt-published" value="2024-01-02">Jan 2</data>`, []any{"2024-01-02"}, []any{"2024-01-02"}},
+		// years and compact dates are not timestamps
+		{`<data class="dt-published" value="2024">2024</data>`, []any{"2024"}, []any{"2024"}},
+		{`<data class="dt-published" value="20240102">Jan 2</data>`, []any{"20240102"}, []any{"20240102"}},
+		{`<data class="dt-published" value="-1700000000">x</data>`, []any{"-1700000000"}, []any{"-1700000000"}},
+		{`<data class="dt-published" value="99999999999999">x</data>`, []any{"99999999999999"}, []any{"99999999999999"}},
+	}
+
+	for _, tt := range tests {
+		doc := `<div class="h-entry">` + tt.html + `</div>`
+		if got := parseItems(doc)[0].Properties["published"]; !cmp.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) published returned %v, want %v", doc, got, tt.want)
+		}
+		if got := parseItemsWith(doc, WithUnixTimestamps())[0].Properties["published"]; !cmp.Equal(got, tt.unix) {
+			t.Errorf("Parse(%q) with WithUnixTimestamps published returned %v, want %v", doc, got, tt.unix)
+		}
+	}
+}
 func Test_ImplyEndDate(t *testing.T) {
 	tests := []struct {
 		description string
@@ -264,6 +330,30 @@ func Test_ImplyEndDate(t *testing.T) {
 			[]string{"01:02:03", "2006-01-02 01:02:03"},
 			[]any{"2007-01-02 01:02:03", "2006-01-02 01:02:03"},
 		},
+		{
+			"end time at midnight",
+			[]string{"2006-01-02 20:00"},
+			[]string{"00:00"},
+			[]any{"2006-01-02 00:00"},
+		},
+		{
+			"end time with am/pm and timezone",
+			[]string{"2006-01-02T18:00-0800"},
+			[]string{"9pm", "21:30-0800"},
+			[]any{"2006-01-02 21:00", "2006-01-02 21:30-0800"},
+		},
+		{
+			"no start date",
+			[]string{"18:00"},
+			[]string{"21:00"},
+			[]any{"21:00"},
+		},
+		{
+			"end timezone only",
+			[]string{"2006-01-02 18:00"},
+			[]string{"-0800"},
+			[]any{"-0800"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -281,3 +371,241 @@ func Test_ImplyEndDate(t *testing.T) {
 		}
 	}
 }
+
+func Test_Parse_EventEndTime(t *testing.T) {
+	tests := []struct {
+		html       string
+		start, end []any
+	}{
+		{
+			`<div class="h-event">
+				<span class="p-name">Meetup</span>
+				<time class="dt-start" datetime="2024-05-01T18:00">May 1, 6pm</time> to
+				<time class="dt-end" datetime="21:00">9pm</time>
+			</div>`,
+			[]any{"2024-05-01T18:00"},
+			[]any{"2024-05-01 21:00"},
+		},
+		{
+			`<div class="h-event">
+				<span class="p-name">Late show</span>
+				<span class="dt-start"><span class="value">2024-05-01</span> <span class="value">22:00</span></span>
+				<span class="dt-end"><span class="value">00:00</span></span>
+			</div>`,
+			[]any{"2024-05-01 22:00"},
+			[]any{"2024-05-01 00:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		if len(items) != 1 {
+			t.Fatalf("Parse(%q) returned %d items, want 1", tt.html, len(items))
+		}
+		props := items[0].Properties
+		if got := props["start"]; !cmp.Equal(got, tt.start) {
+			t.Errorf("Parse(%q) start returned %v, want %v", tt.html, got, tt.start)
+		}
+		if got := props["end"]; !cmp.Equal(got, tt.end) {
+			t.Errorf("Parse(%q) end returned %v, want %v", tt.html, got, tt.end)
+		}
+	}
+}
+
+func Test_ParseWithWarnings_EventDates(t *testing.T) {
+	tests := []struct {
+		html string
+		want []Warning
+	}{
+		{
+			`<div class="h-event">
+				<time class="dt-start" datetime="2024-05-02T18:00">May 2</time> to
+				<time class="dt-end" datetime="2024-05-01T21:00">May 1</time>
+			</div>`,
+			[]Warning{{Message: `h-event end "2024-05-01T21:00" is before start "2024-05-02T18:00"`}},
+		},
+		{
+			`<div class="vevent">
+				<abbr class="dtstart" title="2024-05-02">May 2</abbr>
+				<abbr class="dtend" title="2024-04-30">April 30</abbr>
+			</div>`,
+			[]Warning{{Message: `h-event end "2024-04-30" is before start "2024-05-02"`}},
+		},
+		{
+			// ordered events
+			`<div class="h-event">
+				<time class="dt-start" datetime="2024-05-01">May 1</time>
+				<time class="dt-end" datetime="2024-05-01T21:00Z">9pm</time>
+			</div>`,
+			nil,
+		},
+		{
+			// dates without times are compared by day
+			`<div class="h-event">
+				<time class="dt-start" datetime="2024-05-01T18:00">May 1</time>
+				<time class="dt-end" datetime="2024-05-01">May 1</time>
+			</div>`,
+			nil,
+		},
+		{
+			// other microformats are not checked
+			`<div class="h-entry">
+				<time class="dt-start" datetime="2024-05-02">May 2</time>
+				<time class="dt-end" datetime="2024-05-01">May 1</time>
+			</div>`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		data, warnings := ParseWithWarnings(strings.NewReader(tt.html), nil)
+		if diff := cmp.Diff(tt.want, warnings); diff != "" {
+			t.Errorf("ParseWithWarnings(%q) warnings mismatch (-want +got):\n%s", tt.html, diff)
+		}
+		if len(data.Items) != 1 || len(data.Items[0].Properties["end"]) != 1 {
+			t.Errorf("ParseWithWarnings(%q) returned items %v, want one with an end", tt.html, data.Items)
+		}
+	}
+}
+
+func Test_PublishedIn(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	tests := []struct {
+		published string
+		loc       *time.Location
+		want      time.Time
+		ok        bool
+	}{
+		{"2024-01-02T10:00:00-0800", newYork, time.Date(2024, 1, 2, 13, 0, 0, 0, newYork), true},
+		{"2024-01-02 10:00:00Z", newYork, time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), true},
+		{"2024-01-02T10:00", newYork, time.Date(2024, 1, 2, 10, 0, 0, 0, newYork), true},
+		{"2024-07-02 10:00:30", newYork, time.Date(2024, 7, 2, 10, 0, 30, 0, newYork), true},
+		{"2024-01-02", newYork, time.Date(2024, 1, 2, 0, 0, 0, 0, newYork), true},
+		{"2024-01-02T10:00", nil, time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), true},
+		{"10:00", newYork, time.Time{}, false},
+		{"yesterday", newYork, time.Time{}, false},
+		{"", newYork, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		m := &Microformat{Properties: map[string][]any{}}
+		if tt.published != "" {
+			m.Properties["published"] = []any{tt.published}
+		}
+		got, ok := m.PublishedIn(tt.loc)
+		if !got.Equal(tt.want) || ok != tt.ok {
+			t.Errorf("PublishedIn(%v) for %q returned %v, %t, want %v, %t", tt.loc, tt.published, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// values as parsed, which keep the datetime attribute as it is written
+	items := parseItems(`<div class="h-entry"><time class="dt-published" datetime="2024-01-02T10:00">Jan 2</time></div>`)
+	if got, _ := items[0].PublishedIn(newYork); !got.Equal(time.Date(2024, 1, 2, 10, 0, 0, 0, newYork)) {
+		t.Errorf("PublishedIn(%v) for parsed h-entry returned %v, want 10:00 in %v", newYork, got, newYork)
+	}
+
+	if got, ok := (*Microformat)(nil).PublishedIn(newYork); ok {
+		t.Errorf("PublishedIn on nil Microformat returned %v, %t, want false", got, ok)
+	}
+}
+
+func Test_FormatDate(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	const layout = "Mon Jan 2, 2006 3:04 PM MST"
+	tests := []struct {
+		value  string
+		layout string
+		loc    *time.Location
+		want   string
+		ok     bool
+	}{
+		// date only
+		{"2024-01-02", "January 2, 2006", nil, "January 2, 2024", true},
+		{"2024-01-02", layout, newYork, "Tue Jan 2, 2024 12:00 AM EST", true},
+		{"2024-032", "2006-01-02", nil, "2024-02-01", true},
+		// datetime without a timezone
+		{"2024-07-02 10:00:30", layout, newYork, "Tue Jul 2, 2024 10:00 AM EDT", true},
+		{"2024-01-02T10:00", layout, nil, "Tue Jan 2, 2024 10:00 AM UTC", true},
+		{"2024-01-02 22:00", "15:04", nil, "22:00", true},
+		// datetime with a timezone
+		{"2024-01-02T10:00:00-0800", layout, newYork, "Tue Jan 2, 2024 1:00 PM EST", true},
+		{"2024-01-02T10:00:00-08:00", "15:04 -07:00", nil, "10:00 -08:00", true},
+		{"2024-01-02 10:00Z", layout, newYork, "Tue Jan 2, 2024 5:00 AM EST", true},
+		// unparseable
+		{"10:00", layout, nil, "", false},
+		{"next week", layout, nil, "", false},
+		{"", layout, nil, "", false},
+	}
+
+	for _, tt := range tests {
+		m := &Microformat{Properties: map[string][]any{}}
+		if tt.value != "" {
+			m.Properties["start"] = []any{tt.value}
+		}
+		got, ok := m.FormatDate("start", tt.layout, tt.loc)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("FormatDate(%q, %v) for %q returned %q, %t, want %q, %t", tt.layout, tt.loc, tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if got, ok := (*Microformat)(nil).FormatDate("start", layout, nil); ok {
+		t.Errorf("FormatDate on nil Microformat returned %q, %t, want false", got, ok)
+	}
+}
+
+func Test_GetDuration(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"PT30M", 30 * time.Minute, true},
+		{"PT1H30M", 90 * time.Minute, true},
+		{"pt1h", time.Hour, true},
+		{" PT45S ", 45 * time.Second, true},
+		{"P1D", 24 * time.Hour, true},
+		{"P2DT3H4M5S", 51*time.Hour + 4*time.Minute + 5*time.Second, true},
+		{"P1W", 7 * 24 * time.Hour, true},
+		{"PT1.5H", 90 * time.Minute, true},
+		{"PT0,5S", 500 * time.Millisecond, true},
+		{"P0D", 0, true},
+
+		{"", 0, false},
+		{"30 minutes", 0, false},
+		{"P", 0, false},
+		{"PT", 0, false},
+		{"P1DT", 0, false},
+		{"P1Y", 0, false},
+		{"P1M", 0, false},
+		{"PT1.5H30M", 0, false},
+		{"PT1H1H", 0, false},
+		{"P9999999999W", 0, false},
+	}
+
+	for _, tt := range tests {
+		m := &Microformat{Properties: map[string][]any{"duration": {tt.value}}}
+		got, ok := m.GetDuration("duration")
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetDuration(%q) returned %v, %t, want %v, %t", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	// the raw value is left in the property
+	items := parseItems(`<div class="h-recipe"><time class="dt-duration" datetime="PT1H30M">1½ hours</time></div>`)
+	if got, want := items[0].Properties["duration"], []any{"PT1H30M"}; !cmp.Equal(got, want) {
+		t.Errorf("Parse returned duration %v, want %v", got, want)
+	}
+	if got, ok := items[0].GetDuration("duration"); got != 90*time.Minute || !ok {
+		t.Errorf("GetDuration of parsed h-recipe returned %v, %t, want %v, true", got, ok, 90*time.Minute)
+	}
+	var m *Microformat
+	if _, ok := m.GetDuration("duration"); ok {
+		t.Errorf("GetDuration of nil Microformat returned true")
+	}
+}
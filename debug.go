@@ -0,0 +1,88 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for recording how parsed data was produced.
+
+package microformats
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// modulePath is the module path of this package.
+const modulePath = "willnorris.com/go/microformats"
+
+// specURL identifies the parsing specification implemented by this package.
+const specURL = "https://microformats.org/wiki/microformats2-parsing"
+
+// DebugInfo describes how a Data value was produced, to help explain
+// differences in stored output across upgrades of this package.
+type DebugInfo struct {
+	// Version is the module version of this package, as recorded in the
+	// build information of the running binary.  It is "(devel)" when this
+	// package is the main module, and empty if build information is not
+	// available.
+	Version string
+
+	// Spec is the URL of the parsing specification implemented.
+	Spec string
+
+	// Items is the number of top-level microformats parsed, including
+	// any passed to an OnMicroformat callback instead of added to Items.
+	Items int
+
+	// Rels and RelURLs are the number of rel values and of URLs parsed.
+	Rels    int
+	RelURLs int
+}
+
+// WithDebugInfo records a DebugInfo in Data.Debug.  Since Debug is not part of
+// the canonical JSON representation, it is only available to Go callers, who
+// can store it alongside the JSON if needed.
+func WithDebugInfo() Option {
+	return func(p *parser) {
+		p.debugInfo = true
+	}
+}
+
+var (
+	versionOnce sync.Once
+	version     string
+)
+
+// moduleVersion returns the module version of this package, as described in
+// DebugInfo.
+func moduleVersion() string {
+	versionOnce.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		if info.Main.Path == modulePath {
+			version = info.Main.Version
+			return
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				if dep.Replace != nil {
+					dep = dep.Replace
+				}
+				version = dep.Version
+				return
+			}
+		}
+	})
+	return version
+}
+
+// debug returns the DebugInfo for p, once parsing is complete.
+func (p *parser) debug() *DebugInfo {
+	return &DebugInfo{
+		Version: moduleVersion(),
+		Spec:    specURL,
+		Items:   len(p.curData.Items) + p.handledItems,
+		Rels:    len(p.curData.Rels),
+		RelURLs: len(p.curData.RelURLs),
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_WithDebugInfo(t *testing.T) {
+	doc := `<div class="h-card">Jane</div><div class="h-entry">Post</div>
+		<a rel="me author" href="/jane">me</a><a rel="me" href="/other">other</a>`
+
+	if data := Parse(strings.NewReader(doc), nil); data.Debug != nil {
+		t.Errorf("Parse returned Debug %v without WithDebugInfo, want nil", data.Debug)
+	}
+
+	data := Parse(strings.NewReader(doc), nil, WithDebugInfo())
+	want := &DebugInfo{
+		Version: moduleVersion(),
+		Spec:    "https://microformats.org/wiki/microformats2-parsing",
+		Items:   2,
+		Rels:    2,
+		RelURLs: 2,
+	}
+	if diff := cmp.Diff(want, data.Debug); diff != "" {
+		t.Errorf("Parse with WithDebugInfo mismatch (-want +got):\n%s", diff)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	if strings.Contains(string(b), "microformats2-parsing") {
+		t.Errorf("json.Marshal included debug info: %s", b)
+	}
+
+	// microformats passed to OnMicroformat are still counted
+	data = Parse(strings.NewReader(doc), nil, WithDebugInfo(), OnMicroformat(func(*Microformat) {}))
+	if got := data.Debug.Items; got != 2 {
+		t.Errorf("Parse with OnMicroformat returned Debug.Items %d, want 2", got)
+	}
+}
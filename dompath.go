@@ -0,0 +1,48 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for recording where microformats are in the
+// document tree.
+
+package microformats
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// WithDOMPath records the path through the document tree to the root element
+// of each microformat in its DOMPath field, to help correlate parsed output
+// with the structure of the document, such as to see why a microformat was
+// nested where it was.  The path is a CSS-like child selector from the
+// document's root element, with the tag name and classes of each element,
+// such as "html>body>div.h-feed>article.h-entry".  Paths are recorded for
+// top-level, child, and property microformats.  With NewParser, which does
+// not keep the rest of the document, paths begin at the root element of each
+// top-level microformat.  DOMPath is not part of the canonical JSON
+// representation.  By default, no paths are recorded.
+func WithDOMPath() Option {
+	return func(p *parser) {
+		p.domPath = true
+	}
+}
+
+// domPath returns the path to node described in WithDOMPath.
+func domPath(node *html.Node) string {
+	var steps []string
+	for n := node; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		step := n.Data
+		for _, class := range getClasses(n) {
+			step += "." + class
+		}
+		steps = append(steps, step)
+	}
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+	return strings.Join(steps, ">")
+}
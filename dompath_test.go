@@ -0,0 +1,44 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_WithDOMPath(t *testing.T) {
+	doc := `<!doctype html><html><body><main>
+		<div class="h-feed  feed"><h1 class="p-name">Feed</h1>
+			<section><article class="h-entry"><p class="p-name">Post</p>
+				<span class="p-author h-card">Jane</span></article></section>
+		</div></main></body></html>`
+
+	items := parseItemsWith(doc, WithDOMPath())
+	feed := items[0]
+	if got, want := feed.DOMPath, "html>body>main>div.h-feed.feed"; got != want {
+		t.Errorf("h-feed DOMPath is %q, want %q", got, want)
+	}
+	entry := feed.Children[0]
+	if got, want := entry.DOMPath, "html>body>main>div.h-feed.feed>section>article.h-entry"; got != want {
+		t.Errorf("h-entry DOMPath is %q, want %q", got, want)
+	}
+	author := entry.Properties["author"][0].(*Microformat)
+	if got, want := author.DOMPath, "html>body>main>div.h-feed.feed>section>article.h-entry>span.p-author.h-card"; got != want {
+		t.Errorf("h-card DOMPath is %q, want %q", got, want)
+	}
+
+	b, err := json.Marshal(items)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	if strings.Contains(string(b), "section") {
+		t.Errorf("DOMPath was included in JSON: %s", b)
+	}
+
+	if got := parseItems(doc)[0].DOMPath; got != "" {
+		t.Errorf("DOMPath without WithDOMPath is %q, want empty", got)
+	}
+}
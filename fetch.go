@@ -0,0 +1,188 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for fetching and parsing documents over HTTP.
+
+package microformats
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ParseURL fetches the HTML document at rawURL using client, and parses the
+// microformats found in it.  If client is nil, http.DefaultClient is used.
+// The URL of the final response, after any redirects, is used as the base
+// URL of the document.
+//
+// Response bodies with a Content-Encoding of gzip or deflate are decompressed
+// before parsing, including when client's transport does not handle this
+// itself (such as when DisableCompression is set).  An error is returned if
+// the request fails, the response status is not 2xx, or the body uses another
+// encoding.  As with ParseWithError, if reading the body fails partway
+// through, the returned Data holds the microformats found in the partial
+// document, along with the read error.
+//
+// The character encoding of the document is detected as described in
+// WithCharsetDetection, using the Content-Type header of the response.
+func ParseURL(ctx context.Context, client *http.Client, rawURL string, opts ...Option) (*Data, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRequest(client, req, opts...)
+}
+
+// ParseRequest sends req using client, and parses the microformats found in
+// the HTML document it returns, the same as ParseURL.  This allows the request
+// to carry credentials for pages that require them, such as a session cookie
+// or an Authorization header.  If client is nil, http.DefaultClient is used.
+//
+// When following redirects, client forwards the headers of req as described
+// in http.Client: sensitive headers such as Authorization and Cookie are only
+// sent to the same domain or its subdomains.  Use client's Jar to manage
+// cookies across redirects instead.
+func ParseRequest(client *http.Client, req *http.Request, opts ...Option) (*Data, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("microformats: fetching %s: %s", req.URL, resp.Status)
+	}
+	body, err := decodeBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("microformats: fetching %s: %w", req.URL, err)
+	}
+	opts = append(opts[:len(opts):len(opts)], withContentType(resp.Header.Get("Content-Type")))
+	data, err := ParseWithError(body, resp.Request.URL, opts...)
+	if data != nil {
+		data.HeaderRels = headerRels(resp.Header, resp.Request.URL)
+	}
+	return data, err
+}
+
+// headerRels returns the rels advertised by the X-Pingback and Link headers
+// of h, as described in Data.HeaderRels, with URLs resolved against base.  The
+// X-Pingback URL is first, since it takes precedence.  It returns nil if there
+// are none.
+func headerRels(h http.Header, base *url.URL) map[string][]string {
+	var rels map[string][]string
+	add := func(rel, u string) {
+		if rels == nil {
+			rels = make(map[string][]string)
+		}
+		rels[rel] = append(rels[rel], expandURL(u, base))
+	}
+	if u := strings.TrimSpace(h.Get("X-Pingback")); u != "" {
+		add("pingback", u)
+	}
+	for _, v := range h.Values("Link") {
+		for _, link := range parseLinkHeader(v) {
+			for _, rel := range link.rels {
+				add(rel, link.url)
+			}
+		}
+	}
+	return rels
+}
+
+// headerLink is a link in a Link header.
+type headerLink struct {
+	url  string
+	rels []string
+}
+
+// parseLinkHeader parses the links in the value s of a Link header, as
+// specified by RFC 8288, such as `<https://example.com/>; rel="webmention"`.
+// Links without a rel parameter are omitted, and rel values are lowercased.
+func parseLinkHeader(s string) []headerLink {
+	var links []headerLink
+	for {
+		start := strings.IndexByte(s, '<')
+		end := strings.IndexByte(s, '>')
+		if start < 0 || end < start {
+			return links
+		}
+		link := headerLink{url: s[start+1 : end]}
+		s = s[end+1:]
+
+		// parameters end at the next comma that is not in a quoted string
+		params := s
+		quoted := false
+		for i := 0; i < len(s); i++ {
+			if s[i] == '"' {
+				quoted = !quoted
+			} else if s[i] == ',' && !quoted {
+				params, s = s[:i], s[i+1:]
+				break
+			}
+		}
+		if params == s {
+			s = ""
+		}
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.EqualFold(strings.TrimSpace(name), "rel") {
+				link.rels = splitTokens(strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`)))
+				break
+			}
+		}
+		if len(link.rels) > 0 {
+			links = append(links, link)
+		}
+	}
+}
+
+// decodeBody returns a reader for the body of resp, decompressed according to
+// its Content-Encoding header.  Multiple encodings are removed in the reverse
+// order they were applied.
+func decodeBody(resp *http.Response) (io.Reader, error) {
+	var body io.Reader = resp.Body
+	encodings := strings.Split(resp.Header.Get("Content-Encoding"), ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+		switch enc := strings.ToLower(strings.TrimSpace(encodings[i])); enc {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			body, err = gzip.NewReader(body)
+		case "deflate":
+			body, err = deflateReader(body)
+		default:
+			return nil, fmt.Errorf("unsupported content encoding %q", enc)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// deflateReader returns a reader that decompresses r, which is deflate
+// encoded.  HTTP specifies deflate content as zlib wrapped, but some servers
+// send raw deflate data, so that is accepted too.
+func deflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	// a zlib header uses the deflate method, and is a multiple of 31
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ParseURL(t *testing.T) {
+	doc := `<div class="h-card"><a class="p-name u-url" href="/jane">Jane</a></div>`
+	compress := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		_, _ = io.WriteString(w, doc)
+		_ = w.Close()
+		return buf.Bytes()
+	}
+	bodies := map[string][]byte{
+		"":        []byte(doc),
+		"gzip":    compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }),
+		"deflate": compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }),
+		"raw-deflate": compress(func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page/", http.StatusFound)
+	})
+	mux.HandleFunc("/page/", func(w http.ResponseWriter, r *http.Request) {
+		enc := r.URL.Query().Get("encoding")
+		if enc == "raw-deflate" {
+			w.Header().Set("Content-Encoding", "deflate")
+		} else {
+			w.Header().Set("Content-Encoding", enc)
+		}
+		_, _ = w.Write(bodies[enc])
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// disable transparent decompression, so that compressed bodies are
+	// passed through as sent
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+
+	want := []*Microformat{{
+		Type: []string{"h-card"},
+		Properties: map[string][]any{
+			"name": {"Jane"},
+			"url":  {srv.URL + "/jane"},
+		},
+	}}
+	for _, path := range []string{"/page/", "/page/?encoding=gzip", "/page/?encoding=deflate", "/page/?encoding=raw-deflate", "/redirect"} {
+		data, err := ParseURL(context.Background(), client, srv.URL+path)
+		if err != nil {
+			t.Errorf("ParseURL(%q) returned error: %v", path, err)
+			continue
+		}
+		if diff := cmp.Diff(want, data.Items, ignoreParseState); diff != "" {
+			t.Errorf("ParseURL(%q) mismatch (-want +got):\n%s", path, diff)
+		}
+	}
+
+	// the final URL is used as the base
+	data, err := ParseURL(context.Background(), nil, srv.URL+"/redirect")
+	if err != nil {
+		t.Fatalf("ParseURL returned error: %v", err)
+	}
+	if got, want := data.BaseURL.String(), srv.URL+"/page/"; got != want {
+		t.Errorf("ParseURL returned BaseURL %q, want %q", got, want)
+	}
+
+	for _, path := range []string{"/missing", "/page/?encoding=br"} {
+		if _, err := ParseURL(context.Background(), client, srv.URL+path); err == nil {
+			t.Errorf("ParseURL(%q) did not return error", path)
+		}
+	}
+}
+
+func Test_ParseRequest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/members/", http.StatusFound)
+	})
+	mux.HandleFunc("/members/", func(w http.ResponseWriter, r *http.Request) {
+		session, err := r.Cookie("session")
+		if err != nil || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `<div class="h-card"><span class="p-name">%s</span><a class="u-url" href="me">me</a></div>`, session.Value)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL+"/redirect", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "Jane"})
+
+	data, err := ParseRequest(nil, req)
+	if err != nil {
+		t.Fatalf("ParseRequest returned error: %v", err)
+	}
+	want := []*Microformat{{
+		Type: []string{"h-card"},
+		Properties: map[string][]any{
+			"name": {"Jane"},
+			"url":  {srv.URL + "/members/me"},
+		},
+	}}
+	if diff := cmp.Diff(want, data.Items, ignoreParseState); diff != "" {
+		t.Errorf("ParseRequest mismatch (-want +got):\n%s", diff)
+	}
+
+	req.Header.Del("Authorization")
+	if _, err := ParseRequest(nil, req); err == nil {
+		t.Errorf("ParseRequest without credentials did not return error")
+	}
+}
+
+func Test_ParseLinkHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []headerLink
+	}{
+		{"", nil},
+		{`<https://example.com/wm>; rel="webmention"`, []headerLink{{"https://example.com/wm", []string{"webmention"}}}},
+		{`<https://example.com/wm>; rel=webmention`, []headerLink{{"https://example.com/wm", []string{"webmention"}}}},
+		{
+			`</a>; title="a, b"; REL="Me Author", <b>; rel=next, <c>; type="text/html"`,
+			[]headerLink{{"/a", []string{"me", "author"}}, {"b", []string{"next"}}},
+		},
+		{`<unterminated; rel=me`, nil},
+	}
+	for _, tt := range tests {
+		got := parseLinkHeader(tt.header)
+		if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(headerLink{})); diff != "" {
+			t.Errorf("parseLinkHeader(%q) mismatch (-want +got):\n%s", tt.header, diff)
+		}
+	}
+}
+
+func Test_ParseURL_HeaderRels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `</header-webmention>; rel="webmention"`)
+		w.Header().Set("X-Pingback", "/xmlrpc")
+		fmt.Fprint(w, `<link rel="webmention" href="/webmention"><link rel="pingback" href="/pingback">`)
+	}))
+	defer srv.Close()
+
+	data, err := ParseURL(context.Background(), nil, srv.URL+"/page")
+	if err != nil {
+		t.Fatalf("ParseURL returned error: %v", err)
+	}
+	want := map[string][]string{
+		"webmention": {srv.URL + "/header-webmention"},
+		"pingback":   {srv.URL + "/xmlrpc"},
+	}
+	if diff := cmp.Diff(want, data.HeaderRels); diff != "" {
+		t.Errorf("ParseURL HeaderRels mismatch (-want +got):\n%s", diff)
+	}
+	webmention, pingback := data.MentionEndpoints()
+	if webmention != srv.URL+"/header-webmention" || pingback != srv.URL+"/xmlrpc" {
+		t.Errorf("MentionEndpoints returned %q, %q, want header endpoints", webmention, pingback)
+	}
+}
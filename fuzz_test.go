@@ -0,0 +1,63 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"willnorris.com/go/microformats"
+)
+
+// fuzzSeeds are documents used to seed FuzzParse, in addition to the HTML
+// files of the shared test suite, which may not be checked out.
+var fuzzSeeds = []string{
+	`<div class="h-card"><a class="p-name u-url" href="/jane">Jane</a><img class="u-photo" src="a.jpg" alt="Jane"></div>`,
+	`<div class="h-entry"><span class="p-name"><span class="value">A</span><span class="value">B</span></span>` +
+		`<time class="dt-start"><span class="value">2024-01-02</span><span class="value">10:00pm</span></time>` +
+		`<time class="dt-end"><span class="value">11pm</span></time>` +
+		`<div class="e-content"><p>Hello <a href="x">world</a></p></div>` +
+		`<div class="p-author h-card">Jane</div></div>`,
+	`<base href="//example.net/a/"><a rel="me author" href="../b">b</a><link rel="alternate" type="application/atom+xml" href="feed">`,
+	`<div class="vcard"><span class="fn n"><span class="given-name">Jane</span></span><div class="adr"><span class="locality">X</span></div>` +
+		`<a class="include" href="#other"></a></div><div id="other" class="note">n</div>`,
+	`<table><tr itemref="a b"><td class="vevent"><abbr class="dtstart" title="2024-01-02T03:04">x</abbr><td headers="a"></table>`,
+	`<svg class="h-card"><a href="/jane"><image class="u-photo" href="p.png"/><title>Jane</title></a></svg>`,
+	`<div class="h-feed"><template><div class="h-entry"></div></template><script>var x = "<div class='h-card'>";</script></div>`,
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	files, _ := filepath.Glob(filepath.Join("testdata", "tests", "*", "*", "*.html"))
+	for _, file := range files {
+		if b, err := os.ReadFile(file); err == nil {
+			f.Add(string(b))
+		}
+	}
+
+	base, _ := url.Parse("http://example.com/dir/page")
+	f.Fuzz(func(t *testing.T, doc string) {
+		data := microformats.Parse(strings.NewReader(doc), base)
+		if data == nil {
+			t.Fatalf("Parse(%q) returned nil", doc)
+		}
+		if _, err := json.Marshal(data); err != nil {
+			t.Fatalf("json.Marshal of Parse(%q) returned error: %v", doc, err)
+		}
+		if err, ok := microformats.ValidateSchema(data).(*microformats.SchemaError); ok {
+			for _, problem := range err.Problems {
+				// invalid URLs in the document cannot be resolved
+				if !strings.HasSuffix(problem, "URL is not absolute") {
+					t.Errorf("Parse(%q) returned invalid data: %v", doc, problem)
+				}
+			}
+		}
+	})
+}
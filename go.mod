@@ -5,6 +5,7 @@ require (
 	github.com/google/go-cmp v0.5.9
 	github.com/kylelemons/godebug v1.1.0
 	golang.org/x/net v0.2.0
+	golang.org/x/text v0.4.0
 )
 
 require github.com/andybalholm/cascadia v1.3.1 // indirect
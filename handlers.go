@@ -0,0 +1,47 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes options for handling parsed microformats and rels as
+// they are found, rather than collecting them into Data.
+
+package microformats
+
+// OnMicroformat calls fn with each top-level microformat as soon as it has
+// been completely parsed, including its implied properties, rather than
+// adding it to Data.Items.  Microformats are passed to fn in document order
+// of their closing tags, which for top-level microformats (which never
+// overlap) is the same as the order they would appear in Data.Items.  Items
+// produced by WithJSONLDFallback and similar options are passed to fn at the
+// end of the document.
+//
+// Since parsed microformats are not retained by the parser, aggregating
+// values while parsing large documents only needs memory for the aggregate
+// and the document tree itself, provided fn does not retain the
+// microformats it is passed.  Note that Parse still builds the complete
+// document tree before walking it; OnMicroformat bounds the memory used by
+// results, not by the document.
+//
+// fn is called synchronously, and must not call back into the parser.
+func OnMicroformat(fn func(m *Microformat)) Option {
+	return func(p *parser) {
+		p.onMicroformat = fn
+	}
+}
+
+// OnRel calls fn with each rel value and URL found on the page, in document
+// order, the first time each one is found.  URLs are resolved as they are
+// for Data.Rels, and only rels collected by WithRels are included.  Unlike
+// OnMicroformat, rels are still added to Data.Rels and Data.RelURLs.
+//
+// fn is called synchronously, and must not call back into the parser.
+func OnRel(fn func(rel, url string)) Option {
+	return func(p *parser) {
+		p.onRel = fn
+	}
+}
+
+// withoutHandlers removes any callbacks set by OnMicroformat and OnRel.
+func withoutHandlers(p *parser) {
+	p.onMicroformat = nil
+	p.onRel = nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_OnMicroformat(t *testing.T) {
+	doc := `<div class="h-entry"><span class="p-name">One</span>
+		<div class="p-author h-card">Jane</div><div class="h-cite">child</div></div>
+	<a rel="me author" href="/jane">Jane</a>
+	<div class="vcard"><span class="fn">John</span></div>
+	<a rel="me" href="/jane">again</a>`
+
+	var types [][]string
+	var rels []string
+	data := Parse(strings.NewReader(doc), nil,
+		OnMicroformat(func(m *Microformat) {
+			types = append(types, m.Type)
+		}),
+		OnRel(func(rel, url string) {
+			rels = append(rels, rel+" "+url)
+		}))
+
+	if want := [][]string{{"h-entry"}, {"h-card"}}; !cmp.Equal(types, want) {
+		t.Errorf("OnMicroformat called with types %v, want %v", types, want)
+	}
+	if len(data.Items) != 0 {
+		t.Errorf("Parse with OnMicroformat returned %d items, want 0", len(data.Items))
+	}
+	if want := []string{"me /jane", "author /jane"}; !cmp.Equal(rels, want) {
+		t.Errorf("OnRel called with %q, want %q", rels, want)
+	}
+	if want := []string{"/jane"}; !cmp.Equal(data.Rels["me"], want) {
+		t.Errorf("Parse with OnRel returned me rels %q, want %q", data.Rels["me"], want)
+	}
+}
+
+func Test_OnMicroformat_JSONLD(t *testing.T) {
+	doc := `<script type="application/ld+json">{"@type": "Person", "name": "Jane"}</script>`
+
+	var names []string
+	data := Parse(strings.NewReader(doc), nil, WithJSONLDFallback(),
+		OnMicroformat(func(m *Microformat) {
+			names = append(names, firstString(m, "name"))
+		}))
+
+	if want := []string{"Jane"}; !cmp.Equal(names, want) {
+		t.Errorf("OnMicroformat called with names %q, want %q", names, want)
+	}
+	if len(data.Items) != 0 {
+		t.Errorf("Parse with OnMicroformat returned %d items, want 0", len(data.Items))
+	}
+}
+
+func Test_OnMicroformat_Parser(t *testing.T) {
+	var calls int
+	p := NewParser(nil, OnMicroformat(func(*Microformat) { calls++ }))
+	_, _ = p.Write([]byte(`<div class="h-card">Jane</div>`))
+	_ = p.Close()
+
+	if calls != 0 {
+		t.Errorf("OnMicroformat called %d times by Parser, want 0", calls)
+	}
+	if got := len(p.Items()); got != 1 {
+		t.Errorf("Parser returned %d items, want 1", got)
+	}
+}
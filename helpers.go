@@ -0,0 +1,670 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes convenience methods for inspecting parsed microformats.
+
+package microformats
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// PrimaryType returns the first type of m, such as "h-entry", or an empty
+// string if m has no types.  Most microformats have a single type, but some
+// have several, such as an organization represented as ["h-card", "h-org"].
+// Types are sorted by the parser, so the first type is the alphabetically
+// first one, which is not necessarily the most specific.
+func (m *Microformat) PrimaryType() string {
+	if m == nil || len(m.Type) == 0 {
+		return ""
+	}
+	return m.Type[0]
+}
+
+// FlatProperties returns the properties of m flattened into a single string
+// per property name, which is useful for logging or quick inspection.  Each
+// property value is converted to a string as follows, and multiple values are
+// joined with ", ":
+//
+//   - string values are used as-is
+//   - embedded markup (e-* properties) uses its plain text value
+//   - images with alt text use their URL
+//   - nested microformats use their value, or else their name or url
+//
+// This is lossy by design, and not suitable for processing property values.
+func (m *Microformat) FlatProperties() map[string]string {
+	if m == nil {
+		return nil
+	}
+	flat := make(map[string]string, len(m.Properties))
+	for name, values := range m.Properties {
+		var s []string
+		for _, v := range values {
+			if str := valueString(v); str != "" {
+				s = append(s, str)
+			}
+		}
+		flat[name] = strings.Join(s, ", ")
+	}
+	return flat
+}
+
+// IsEmpty returns whether parsing found nothing on the page: no items, rels,
+// or rel URLs.  A nil Data is empty.
+func (d *Data) IsEmpty() bool {
+	return d == nil || len(d.Items) == 0 && len(d.Rels) == 0 && len(d.RelURLs) == 0
+}
+
+// TypeCounts returns the number of microformats of each type found on the
+// page.  Counts include top-level items, their nested children, and
+// microformats nested as property values, at any depth.  A microformat with
+// multiple types is counted once for each of its types.
+func (d *Data) TypeCounts() map[string]int {
+	counts := make(map[string]int)
+	d.walkItems(func(m *Microformat) {
+		for _, t := range m.Type {
+			counts[t]++
+		}
+	})
+	return counts
+}
+
+// FindByProperty returns all microformats on the page that have at least one
+// value of the property prop for which match returns true.  Values passed to
+// match are strings, map[string]string values for embedded markup (e-*
+// properties) and images with alt text, or *Microformat values for nested
+// microformats.
+//
+// Microformats are searched depth first: each top-level item is checked,
+// then the microformats nested in its property values (in order of property
+// name), and then its children, before moving on to the next item.
+// Microformats nested as property values and children are included at any
+// depth.
+func (d *Data) FindByProperty(prop string, match func(value any) bool) []*Microformat {
+	var found []*Microformat
+	d.walkItems(func(m *Microformat) {
+		for _, v := range m.Properties[prop] {
+			if match(v) {
+				found = append(found, m)
+				return
+			}
+		}
+	})
+	return found
+}
+
+// EntriesByDate returns the h-entry microformats of d, such as the posts of a
+// feed, sorted by their published date, newest first if descending is true.
+// This includes top-level items and their children at any depth, but not
+// h-entry microformats that are property values, such as the post an entry
+// is in reply to, since those describe other posts.  Dates are parsed as
+// described in PublishedIn, with values without a timezone offset in UTC.
+// Entries without a published date are sorted last, in either direction, and
+// entries with the same date keep their document order.
+func (d *Data) EntriesByDate(descending bool) []*Microformat {
+	if d == nil {
+		return nil
+	}
+	type datedEntry struct {
+		entry     *Microformat
+		published time.Time
+		ok        bool
+	}
+	var entries []datedEntry
+	var collect func(items []*Microformat)
+	collect = func(items []*Microformat) {
+		for _, m := range items {
+			if m == nil {
+				continue
+			}
+			if m.hasType("h-entry") {
+				published, ok := m.PublishedIn(nil)
+				entries = append(entries, datedEntry{m, published, ok})
+			}
+			collect(m.Children)
+		}
+	}
+	collect(d.Items)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.ok || !b.ok {
+			return a.ok && !b.ok
+		}
+		if descending {
+			return a.published.After(b.published)
+		}
+		return a.published.Before(b.published)
+	})
+	sorted := make([]*Microformat, len(entries))
+	for i, e := range entries {
+		sorted[i] = e.entry
+	}
+	return sorted
+}
+
+// Link is a hyperlink found in the content of a microformat.
+type Link struct {
+	URL  string
+	Text string   // text content of the link
+	Rel  []string // values of the link's rel attribute, if any
+}
+
+// ContentLinks returns the links in the content property of m, such as an
+// h-entry's e-content, in document order.  Each <a> element with an href
+// attribute in the html of each content value is included.  URLs in
+// embedded markup are already absolute if the page was parsed with a base
+// URL; otherwise, relative URLs are resolved against m's url property if it
+// is absolute, and returned as-is if not.  Content values without html, such
+// as p-content, have no links.
+func (m *Microformat) ContentLinks() []Link {
+	if m == nil {
+		return nil
+	}
+	var base *url.URL
+	if u, err := url.Parse(firstString(m, "url")); err == nil && u.IsAbs() {
+		base = u
+	}
+
+	var links []Link
+	for _, v := range m.Properties["content"] {
+		for _, n := range parseMarkup(v) {
+			links = appendLinks(links, n, base)
+		}
+	}
+	return links
+}
+
+// unfollowedRels are the rel values that mark links as not endorsed by the
+// author, which search engines do not follow.
+var unfollowedRels = []string{"nofollow", "ugc", "sponsored"}
+
+// ContentLinksFollowable returns the links in the content property of m, as
+// described in ContentLinks, except those marked with a rel value of
+// "nofollow", "ugc", or "sponsored", such as links in user-generated content,
+// for building a link graph of the links the author endorses.  Rel values are
+// compared case insensitively.
+func (m *Microformat) ContentLinksFollowable() []Link {
+	var links []Link
+	for _, link := range m.ContentLinks() {
+		if !link.unfollowed() {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// unfollowed returns whether l has one of the unfollowedRels.
+func (l Link) unfollowed() bool {
+	for _, rel := range l.Rel {
+		for _, r := range unfollowedRels {
+			if strings.EqualFold(rel, r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseMarkup parses the html of v, a value of an e-* property, returning nil
+// if v has no html.
+func parseMarkup(v any) []*html.Node {
+	var markup string
+	switch v := v.(type) {
+	case map[string]string:
+		markup = v["html"]
+	case *Microformat:
+		if v != nil {
+			markup = v.HTML
+		}
+	}
+	if markup == "" {
+		return nil
+	}
+	context := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(markup), context)
+	if err != nil {
+		return nil
+	}
+	return nodes
+}
+
+// appendLinks appends the links in the tree rooted at node to links.
+func appendLinks(links []Link, node *html.Node, base *url.URL) []Link {
+	if isAtom(node, atom.A) {
+		if href := getAttrPtr(node, "href"); href != nil {
+			links = append(links, Link{
+				URL:  expandURL(*href, base),
+				Text: strings.TrimSpace(getTextContent(node, nil)),
+				Rel:  splitTokens(getAttr(node, "rel")),
+			})
+		}
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		links = appendLinks(links, c, base)
+	}
+	return links
+}
+
+// AllURLs returns every absolute URL found on the page, such as for checking
+// for broken links, in document order and without duplicates.  This includes
+// the property values of each microformat that are absolute URLs (including
+// the values of nested microformats), the links in the html of e-* property
+// values, as described in ContentLinks, and the page's rel URLs.  Relative
+// URLs are resolved against the page's BaseURL, and URLs that are still not
+// absolute are omitted.  Values of u-* properties are only included if they
+// are absolute, since other string values, such as names, cannot be told
+// apart from relative URLs.
+//
+// Each URL is ordered by the first element it was found on by Parse.  An
+// implied url or photo is ordered by the element of its microformat.  The
+// order of URLs that were not found by Parse, such as in Data that was
+// unmarshaled from JSON or modified after parsing, is not known, so they
+// follow the others, with the values of microformats in the order described
+// in FindByProperty and the properties of each in order of property name,
+// and then rel URLs in sorted order.
+func (d *Data) AllURLs() []string {
+	if d == nil {
+		return nil
+	}
+	var urls []string
+	seen := make(map[string]bool)
+	add := func(u string) {
+		if !seen[u] && isAbsoluteURL(u) {
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+
+	d.walkItems(func(m *Microformat) {
+		names := make([]string, 0, len(m.Properties))
+		for name := range m.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, v := range m.Properties[name] {
+				if mf, ok := v.(*Microformat); ok {
+					if mf != nil {
+						add(mf.Value)
+					}
+					continue
+				}
+				add(valueString(v))
+				for _, n := range parseMarkup(v) {
+					for _, link := range appendLinks(nil, n, d.BaseURL) {
+						add(link.URL)
+					}
+				}
+			}
+		}
+	})
+
+	rels := make([]string, 0, len(d.RelURLs))
+	for u := range d.RelURLs {
+		rels = append(rels, u)
+	}
+	sort.Strings(rels)
+	for _, u := range rels {
+		add(expandURL(u, d.BaseURL))
+	}
+
+	positions := make(map[string]int, len(d.urls))
+	for _, u := range d.urls {
+		if pos, ok := positions[u.url]; !ok || u.position < pos {
+			positions[u.url] = u.position
+		}
+	}
+	sort.SliceStable(urls, func(i, j int) bool {
+		pi, iok := positions[urls[i]]
+		pj, jok := positions[urls[j]]
+		if iok != jok {
+			return iok
+		}
+		return pi < pj
+	})
+	return urls
+}
+
+// A documentURL is a URL found while parsing, with the position in the
+// document of the element it was found on, counted in the order elements are
+// walked.
+type documentURL struct {
+	url      string
+	position int
+}
+
+// recordURL records u as found on the element at position in the document,
+// for AllURLs.
+func (p *parser) recordURL(u string, position int) {
+	if u != "" {
+		p.curData.urls = append(p.curData.urls, documentURL{url: u, position: position})
+	}
+}
+
+// recordContentURLs records the links within node, the element of an e-*
+// property at position in the document, as they are found in its html by
+// AllURLs.  Each link is given the position of its own element, counting
+// elements from node in the same order they are walked.
+func (p *parser) recordContentURLs(node *html.Node, position int) {
+	var record func(n *html.Node)
+	record = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			position++
+			if isAtom(c, atom.A) {
+				if href := getAttrPtr(c, "href"); href != nil {
+					p.recordURL(expandURL(*href, p.base), position)
+				}
+			}
+			if !isAtom(c, atom.Template) {
+				record(c)
+			}
+		}
+	}
+	record(node)
+}
+
+// Photo is an image referenced by a u-photo property, with its alternative
+// text.
+type Photo struct {
+	URL string
+	Alt string
+}
+
+// Photos returns the photo property values of m as Photos.  This handles
+// both forms of image values: plain URL strings (Alt is empty), and maps with
+// "value" and "alt" members.  Nested microformats use their value, or else
+// their url property.  Empty values are omitted.
+func (m *Microformat) Photos() []Photo {
+	return m.images("photo")
+}
+
+// images returns the values of the u-* image property prop of m as Photos, as
+// described in Photos.
+func (m *Microformat) images(prop string) []Photo {
+	if m == nil {
+		return nil
+	}
+	var photos []Photo
+	for _, v := range m.Properties[prop] {
+		var photo Photo
+		switch v := v.(type) {
+		case string:
+			photo.URL = v
+		case map[string]string:
+			photo.URL, photo.Alt = v["value"], v["alt"]
+		case *Microformat:
+			if v != nil {
+				photo.URL = v.Value
+				if photo.URL == "" {
+					photo.URL = firstString(v, "url")
+				}
+			}
+		}
+		if photo.URL != "" {
+			photos = append(photos, photo)
+		}
+	}
+	return photos
+}
+
+// Categories returns the category property values of m as strings, such as
+// the tags of an h-entry, in order and without duplicates.  Nested
+// microformats, such as the h-card of a person tagged in a post, use their
+// name, or else their value or url.  Empty values are omitted.
+func (m *Microformat) Categories() []string {
+	if m == nil {
+		return nil
+	}
+	var categories []string
+	seen := make(map[string]bool)
+	for _, v := range m.Properties["category"] {
+		s := valueString(v)
+		if mf, ok := v.(*Microformat); ok && mf != nil {
+			if name := firstString(mf, "name"); name != "" {
+				s = name
+			}
+		}
+		if s != "" && !seen[s] {
+			seen[s] = true
+			categories = append(categories, s)
+		}
+	}
+	return categories
+}
+
+// ReplyContext is a post that an entry is in reply to.  The page either
+// embeds a citation of the post, usually as a nested h-cite, or only links to
+// it, in which case the caller may fetch URL (such as with ParseURL) to get
+// the context to display.
+type ReplyContext struct {
+	// URL is the URL of the post.  It may be empty for an embedded citation
+	// that has no url property.
+	URL string
+
+	// Cite is the embedded citation of the post, or nil if the page only
+	// links to it.
+	Cite *HCite
+}
+
+// ReplyContexts returns the in-reply-to property values of entry as
+// ReplyContexts, in order.  Nested h-cite (or h-entry) microformats are
+// mapped to an HCite, as they are by AsHEntry, while string values are bare
+// links with no Cite.  URLs are resolved against the page's BaseURL.
+func (d *Data) ReplyContexts(entry *Microformat) []ReplyContext {
+	if entry == nil {
+		return nil
+	}
+	var base *url.URL
+	if d != nil {
+		base = d.BaseURL
+	}
+	var contexts []ReplyContext
+	for _, cite := range citations(entry, "in-reply-to") {
+		if cite.URL != "" {
+			cite.URL = expandURL(cite.URL, base)
+		}
+		context := ReplyContext{URL: cite.URL}
+		if cite.Source != nil {
+			context.Cite = cite
+		}
+		contexts = append(contexts, context)
+	}
+	return contexts
+}
+
+// responseProperties are the properties of an entry that respond to another
+// post, in the order they are checked by IsResponseTo.
+var responseProperties = []string{"in-reply-to", "like-of", "repost-of", "bookmark-of"}
+
+// IsResponseTo returns whether m responds to the post at target, such as when
+// matching received webmentions to the post they mention.  The in-reply-to,
+// like-of, repost-of, and bookmark-of properties of m are checked in that
+// order, and kind is the name of the first one with a value that matches
+// target: either a URL, or a nested h-cite (or h-entry) with a url property
+// that matches.  URLs are compared with their scheme and host lowercased and
+// any default port removed.
+func IsResponseTo(m *Microformat, target string) (kind string, ok bool) {
+	if m == nil || target == "" {
+		return "", false
+	}
+	target = normalizeURL(target, false)
+	for _, prop := range responseProperties {
+		for _, v := range m.Properties[prop] {
+			var urls []string
+			switch v := v.(type) {
+			case string:
+				urls = []string{v}
+			case *Microformat:
+				if v != nil && (v.hasType("h-cite") || v.hasType("h-entry")) {
+					urls = allStrings(v, "url")
+				}
+			}
+			for _, u := range urls {
+				if normalizeURL(u, false) == target {
+					return prop, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// Preview returns a short plain text preview of m, as shown by feed readers.
+// This is m's summary property if it has one, or else the plain text value of
+// its content property.  Runs of whitespace are collapsed to a single space.
+// If the text is longer than maxRunes characters, it is truncated on a word
+// boundary and an ellipsis ("…") is appended, so that the result is at most
+// maxRunes characters long.  A single word longer than the limit is cut
+// mid-word.  If maxRunes is zero or negative, the text is not truncated.
+func (m *Microformat) Preview(maxRunes int) string {
+	if m == nil {
+		return ""
+	}
+	text := firstString(m, "summary")
+	if text == "" {
+		text = firstString(m, "content")
+	}
+	text = strings.Join(strings.Fields(text), " ")
+
+	runes := []rune(text)
+	if maxRunes <= 0 || len(runes) <= maxRunes {
+		return text
+	}
+	cut := maxRunes - 1 // leave room for the ellipsis
+	if i := lastSpace(runes[:cut+1]); i > 0 {
+		cut = i
+	}
+	return strings.TrimRight(string(runes[:cut]), " ") + "…"
+}
+
+// FirstParagraph returns the plain text of the first paragraph of m's content
+// property, such as the opening of an h-entry's e-content, for use as a
+// summary.  This is the text of the first <p> element in the html of the
+// content with any text, so headings, images, and figures that precede it
+// are skipped.  If the content has no such paragraph, or is not embedded
+// markup, its whole plain text value is returned instead.  In either case,
+// runs of whitespace are collapsed to a single space.
+func (m *Microformat) FirstParagraph() string {
+	if m == nil || len(m.Properties["content"]) == 0 {
+		return ""
+	}
+	v := m.Properties["content"][0]
+	text := valueString(v)
+	for _, n := range parseMarkup(v) {
+		if p := firstParagraph(n); p != "" {
+			text = p
+			break
+		}
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// firstParagraph returns the text of the first <p> element with any text in
+// the tree rooted at node, or an empty string if there is none.
+func firstParagraph(node *html.Node) string {
+	if isAtom(node, atom.P) {
+		if text := strings.TrimSpace(getTextContent(node, nil)); text != "" {
+			return text
+		}
+		return ""
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if text := firstParagraph(c); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// lastSpace returns the index of the last space in runes, or -1 if there is
+// none.
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == ' ' {
+			return i
+		}
+	}
+	return -1
+}
+
+// walkItems calls fn for each microformat on the page, in the order described
+// in FindByProperty.
+func (d *Data) walkItems(fn func(*Microformat)) {
+	if d == nil {
+		return
+	}
+	for _, item := range d.Items {
+		walkMicroformat(item, fn)
+	}
+}
+
+// walkMicroformat calls fn for m and each microformat nested within it.
+func walkMicroformat(m *Microformat, fn func(*Microformat)) {
+	if m == nil {
+		return
+	}
+	fn(m)
+
+	names := make([]string, 0, len(m.Properties))
+	for name := range m.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range m.Properties[name] {
+			if v, ok := v.(*Microformat); ok {
+				walkMicroformat(v, fn)
+			}
+		}
+	}
+	for _, child := range m.Children {
+		walkMicroformat(child, fn)
+	}
+}
+
+// valueString returns the string representation of the property value v, as
+// described in FlatProperties.
+func valueString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]string:
+		return v["value"]
+	case *Microformat:
+		if v == nil {
+			return ""
+		}
+		if v.Value != "" {
+			return v.Value
+		}
+		if s := firstString(v, "name"); s != "" {
+			return s
+		}
+		return firstString(v, "url")
+	}
+	return ""
+}
+
+// firstString returns the string representation of the first value of prop
+// in m that has one, as described in FlatProperties.
+func firstString(m *Microformat, prop string) string {
+	for _, v := range m.Properties[prop] {
+		if s := valueString(v); s != "" {
+			return s
+		}
+	}
+	return ""
+}
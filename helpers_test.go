@@ -0,0 +1,573 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func Test_FlatProperties(t *testing.T) {
+	tests := []struct {
+		html string
+		want map[string]string
+	}{
+		{`<div class="h-entry"></div>`, map[string]string{}},
+		{
+			`<div class="h-entry">
+				<h1 class="p-name">Title</h1>
+				<a class="p-category" href="/a">a</a><a class="p-category" href="/b">b</a>
+				<img class="u-photo" src="/photo.png" alt="Photo">
+				<div class="e-content"><p>Hello <b>world</b></p></div>
+				<div class="p-author h-card"><a class="u-url" href="/jane">Jane</a></div>
+				<div class="u-in-reply-to h-cite"><a class="u-url" href="/post">Post</a></div>
+			</div>`,
+			map[string]string{
+				"name":        "Title",
+				"category":    "a, b",
+				"photo":       "http://example.com/photo.png",
+				"content":     "Hello world",
+				"author":      "Jane",
+				"in-reply-to": "http://example.com/post",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		if diff := cmp.Diff(tt.want, items[0].FlatProperties()); diff != "" {
+			t.Errorf("FlatProperties(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+
+	var m *Microformat
+	if got := m.FlatProperties(); got != nil {
+		t.Errorf("FlatProperties on nil Microformat returned %v, want nil", got)
+	}
+}
+
+func Test_TypeCounts(t *testing.T) {
+	tests := []struct {
+		html string
+		want map[string]int
+	}{
+		{``, map[string]int{}},
+		{
+			`<div class="h-feed">
+				<div class="h-entry"><a class="p-author h-card" href="/a">A</a></div>
+				<div class="h-entry">
+					<div class="h-entry"><div class="h-cite"></div></div>
+				</div>
+			</div>
+			<div class="h-card h-org"></div>
+			<div class="vcard"><span class="fn">B</span></div>`,
+			map[string]int{"h-feed": 1, "h-entry": 3, "h-card": 3, "h-org": 1, "h-cite": 1},
+		},
+	}
+
+	base, _ := url.Parse("http://example.com/")
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(tt.html), base)
+		if diff := cmp.Diff(tt.want, data.TypeCounts()); diff != "" {
+			t.Errorf("TypeCounts(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+
+	var d *Data
+	if got := d.TypeCounts(); len(got) != 0 {
+		t.Errorf("TypeCounts on nil Data returned %v, want empty map", got)
+	}
+}
+
+func Test_FindByProperty(t *testing.T) {
+	doc := `<div class="h-feed">
+		<div class="h-entry">
+			<a class="u-url" href="/one">one</a>
+			<img class="u-photo" src="/one.jpg" alt="One">
+			<div class="p-author h-card"><a class="u-url p-name" href="/jane">Jane</a><img class="u-photo" src="/jane.jpg"></div>
+		</div>
+		<div class="h-entry"><a class="u-url" href="/two">two</a></div>
+		<div class="h-entry">
+			<a class="u-url" href="/three">three</a>
+			<div class="h-cite"><a class="u-url" href="/one">cited</a></div>
+		</div>
+	</div>`
+
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+	feed := data.Items[0]
+	one, two, three := feed.Children[0], feed.Children[1], feed.Children[2]
+	author := one.Properties["author"][0].(*Microformat)
+	cite := three.Children[0]
+
+	isURL := func(u string) func(any) bool {
+		return func(v any) bool { return v == u }
+	}
+	anything := func(any) bool { return true }
+
+	tests := []struct {
+		description string
+		prop        string
+		match       func(any) bool
+		want        []*Microformat
+	}{
+		{"url one", "url", isURL("http://example.com/one"), []*Microformat{one, cite}},
+		{"url two", "url", isURL("http://example.com/two"), []*Microformat{two}},
+		{"no match", "url", isURL("http://example.com/none"), nil},
+		{"any photo", "photo", anything, []*Microformat{one, author}},
+		{"any url", "url", anything, []*Microformat{one, author, two, three, cite}},
+		{"missing property", "nope", anything, nil},
+	}
+
+	for _, tt := range tests {
+		got := data.FindByProperty(tt.prop, tt.match)
+		if len(got) != len(tt.want) {
+			t.Errorf("FindByProperty(%q) %s returned %d items, want %d", tt.prop, tt.description, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("FindByProperty(%q) %s item %d is %v, want %v", tt.prop, tt.description, i, got[i].Properties, tt.want[i].Properties)
+			}
+		}
+	}
+
+	var d *Data
+	if got := d.FindByProperty("url", anything); got != nil {
+		t.Errorf("FindByProperty on nil Data returned %v, want nil", got)
+	}
+}
+
+func Test_EntriesByDate(t *testing.T) {
+	doc := `<div class="h-feed">
+		<div class="h-entry"><p class="p-name">b</p><time class="dt-published" datetime="2024-01-02">Jan 2</time></div>
+		<div class="h-entry"><p class="p-name">undated 1</p></div>
+		<div class="h-entry"><p class="p-name">d</p><time class="dt-published" datetime="2024-01-03T09:00:00+01:00">Jan 3</time>
+			<div class="u-in-reply-to h-entry"><p class="p-name">cited</p><time class="dt-published" datetime="2030-01-01">2030</time></div>
+			<div class="h-entry"><p class="p-name">c</p><time class="dt-published" datetime="2024-01-03 07:00">Jan 3</time></div></div>
+		<div class="h-entry"><p class="p-name">undated 2</p><time class="dt-published" datetime="soon">soon</time></div>
+		<div class="h-card"><p class="p-name">not an entry</p><time class="dt-published" datetime="2024-01-01">Jan 1</time></div>
+	</div>
+	<div class="h-entry"><p class="p-name">a</p><time class="dt-published" datetime="2023-12-31T23:00:00Z">Dec 31</time></div>`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	names := func(entries []*Microformat) []string {
+		var s []string
+		for _, e := range entries {
+			s = append(s, firstString(e, "name"))
+		}
+		return s
+	}
+	tests := []struct {
+		descending bool
+		want       []string
+	}{
+		{false, []string{"a", "b", "c", "d", "undated 1", "undated 2"}},
+		{true, []string{"d", "c", "b", "a", "undated 1", "undated 2"}},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, names(data.EntriesByDate(tt.descending))); diff != "" {
+			t.Errorf("EntriesByDate(%t) mismatch (-want +got):\n%s", tt.descending, diff)
+		}
+	}
+
+	if got := (*Data)(nil).EntriesByDate(true); got != nil {
+		t.Errorf("EntriesByDate on nil Data returned %v, want nil", got)
+	}
+}
+
+func Test_ContentLinks(t *testing.T) {
+	doc := `<div class="h-entry"><a class="u-url" href="https://example.com/post/1">#</a>
+		<div class="e-content"><p>See <em><a href="/a" rel="nofollow ugc">the <b>first</b> post</a></em>
+		and <a href="b">b</a>.</p><a name="anchor">no href</a>
+		<a href="/outer">outer <a href="/inner">inner</a></a></div></div>`
+
+	tests := []struct {
+		base *url.URL
+		want []Link
+	}{
+		{
+			// relative URLs are expanded when parsing
+			&url.URL{Scheme: "http", Host: "example.net", Path: "/"},
+			[]Link{
+				{URL: "http://example.net/a", Text: "the first post", Rel: []string{"nofollow", "ugc"}},
+				{URL: "http://example.net/b", Text: "b"},
+				{URL: "http://example.net/outer", Text: "outer"},
+				{URL: "http://example.net/inner", Text: "inner"},
+			},
+		},
+		{
+			// otherwise, they are resolved against the url property
+			nil,
+			[]Link{
+				{URL: "https://example.com/a", Text: "the first post", Rel: []string{"nofollow", "ugc"}},
+				{URL: "https://example.com/b", Text: "b"},
+				{URL: "https://example.com/outer", Text: "outer"},
+				{URL: "https://example.com/inner", Text: "inner"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(doc), tt.base)
+		got := data.Items[0].ContentLinks()
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("ContentLinks with base %v mismatch (-want +got):\n%s", tt.base, diff)
+		}
+	}
+
+	plain := &Microformat{Properties: map[string][]any{"content": {"text only"}}}
+	if got := plain.ContentLinks(); got != nil {
+		t.Errorf("ContentLinks of plain text content returned %v, want nil", got)
+	}
+}
+
+func Test_ContentLinksFollowable(t *testing.T) {
+	doc := `<div class="h-entry"><div class="e-content">
+		<a href="/a">a</a>
+		<a href="/b" rel="nofollow">b</a>
+		<a href="/c" rel="UGC">c</a>
+		<a href="/d" rel="external sponsored">d</a>
+		<a href="/e" rel="external noopener">e</a>
+	</div></div>`
+	entry := parseItems(doc)[0]
+
+	want := []Link{
+		{URL: "http://example.com/a", Text: "a"},
+		{URL: "http://example.com/e", Text: "e", Rel: []string{"external", "noopener"}},
+	}
+	if diff := cmp.Diff(want, entry.ContentLinksFollowable()); diff != "" {
+		t.Errorf("ContentLinksFollowable mismatch (-want +got):\n%s", diff)
+	}
+	if got := len(entry.ContentLinks()); got != 5 {
+		t.Errorf("ContentLinks returned %d links, want 5", got)
+	}
+
+	if got := (*Microformat)(nil).ContentLinksFollowable(); got != nil {
+		t.Errorf("ContentLinksFollowable of nil returned %v, want nil", got)
+	}
+}
+
+func Test_AllURLs(t *testing.T) {
+	// properties and rels are mixed, and out of alphabetical order
+	doc := `<link rel="webmention" href="/webmention">
+	<div class="h-entry">
+		<a class="u-url p-name" href="/post">Post</a>
+		<a rel="me" href="https://social.example/@jane">me</a>
+		<img class="u-photo" src="/photo.jpg" alt="A photo">
+		<a class="u-in-reply-to h-cite" href="https://other.example/note">note</a>
+		<div class="e-content">See <a href="/post">this</a> and <a href="https://news.example/">news</a>.
+			<a href="mailto:jane@example.com">mail</a>
+			<a class="u-syndication" href="https://archive.example/post">archived</a></div>
+		<div class="p-author h-card"><a class="p-name u-url" href="/jane">Jane</a></div>
+		<span class="u-uid">tag:example.com,2024:post</span>
+		<div class="h-card"><a href="https://john.example/">John</a></div>
+	</div>
+	<a rel="alternate" href="/feed">feed</a>`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	want := []string{
+		"http://example.com/webmention",
+		"http://example.com/post",
+		"https://social.example/@jane",
+		"http://example.com/photo.jpg",
+		"https://other.example/note",
+		"https://news.example/",
+		"https://archive.example/post",
+		"http://example.com/jane",
+		"https://john.example/", // implied url
+		"http://example.com/feed",
+	}
+	if diff := cmp.Diff(want, data.AllURLs()); diff != "" {
+		t.Errorf("AllURLs mismatch (-want +got):\n%s", diff)
+	}
+
+	// without the document order, the same URLs are returned in property and
+	// rel order
+	var unmarshaled Data
+	b, _ := json.Marshal(data)
+	if err := json.Unmarshal(b, &unmarshaled); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %v", err)
+	}
+	got := unmarshaled.AllURLs()
+	sort.Strings(got)
+	sort.Strings(want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("AllURLs of unmarshaled Data mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := (*Data)(nil).AllURLs(); got != nil {
+		t.Errorf("AllURLs on nil Data returned %q, want nil", got)
+	}
+}
+
+func Test_Photos(t *testing.T) {
+	doc := `<div class="h-card">
+		<img class="u-photo" src="/a.jpg">
+		<img class="u-photo" src="/b.jpg" alt="Jane at the beach">
+		<a class="u-photo" href="/c.jpg">photo</a>
+		<img class="u-photo" src="/d.jpg" alt="">
+	</div>`
+
+	want := []Photo{
+		{URL: "http://example.com/a.jpg"},
+		{URL: "http://example.com/b.jpg", Alt: "Jane at the beach"},
+		{URL: "http://example.com/c.jpg"},
+		{URL: "http://example.com/d.jpg"},
+	}
+	items := parseItems(doc)
+	if diff := cmp.Diff(want, items[0].Photos()); diff != "" {
+		t.Errorf("Photos mismatch (-want +got):\n%s", diff)
+	}
+
+	m := &Microformat{Properties: map[string][]any{"photo": {
+		"",
+		map[string]string{"value": "http://example.com/e.jpg", "alt": "E"},
+		&Microformat{Value: "http://example.com/f.jpg"},
+		&Microformat{Properties: map[string][]any{"url": {"http://example.com/g.jpg"}}},
+	}}}
+	want = []Photo{
+		{URL: "http://example.com/e.jpg", Alt: "E"},
+		{URL: "http://example.com/f.jpg"},
+		{URL: "http://example.com/g.jpg"},
+	}
+	if diff := cmp.Diff(want, m.Photos()); diff != "" {
+		t.Errorf("Photos mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_Categories(t *testing.T) {
+	doc := `<div class="h-entry">
+		<a class="p-category" href="/tag/go">go</a>
+		<a class="u-category h-card" href="https://jane.example/">Jane Doe</a>
+		<span class="p-category">indieweb</span>
+		<a class="p-category" href="/tag/go">go</a>
+		<span class="p-category h-card"><a class="p-name u-url" href="https://jane.example/">Jane Doe</a></span>
+		<data class="u-category h-card" value="https://john.example/"></data>
+	</div>`
+
+	want := []string{"go", "Jane Doe", "indieweb", "https://john.example/"}
+	items := parseItems(doc)
+	if diff := cmp.Diff(want, items[0].Categories()); diff != "" {
+		t.Errorf("Categories mismatch (-want +got):\n%s", diff)
+	}
+
+	var m *Microformat
+	if got := m.Categories(); got != nil {
+		t.Errorf("Categories of nil Microformat returned %q, want nil", got)
+	}
+}
+
+func Test_ReplyContexts(t *testing.T) {
+	doc := `<div class="h-entry">
+		<div class="u-in-reply-to h-cite">
+			<a class="u-url p-name" href="/post">A post</a>
+			<span class="p-author h-card">Jane</span>
+		</div>
+		<a class="u-in-reply-to" href="https://other.example/note">a note</a>
+		<div class="p-in-reply-to h-cite"><span class="p-name">Unlinked</span></div>
+	</div>`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+	entry := data.Items[0]
+
+	got := data.ReplyContexts(entry)
+	want := []ReplyContext{
+		{URL: "http://example.com/post", Cite: &HCite{
+			Name:   "A post",
+			URL:    "http://example.com/post",
+			Author: &HCard{Name: "Jane"},
+		}},
+		{URL: "https://other.example/note"},
+		{Cite: &HCite{Name: "Unlinked"}},
+	}
+	opts := cmp.Options{ignoreSource, cmpopts.IgnoreFields(HCite{}, "Source")}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("ReplyContexts mismatch (-want +got):\n%s", diff)
+	}
+
+	// relative values in data built by hand are resolved against BaseURL
+	entry = &Microformat{Properties: map[string][]any{"in-reply-to": {"/a"}}}
+	got = (&Data{BaseURL: base}).ReplyContexts(entry)
+	if diff := cmp.Diff([]ReplyContext{{URL: "http://example.com/a"}}, got); diff != "" {
+		t.Errorf("ReplyContexts mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := data.ReplyContexts(nil); got != nil {
+		t.Errorf("ReplyContexts(nil) returned %v, want nil", got)
+	}
+}
+
+func Test_IsResponseTo(t *testing.T) {
+	doc := `<div class="h-entry"><a class="u-in-reply-to" href="https://Example.org:443/reply">r</a></div>
+	<div class="h-entry"><a class="u-like-of" href="https://example.org/like">l</a></div>
+	<div class="h-entry"><a class="u-repost-of" href="https://example.org/repost">r</a></div>
+	<div class="h-entry"><a class="u-bookmark-of" href="https://example.org/bookmark">b</a></div>
+	<div class="h-entry"><div class="u-in-reply-to h-cite"><a class="u-url" href="/local">local</a>
+		<a class="u-url" href="https://example.org/cite">cite</a></div></div>
+	<div class="h-entry"><div class="u-like-of h-entry"><a class="u-url p-name" href="https://example.org/entry">e</a></div></div>
+	<div class="h-entry"><div class="p-in-reply-to h-card"><a class="u-url p-name" href="https://example.org/card">c</a></div></div>`
+	items := parseItems(doc)
+
+	tests := []struct {
+		item     int
+		target   string
+		wantKind string
+		wantOK   bool
+	}{
+		{0, "https://example.org/reply", "in-reply-to", true},
+		{0, "HTTPS://EXAMPLE.ORG/reply", "in-reply-to", true},
+		{0, "https://example.org/REPLY", "", false},
+		{1, "https://example.org/like", "like-of", true},
+		{1, "https://example.org/reply", "", false},
+		{2, "https://example.org/repost", "repost-of", true},
+		{3, "https://example.org/bookmark", "bookmark-of", true},
+		// nested h-cite with more than one url
+		{4, "https://example.org/cite", "in-reply-to", true},
+		{4, "http://example.com/local", "in-reply-to", true},
+		// nested h-entry
+		{5, "https://example.org/entry", "like-of", true},
+		// other nested microformats are not citations
+		{6, "https://example.org/card", "", false},
+		{0, "", "", false},
+	}
+
+	for _, tt := range tests {
+		kind, ok := IsResponseTo(items[tt.item], tt.target)
+		if kind != tt.wantKind || ok != tt.wantOK {
+			t.Errorf("IsResponseTo(item %d, %q) returned %q, %v, want %q, %v", tt.item, tt.target, kind, ok, tt.wantKind, tt.wantOK)
+		}
+	}
+
+	if kind, ok := IsResponseTo(nil, "https://example.org/"); kind != "" || ok {
+		t.Errorf("IsResponseTo(nil) returned %q, %v, want \"\", false", kind, ok)
+	}
+}
+
+func Test_Preview(t *testing.T) {
+	content := map[string]string{
+		"value": "The quick brown\n\tfox jumps over the lazy dog",
+		"html":  "<p>The quick brown</p>\n\t<p>fox jumps over the lazy dog</p>",
+	}
+	tests := []struct {
+		props    map[string][]any
+		maxRunes int
+		want     string
+	}{
+		{map[string][]any{}, 10, ""},
+		{map[string][]any{"summary": {"A summary"}, "content": {content}}, 0, "A summary"},
+		{map[string][]any{"summary": {"A longer summary"}, "content": {content}}, 10, "A longer…"},
+		{map[string][]any{"content": {content}}, 0, "The quick brown fox jumps over the lazy dog"},
+		{map[string][]any{"content": {content}}, 100, "The quick brown fox jumps over the lazy dog"},
+		{map[string][]any{"content": {content}}, 16, "The quick brown…"},
+		{map[string][]any{"content": {content}}, 15, "The quick…"},
+		{map[string][]any{"content": {"Supercalifragilistic"}}, 6, "Super…"},
+		{map[string][]any{"content": {"héllo wörld ünïcode"}}, 13, "héllo wörld…"},
+	}
+
+	for _, tt := range tests {
+		m := &Microformat{Properties: tt.props}
+		if got := m.Preview(tt.maxRunes); got != tt.want {
+			t.Errorf("Preview(%d) of %v returned %q, want %q", tt.maxRunes, tt.props, got, tt.want)
+		}
+	}
+}
+
+func Test_IsEmpty(t *testing.T) {
+	tests := []struct {
+		data *Data
+		want bool
+	}{
+		{nil, true},
+		{&Data{}, true},
+		{&Data{Items: []*Microformat{}, Rels: map[string][]string{}, RelURLs: map[string]*RelURL{}}, true},
+		{Parse(strings.NewReader(`<p>nothing here</p>`), nil), true},
+		{Parse(strings.NewReader(`<div class="h-card">Jane</div>`), nil), false},
+		{Parse(strings.NewReader(`<a rel="me" href="/jane">Jane</a>`), nil), false},
+		{&Data{RelURLs: map[string]*RelURL{"/jane": {}}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.data.IsEmpty(); got != tt.want {
+			t.Errorf("IsEmpty(%+v) returned %t, want %t", tt.data, got, tt.want)
+		}
+	}
+}
+
+func Test_PrimaryType(t *testing.T) {
+	tests := []struct {
+		m    *Microformat
+		want string
+	}{
+		{nil, ""},
+		{&Microformat{}, ""},
+		{&Microformat{Type: []string{"h-entry"}}, "h-entry"},
+		{&Microformat{Type: []string{"h-card", "h-org"}}, "h-card"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.PrimaryType(); got != tt.want {
+			t.Errorf("PrimaryType(%v) returned %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func Test_FirstParagraph(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{`<div class="h-entry"><span class="p-name">No content</span></div>`, ""},
+		{`<div class="h-entry"><div class="e-content"><p>One
+			two</p><p>Three</p></div></div>`, "One two"},
+		{`<div class="h-entry"><div class="e-content"><h2>Title</h2><img src="a.jpg" alt="A"><p></p><p><img src="b.jpg"></p><p>First <em>real</em> paragraph</p></div></div>`, "First real paragraph"},
+		{`<div class="h-entry"><div class="e-content"><figure><img src="a.jpg"><figcaption>Caption</figcaption></figure><section><p>Nested</p></section></div></div>`, "Nested"},
+		{`<div class="h-entry"><div class="e-content">Just some <b>text</b>
+			and more</div></div>`, "Just some text and more"},
+		{`<div class="h-entry"><div class="p-content">Plain   text</div></div>`, "Plain text"},
+		{`<div class="h-entry"><div class="e-content h-cite"><p>Cited</p></div></div>`, "Cited"},
+	}
+
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		if got := items[0].FirstParagraph(); got != tt.want {
+			t.Errorf("FirstParagraph(%q) returned %q, want %q", tt.html, got, tt.want)
+		}
+	}
+
+	var m *Microformat
+	if got := m.FirstParagraph(); got != "" {
+		t.Errorf("FirstParagraph of nil Microformat returned %q, want empty string", got)
+	}
+}
+
+func Test_ValueString(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{nil, ""},
+		{1, ""},
+		{"a", "a"},
+		{map[string]string{"html": "<b>a</b>", "value": "a"}, "a"},
+		{(*Microformat)(nil), ""},
+		{&Microformat{Value: "v", Properties: map[string][]any{"name": {"n"}}}, "v"},
+		{&Microformat{Properties: map[string][]any{"name": {"n"}, "url": {"u"}}}, "n"},
+		{&Microformat{Properties: map[string][]any{"url": {"u"}}}, "u"},
+	}
+
+	for _, tt := range tests {
+		if got := valueString(tt.value); got != tt.want {
+			t.Errorf("valueString(%v) returned %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for parsing microformats in the documents of
+// <iframe> elements.
+
+package microformats
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// WithIframeResolver parses the documents of <iframe> elements, as supplied
+// by resolve, for composite pages whose microformats are in framed content.
+// The parser never fetches iframes itself.  resolve is called with the src
+// attribute of each iframe, resolved against the base URL, and returns the
+// framed document, or nil to leave the iframe alone.  By default, iframes are
+// ignored, as they are in the microformats2 parsing specification.
+//
+// The <body> of each framed document is walked in place of the content of the
+// iframe element, so its microformats are merged into the results as if they
+// appeared there: top-level microformats in it become top-level items, or
+// children or properties of a microformat enclosing the iframe, and its rel
+// links are collected.  Relative URLs in the framed document are resolved
+// against the src URL; its <head> is not walked, so a <base> element or rel
+// links there are not used.  Framed content is not included in the text or
+// html values of properties enclosing the iframe.  An iframe whose src is
+// already being walked, such as a page that frames itself, is not resolved
+// again.
+func WithIframeResolver(resolve func(src string) io.Reader) Option {
+	return func(p *parser) {
+		p.iframeResolver = resolve
+	}
+}
+
+// iframeDocument returns the <body> element of the document of node, if it is
+// an <iframe> resolved by the resolver set by WithIframeResolver, along with
+// the URL of the document.
+func (p *parser) iframeDocument(node *html.Node) (*html.Node, *url.URL) {
+	if p.iframeResolver == nil || !isAtom(node, atom.Iframe) {
+		return nil, nil
+	}
+	src := strings.TrimSpace(getAttr(node, "src"))
+	if src == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, nil
+	}
+	u = p.base.ResolveReference(u)
+	src = u.String()
+	if p.iframeSrcs[src] {
+		return nil, nil
+	}
+	r := p.iframeResolver(src)
+	if r == nil {
+		return nil, nil
+	}
+	doc, err := html.ParseWithOptions(r, p.htmlOptions...)
+	if err != nil {
+		return nil, nil
+	}
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		if !isAtom(c, atom.Html) {
+			continue
+		}
+		for b := c.FirstChild; b != nil; b = b.NextSibling {
+			if isAtom(b, atom.Body) {
+				return b, u
+			}
+		}
+	}
+	return nil, nil
+}
+
+// walkIframe walks body, the <body> element of the document at u, as
+// described in WithIframeResolver.
+func (p *parser) walkIframe(body *html.Node, u *url.URL) {
+	if p.iframeSrcs == nil {
+		p.iframeSrcs = make(map[string]bool)
+	}
+	src := u.String()
+	p.iframeSrcs[src] = true
+	prior := p.base
+	p.base = u
+	p.walk(body)
+	p.base = prior
+	delete(p.iframeSrcs, src)
+}
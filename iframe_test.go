@@ -0,0 +1,77 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_WithIframeResolver(t *testing.T) {
+	frames := map[string]string{
+		"http://example.com/frames/entry": `<html><head><base href="http://ignored.example/"></head><body>
+			<div class="h-entry"><a class="p-name u-url" href="post">Framed</a></div>
+			<a rel="license" href="/license">license</a>
+		</body></html>`,
+		"http://example.com/frames/loop": `<div class="h-card"><p class="p-name">Loop</p>
+			<iframe src="/frames/loop"></iframe></div>`,
+	}
+	var resolved []string
+	resolve := func(src string) io.Reader {
+		resolved = append(resolved, src)
+		if s, ok := frames[src]; ok {
+			return strings.NewReader(s)
+		}
+		return nil
+	}
+
+	doc := `<div class="h-feed"><p class="p-name">Feed</p>
+		<iframe src="/frames/entry">fallback</iframe>
+		<iframe src="/frames/missing"></iframe>
+	</div>
+	<iframe src="frames/loop"></iframe>`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base, WithIframeResolver(resolve))
+
+	want := []*Microformat{
+		{
+			Type:       []string{"h-feed"},
+			Properties: map[string][]any{"name": {"Feed"}},
+			Children: []*Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"name": {"Framed"},
+					"url":  {"http://example.com/frames/post"},
+				},
+			}},
+		},
+		{
+			Type:       []string{"h-card"},
+			Properties: map[string][]any{"name": {"Loop"}},
+		},
+	}
+	if diff := cmp.Diff(want, data.Items, ignoreParseState); diff != "" {
+		t.Errorf("Parse with WithIframeResolver items mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string][]string{"license": {"http://example.com/license"}}, data.Rels); diff != "" {
+		t.Errorf("Parse with WithIframeResolver rels mismatch (-want +got):\n%s", diff)
+	}
+	wantResolved := []string{
+		"http://example.com/frames/entry",
+		"http://example.com/frames/missing",
+		"http://example.com/frames/loop",
+	}
+	if diff := cmp.Diff(wantResolved, resolved); diff != "" {
+		t.Errorf("resolver calls mismatch (-want +got):\n%s", diff)
+	}
+
+	// iframes are ignored by default
+	if items := parseItems(doc); len(items) != 1 || len(items[0].Children) != 0 {
+		t.Errorf("Parse without WithIframeResolver returned %+v, want one h-feed without children", items)
+	}
+}
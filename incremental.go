@@ -0,0 +1,342 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for incrementally parsing a document as it is
+// received.
+
+package microformats
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Parser parses microformats incrementally from an HTML document that is
+// received in chunks, such as the body of an HTTP response being proxied.
+// Write document content to the Parser as it arrives, and call Items at any
+// point to get the top-level microformats whose closing tags have been seen
+// so far.  Once the entire document has been written, call Close.
+//
+// Incremental results are an approximation of what Parse would return for
+// the complete document:
+//
+//   - each top-level microformat is parsed on its own, as soon as its closing
+//     tag is seen, so the v1 include pattern can only reference elements
+//     within that microformat
+//   - a <base> element only affects microformats that appear after it
+//   - tags are matched without the full HTML tree construction algorithm, so
+//     badly malformed markup may cause a microformat to be reported early,
+//     late, or at Close
+//   - rels are only collected when the complete document is parsed at Close
+//
+// After Close, Items and Data reflect a full parse of the complete document,
+// identical to calling Parse on it.
+//
+// A Parser is not safe for concurrent use.
+type Parser struct {
+	opts      []Option
+	baseURL   *url.URL // base URL as provided by the caller
+	base      *url.URL // base URL, possibly updated by a <base> element
+	baseFound bool
+
+	// whether v1 root classes are matched case insensitively, set by the
+	// WithCaseInsensitiveV1 option
+	caseInsensitiveV1 bool
+
+	// whether v2 root prefixes are matched case insensitively, set by the
+	// WithCaseInsensitivePrefixes option
+	caseInsensitivePrefixes bool
+
+	// options for parsing HTML, set by the WithHTMLParseOptions option
+	htmlOptions []html.ParseOption
+
+	// attribute marking elements to skip, set by the WithIgnoreAttribute
+	// option
+	ignoreAttr string
+
+	buf    []byte // all document content written so far
+	offset int    // offset into buf of the first unprocessed token
+
+	stack []openElement
+	items []*Microformat
+	data  *Data
+}
+
+// openElement is an element whose start tag has been seen by Parser, but not
+// its end tag.
+type openElement struct {
+	name    string
+	start   int  // offset into buf of the element's start tag
+	root    bool // whether element is a top-level microformat root
+	ignored bool // whether element is skipped by WithIgnoreAttribute
+}
+
+// bufferedToken is a token read by Parser, along with its position in buf.
+type bufferedToken struct {
+	html.Token
+	start, end int
+}
+
+// errParserClosed is returned when writing to a closed Parser.
+var errParserClosed = errors.New("microformats: write to closed Parser")
+
+// NewParser returns a new Parser for an HTML document.  baseURL is the URL
+// this document was retrieved from and is used to expand any relative URLs.
+// If baseURL is nil and the base URL is not referenced in the document,
+// relative URLs are not expanded.  opts configure optional parsing behavior,
+// except for OnMicroformat and OnRel, which have no effect.
+func NewParser(baseURL *url.URL, opts ...Option) *Parser {
+	// callbacks would be called for both incremental and final results
+	opts = append(opts[:len(opts):len(opts)], withoutHandlers)
+	p := &Parser{opts: opts, baseURL: baseURL, base: baseURL}
+	var config parser
+	for _, opt := range opts {
+		opt(&config)
+	}
+	p.caseInsensitiveV1 = config.caseInsensitiveV1
+	p.caseInsensitivePrefixes = config.caseInsensitivePrefixes
+	p.htmlOptions = config.htmlOptions
+	p.ignoreAttr = config.ignoreAttr
+	if p.base == nil {
+		p.base = &url.URL{}
+	}
+	return p
+}
+
+// Write appends b to the document being parsed, parsing any top-level
+// microformats that are completed by it.  Write always consumes all of b,
+// and only returns an error if p has already been closed.
+func (p *Parser) Write(b []byte) (int, error) {
+	if p.data != nil {
+		return 0, errParserClosed
+	}
+	p.buf = append(p.buf, b...)
+	p.process(false)
+	return len(b), nil
+}
+
+// Close signals that the entire document has been written.  Any microformats
+// still open are completed, and the full document is parsed to collect rels
+// and produce final results.
+func (p *Parser) Close() error {
+	if p.data != nil {
+		return nil
+	}
+	p.process(true)
+	p.data = Parse(bytes.NewReader(p.buf), p.baseURL, p.opts...)
+	p.items = p.data.Items
+	return nil
+}
+
+// Items returns the top-level microformats parsed so far.
+func (p *Parser) Items() []*Microformat {
+	return append([]*Microformat(nil), p.items...)
+}
+
+// Data returns the microformats, rels, and rel-urls parsed from the complete
+// document.  Data returns nil until p is closed.
+func (p *Parser) Data() *Data {
+	return p.data
+}
+
+// process tokenizes unprocessed content in p.buf.  Unless final is true, a
+// trailing text or comment token is left unprocessed since it may not be
+// complete yet, as is any raw text element (such as <script>) whose end tag
+// has not yet been seen.
+func (p *Parser) process(final bool) {
+	var tokens []bufferedToken
+	z := html.NewTokenizer(bytes.NewReader(p.buf[p.offset:]))
+	pos := p.offset
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		end := pos + len(z.Raw())
+		tokens = append(tokens, bufferedToken{Token: z.Token(), start: pos, end: end})
+		pos = end
+	}
+
+	n := len(tokens)
+	if !final && n > 0 {
+		// tags are only returned by the tokenizer once complete, but
+		// text and comments may continue in the next write.
+		if tt := tokens[n-1].Type; tt == html.TextToken || tt == html.CommentToken {
+			n--
+		}
+	}
+	for i := 0; i < n; i++ {
+		t := tokens[i]
+		if !final && t.Type == html.StartTagToken && isRawTextElement(t.Data) {
+			// raw text is only processed once it is known to be
+			// complete, so that tokenizing can be resumed after it.
+			j := i + 1
+			if j < n && tokens[j].Type == html.TextToken {
+				j++
+			}
+			if j >= n || tokens[j].Type != html.EndTagToken || tokens[j].Data != t.Data {
+				break
+			}
+		}
+		p.processToken(t)
+		p.offset = t.end
+	}
+
+	if final {
+		for len(p.stack) > 0 {
+			p.popElement(len(p.stack)-1, len(p.buf))
+		}
+	}
+}
+
+// processToken updates the stack of open elements for t, parsing any
+// top-level microformats that are completed.
+func (p *Parser) processToken(t bufferedToken) {
+	switch t.Type {
+	case html.StartTagToken, html.SelfClosingTagToken:
+		if t.DataAtom == atom.Base && !p.baseFound {
+			p.setBase(t.Token)
+		}
+		ignored := p.ignoreAttr != "" && hasTokenAttr(t.Token, p.ignoreAttr)
+		root := !ignored && !p.inRoot() && isRootToken(t.Token, p.caseInsensitiveV1, p.caseInsensitivePrefixes)
+		if isVoidElement(t.Data) {
+			if root {
+				p.parseRoot(t.start, t.end)
+			}
+			return
+		}
+		p.stack = append(p.stack, openElement{name: t.Data, start: t.start, root: root, ignored: ignored})
+	case html.EndTagToken:
+		for i := len(p.stack) - 1; i >= 0; i-- {
+			if p.stack[i].name == t.Data {
+				p.popElement(i, t.end)
+				return
+			}
+		}
+	case html.ErrorToken, html.TextToken, html.CommentToken, html.DoctypeToken:
+		// nothing to do
+	}
+}
+
+// popElement removes the open element at index i and all elements above it
+// from the stack.  If one of them is a microformat root, it is parsed,
+// treating end as the offset into buf where the root ends.
+func (p *Parser) popElement(i, end int) {
+	for j := i; j < len(p.stack); j++ {
+		if p.stack[j].root {
+			start := p.stack[j].start
+			p.stack = p.stack[:i]
+			p.parseRoot(start, end)
+			return
+		}
+	}
+	p.stack = p.stack[:i]
+}
+
+// inRoot returns whether any of the open elements is a microformat root, or
+// is ignored, either of which means that no new root can start.
+func (p *Parser) inRoot() bool {
+	for _, e := range p.stack {
+		if e.root || e.ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// setBase updates the base URL of p from the <base> element t.
+func (p *Parser) setBase(t html.Token) {
+	for _, a := range t.Attr {
+		if a.Key == "href" && a.Val != "" {
+			if newbase, err := url.Parse(a.Val); err == nil {
+				p.base = p.base.ResolveReference(newbase)
+				p.baseFound = true
+			}
+			return
+		}
+	}
+}
+
+// parseRoot parses the microformats in the content of p.buf between start and
+// end, adding them to p.items.
+func (p *Parser) parseRoot(start, end int) {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	if len(p.stack) > 0 {
+		name := p.stack[len(p.stack)-1].name
+		context = &html.Node{Type: html.ElementNode, Data: name, DataAtom: atom.Lookup([]byte(name))}
+	}
+	nodes, err := html.ParseFragmentWithOptions(bytes.NewReader(p.buf[start:end]), context, p.htmlOptions...)
+	if err != nil {
+		return
+	}
+
+	doc := &html.Node{Type: html.DocumentNode}
+	for _, n := range nodes {
+		doc.AppendChild(n)
+	}
+	mp := newParser(doc, p.base, p.opts)
+	mp.baseFound = true
+	mp.walk(doc)
+	p.items = append(p.items, mp.curData.Items...)
+}
+
+// isRootToken returns whether t has a microformats v2 or v1 root class.  If
+// caseInsensitiveV1 is true, v1 root classes are matched case insensitively,
+// and if caseInsensitivePrefixes is true, so are v2 root prefixes.
+func isRootToken(t html.Token, caseInsensitiveV1, caseInsensitivePrefixes bool) bool {
+	for _, a := range t.Attr {
+		if a.Key != "class" {
+			continue
+		}
+		for _, class := range splitTokens(a.Val) {
+			v1class := class
+			if caseInsensitiveV1 {
+				v1class = strings.ToLower(class)
+			}
+			if caseInsensitivePrefixes {
+				class = lowercasePrefix(class)
+			}
+			if _, ok := backcompatRootMap[v1class]; ok || rootClassNames.MatchString(class) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasTokenAttr returns whether t has an attribute with the specified name.
+func hasTokenAttr(t html.Token, name string) bool {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isVoidElement returns whether name identifies an HTML void element, which
+// never has an end tag.
+func isVoidElement(name string) bool {
+	switch name {
+	case "area", "base", "br", "col", "embed", "hr", "img", "input", "keygen",
+		"link", "meta", "param", "source", "track", "wbr":
+		return true
+	}
+	return false
+}
+
+// isRawTextElement returns whether name identifies an element whose content
+// is tokenized as raw text.
+func isRawTextElement(name string) bool {
+	switch name {
+	case "iframe", "noembed", "noframes", "noscript", "script", "style",
+		"textarea", "title", "xmp":
+		return true
+	}
+	return false
+}
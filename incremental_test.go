@@ -0,0 +1,128 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Parser_Incremental(t *testing.T) {
+	base, _ := url.Parse("http://example.com/")
+	doc := `<html><head><title>a < b</title></head><body>` +
+		`<div class="h-card"><a class="p-name u-url" href="/jane">Jane</a></div>` +
+		`<script>if (a<b) { document.write("<div class='h-card'>x</div>") }</script>` +
+		`<a rel="me" href="/me">me</a>` +
+		`<img class="h-card" src="/logo.png" alt="Logo">` +
+		`<article class="h-entry"><p class="p-name">Hello<p>World</article>` +
+		`</body></html>`
+
+	// the number of items expected after writing each chunk of doc
+	chunks := []struct {
+		until string
+		items int
+	}{
+		{`<a class="p-name u-url"`, 0},
+		{`Jane</a>`, 0},
+		{`</div>`, 1},
+		{`<script>if (a<b) {`, 1},
+		{`</script>`, 1},
+		{`alt="Logo">`, 2},
+		{`<article class="h-entry"><p class="p-name">Hello<p>World`, 2},
+		{`</article>`, 3},
+		{`</html>`, 3},
+	}
+
+	p := NewParser(base)
+	var offset int
+	for _, c := range chunks {
+		end := strings.Index(doc, c.until) + len(c.until)
+		// write one byte at a time to exercise resuming partial tokens
+		for ; offset < end; offset++ {
+			if _, err := p.Write([]byte{doc[offset]}); err != nil {
+				t.Fatalf("Write returned error: %v", err)
+			}
+		}
+		if got, want := len(p.Items()), c.items; got != want {
+			t.Errorf("after writing %q, Items returned %d items, want %d", doc[:end], got, want)
+		}
+	}
+
+	items := p.Items()
+	want := Parse(strings.NewReader(doc), base)
+	if diff := cmp.Diff(want.Items, items, ignoreParseState); diff != "" {
+		t.Errorf("incremental items mismatch (-want +got):\n%s", diff)
+	}
+
+	if p.Data() != nil {
+		t.Errorf("Data returned non-nil value before Close")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if diff := cmp.Diff(want, p.Data(), ignoreParseState); diff != "" {
+		t.Errorf("Data mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := p.Write([]byte("<p>")); err == nil {
+		t.Errorf("Write after Close did not return error")
+	}
+}
+
+func Test_Parser_Base(t *testing.T) {
+	base, _ := url.Parse("http://example.com/")
+	doc := `<a class="h-card" href="a">A</a><base href="/dir/"><a class="h-card" href="b">B</a>`
+
+	p := NewParser(base)
+	_, _ = p.Write([]byte(doc))
+	var got []string
+	for _, item := range p.Items() {
+		got = append(got, item.Properties["url"][0].(string))
+	}
+	want := []string{"http://example.com/a", "http://example.com/dir/b"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Items returned urls %v, want %v", got, want)
+	}
+}
+
+func Test_Parser_Close(t *testing.T) {
+	// microformats not closed by the end of the document are parsed on Close
+	p := NewParser(nil)
+	_, _ = p.Write([]byte(`<div class="h-card"><span class="p-name">Jane`))
+	if got := len(p.Items()); got != 0 {
+		t.Errorf("Items returned %d items before Close, want 0", got)
+	}
+	_ = p.Close()
+	if got := len(p.Items()); got != 1 {
+		t.Errorf("Items returned %d items after Close, want 1", got)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<div class="h-card">`)
+	p = NewParser(nil)
+	_, _ = p.Write(buf.Bytes())
+	_ = p.Close()
+	_ = p.Close() // closing twice is a no-op
+	if got := len(p.Data().Items); got != 1 {
+		t.Errorf("Data returned %d items, want 1", got)
+	}
+}
+
+func Test_Parser_IgnoreAttribute(t *testing.T) {
+	doc := `<div data-mf-ignore><pre><div class="h-card">Example</div></pre></div>` +
+		`<div class="h-card" data-mf-ignore>Example</div>` +
+		`<div class="h-card">Jane</div>`
+
+	p := NewParser(nil, WithIgnoreAttribute("data-mf-ignore"))
+	if _, err := p.Write([]byte(doc)); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	want := []*Microformat{{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Jane"}}}}
+	if diff := cmp.Diff(want, p.Items(), ignoreParseState); diff != "" {
+		t.Errorf("incremental items mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for encoding and decoding canonical microformats
+// JSON.
+
+package microformats
+
+import (
+	"encoding/json"
+)
+
+// MarshalJSON encodes d in the canonical JSON representation.  The items,
+// rels, and rel-urls members are always present, as empty arrays and objects
+// if d has none, rather than null, so that a zero Data marshals the same as
+// the result of parsing an empty document:
+//
+//	{"items":[],"rels":{},"rel-urls":{}}
+//
+// MarshalJSON has a value receiver so that this applies to Data values as
+// well as pointers.
+func (d Data) MarshalJSON() ([]byte, error) {
+	// alias type to prevent infinite recursion
+	type data Data
+	if d.Items == nil {
+		d.Items = []*Microformat{}
+	}
+	if d.Rels == nil {
+		d.Rels = map[string][]string{}
+	}
+	if d.RelURLs == nil {
+		d.RelURLs = map[string]*RelURL{}
+	}
+	return json.Marshal(data(d))
+}
+
+// UnmarshalJSON decodes the canonical JSON representation of a microformat,
+// as produced by json.Marshal.  Property values are decoded to the same types
+// used by Parse: JSON strings as strings, objects with a "type" member as
+// nested *Microformat values, and all other objects (such as e-* properties
+// or images with alt text) as map[string]string.
+//
+// Because Data has no unmarshaler of its own, decoding into a Data value
+// uses this method for each of its items.
+func (m *Microformat) UnmarshalJSON(b []byte) error {
+	// alias type to prevent infinite recursion
+	type microformat Microformat
+	var raw struct {
+		*microformat
+		Properties map[string][]json.RawMessage `json:"properties"`
+	}
+	raw.microformat = (*microformat)(m)
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	m.Properties = nil
+	if raw.Properties != nil {
+		m.Properties = make(map[string][]any, len(raw.Properties))
+	}
+	for name, values := range raw.Properties {
+		m.Properties[name] = make([]any, 0, len(values))
+		for _, v := range values {
+			value, err := unmarshalValue(v)
+			if err != nil {
+				return err
+			}
+			m.Properties[name] = append(m.Properties[name], value)
+		}
+	}
+	return nil
+}
+
+// unmarshalValue decodes a single property value, as described in
+// Microformat.UnmarshalJSON.
+func unmarshalValue(b json.RawMessage) (any, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(b, &obj); err != nil {
+		// not an object, so decode as a string or other plain value
+		var v any
+		err := json.Unmarshal(b, &v)
+		return v, err
+	}
+	if obj == nil { // JSON null
+		return nil, nil
+	}
+
+	if _, ok := obj["type"]; ok {
+		m := new(Microformat)
+		err := json.Unmarshal(b, m)
+		return m, err
+	}
+	m := make(map[string]string, len(obj))
+	err := json.Unmarshal(b, &m)
+	return m, err
+}
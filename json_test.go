@@ -0,0 +1,98 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_UnmarshalJSON_RoundTrip(t *testing.T) {
+	tests := []string{
+		`<div class="h-card"><a class="p-name u-url" href="/jane">Jane</a></div>`,
+		`<div class="h-card"><img class="u-photo" src="/photo.png" alt="Jane"></div>`,
+		`<article class="h-entry" id="post">
+			<h1 class="p-name">Title</h1>
+			<div class="e-content" lang="en"><p>Hello <b>world</b></p></div>
+			<a class="p-author h-card" href="/jane">Jane</a>
+			<div class="h-cite"><a class="u-url" href="/child">child</a></div>
+		</article>`,
+		`<div class="vcard"><span class="fn">Jane</span></div>`,
+		`<a rel="me" href="https://social.example/@jane">me</a>`,
+	}
+
+	base, _ := url.Parse("http://example.com/")
+	for _, tt := range tests {
+		want := Parse(strings.NewReader(tt), base)
+		want.BaseURL = nil // not included in JSON
+		b, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("json.Marshal returned error: %v", err)
+		}
+		got := new(Data)
+		if err := json.Unmarshal(b, got); err != nil {
+			t.Fatalf("json.Unmarshal(%s) returned error: %v", b, err)
+		}
+		if diff := cmp.Diff(want, got, ignoreParseState); diff != "" {
+			t.Errorf("round trip of %q mismatch (-want +got):\n%s", tt, diff)
+		}
+	}
+}
+
+func Test_MarshalJSON_Empty(t *testing.T) {
+	empty := `{"items":[],"rels":{},"rel-urls":{}}`
+	tests := []struct {
+		description string
+		data        any
+		want        string
+	}{
+		{"empty document", Parse(strings.NewReader(""), nil), empty},
+		{"zero Data", Data{}, empty},
+		{"zero *Data", &Data{}, empty},
+		{
+			"Data with only rels",
+			&Data{Rels: map[string][]string{"me": {"http://example.com/"}}},
+			`{"items":[],"rels":{"me":["http://example.com/"]},"rel-urls":{}}`,
+		},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.data)
+		if err != nil {
+			t.Fatalf("json.Marshal(%s) returned error: %v", tt.description, err)
+		}
+		if got := string(b); got != tt.want {
+			t.Errorf("json.Marshal(%s) returned %s, want %s", tt.description, got, tt.want)
+		}
+	}
+}
+
+func Test_UnmarshalValue(t *testing.T) {
+	tests := []struct {
+		json string
+		want any
+	}{
+		{`"a"`, "a"},
+		{`null`, nil},
+		{`{"value":"v","alt":"a"}`, map[string]string{"value": "v", "alt": "a"}},
+		{`{"type":["h-card"],"properties":{"name":["a"]},"value":"a"}`, &Microformat{
+			Type:       []string{"h-card"},
+			Properties: map[string][]any{"name": {"a"}},
+			Value:      "a",
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := unmarshalValue(json.RawMessage(tt.json))
+		if err != nil {
+			t.Errorf("unmarshalValue(%q) returned error: %v", tt.json, err)
+		}
+		if diff := cmp.Diff(tt.want, got, ignoreParseState); diff != "" {
+			t.Errorf("unmarshalValue(%q) mismatch (-want +got):\n%s", tt.json, diff)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for converting JSON-LD structured data into
+// microformats.
+
+package microformats
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// WithJSONLDFallback converts schema.org structured data found in
+// <script type="application/ld+json"> elements into microformats, if the
+// document contains no microformats of its own.  Supported types are Person
+// (as h-card), Article and its subtypes BlogPosting, NewsArticle, and
+// SocialMediaPosting (as h-entry), and Event (as h-event).  Items may appear
+// at the top level of a script, in an array, or in an "@graph" array.  Other
+// types are ignored.
+func WithJSONLDFallback() Option {
+	return func(p *parser) {
+		p.jsonldFallback = true
+	}
+}
+
+// jsonldTypes maps schema.org types to their equivalent microformat type.
+var jsonldTypes = map[string]string{
+	"Person":             "h-card",
+	"Article":            "h-entry",
+	"BlogPosting":        "h-entry",
+	"NewsArticle":        "h-entry",
+	"SocialMediaPosting": "h-entry",
+	"Event":              "h-event",
+}
+
+// jsonldProperties maps schema.org properties to microformat properties, for
+// each microformat type.
+var jsonldProperties = map[string]map[string]string{
+	"h-card": {
+		"name":      "name",
+		"url":       "url",
+		"image":     "photo",
+		"email":     "email",
+		"jobTitle":  "job-title",
+		"worksFor":  "org",
+		"telephone": "tel",
+	},
+	"h-entry": {
+		"headline":      "name",
+		"name":          "name",
+		"description":   "summary",
+		"articleBody":   "content",
+		"datePublished": "published",
+		"dateModified":  "updated",
+		"author":        "author",
+		"url":           "url",
+		"image":         "photo",
+		"keywords":      "category",
+	},
+	"h-event": {
+		"name":        "name",
+		"description": "summary",
+		"startDate":   "start",
+		"endDate":     "end",
+		"location":    "location",
+		"url":         "url",
+		"image":       "photo",
+	},
+}
+
+// jsonldURLProperties are the microformat properties resolved as URLs.
+var jsonldURLProperties = map[string]bool{"url": true, "photo": true}
+
+// jsonldItems returns the microformats converted from the JSON-LD scripts in
+// doc, in document order.
+func (p *parser) jsonldItems(doc *html.Node) []*Microformat {
+	var items []*Microformat
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if isAtom(n, atom.Script) && strings.EqualFold(strings.TrimSpace(getAttr(n, "type")), "application/ld+json") {
+			var v any
+			if err := json.Unmarshal([]byte(getTextContent(n.FirstChild, nil)), &v); err == nil {
+				items = append(items, p.jsonldValues(v)...)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return items
+}
+
+// jsonldValues returns the microformats converted from the top-level JSON-LD
+// value v, which may be a single object, an array, or an object with an
+// "@graph" array.
+func (p *parser) jsonldValues(v any) []*Microformat {
+	var items []*Microformat
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			items = append(items, p.jsonldValues(e)...)
+		}
+	case map[string]any:
+		if graph, ok := v["@graph"]; ok {
+			return p.jsonldValues(graph)
+		}
+		if m := p.jsonldObject(v); m != nil {
+			items = append(items, m)
+		}
+	}
+	return items
+}
+
+// jsonldObject converts the JSON-LD object obj to a microformat, or returns
+// nil if obj is not of a supported type.
+func (p *parser) jsonldObject(obj map[string]any) *Microformat {
+	var mfType string
+	for _, t := range jsonldStrings(obj["@type"]) {
+		if mfType = jsonldTypes[strings.TrimPrefix(strings.TrimPrefix(t, "http://schema.org/"), "https://schema.org/")]; mfType != "" {
+			break
+		}
+	}
+	if mfType == "" {
+		return nil
+	}
+
+	m := &Microformat{Type: []string{mfType}, Properties: make(map[string][]any)}
+	if id, ok := obj["@id"].(string); ok && strings.HasPrefix(id, "#") {
+		m.ID = id[1:]
+	}
+
+	// iterate in a stable order, so that properties mapped from more than
+	// one schema.org property (such as headline and name) are consistent.
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		prop := jsonldProperties[mfType][k]
+		if prop == "" || len(m.Properties[prop]) > 0 {
+			continue
+		}
+		values := jsonldList(obj[k])
+		if s, ok := obj[k].(string); ok && prop == "category" {
+			// keywords are commonly given as a comma separated string
+			values = nil
+			for _, kw := range strings.Split(s, ",") {
+				values = append(values, strings.TrimSpace(kw))
+			}
+		}
+		for _, v := range values {
+			if value := p.jsonldValue(prop, v); value != nil {
+				m.Properties[prop] = append(m.Properties[prop], value)
+			}
+		}
+		if len(m.Properties[prop]) == 0 {
+			delete(m.Properties, prop)
+		}
+	}
+	return m
+}
+
+// jsonldValue converts v, a JSON-LD value of the microformat property prop,
+// to a microformat property value.  Nested objects of a supported type are
+// converted to microformats, and other objects use their name, or url for
+// URL properties.
+func (p *parser) jsonldValue(prop string, v any) any {
+	switch v := v.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		if jsonldURLProperties[prop] {
+			return expandURL(v, p.base)
+		}
+		return v
+	case map[string]any:
+		if m := p.jsonldObject(v); m != nil {
+			m.Value = valueString(m)
+			return m
+		}
+		key := "name"
+		if jsonldURLProperties[prop] {
+			key = "url"
+		}
+		if s, ok := v[key].(string); ok {
+			return p.jsonldValue(prop, s)
+		}
+	case float64, bool:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return nil
+}
+
+// jsonldList returns v as a list of values.  Arrays are returned as-is, and
+// other values as a single element list.
+func jsonldList(v any) []any {
+	if l, ok := v.([]any); ok {
+		return l
+	}
+	if v == nil {
+		return nil
+	}
+	return []any{v}
+}
+
+// jsonldStrings returns the string values in v, which may be a string or an
+// array of strings.
+func jsonldStrings(v any) []string {
+	var s []string
+	for _, e := range jsonldList(v) {
+		if str, ok := e.(string); ok {
+			s = append(s, str)
+		}
+	}
+	return s
+}
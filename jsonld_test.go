@@ -0,0 +1,110 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_WithJSONLDFallback(t *testing.T) {
+	article := `<html><head><script type="application/ld+json">
+	{
+		"@context": "https://schema.org",
+		"@graph": [
+			{
+				"@type": "BlogPosting",
+				"@id": "#post",
+				"headline": "Hello",
+				"name": "ignored",
+				"description": "A post",
+				"articleBody": "Hello world",
+				"datePublished": "2024-01-02T03:04:05Z",
+				"url": "/hello",
+				"image": {"@type": "ImageObject", "url": "/hello.jpg"},
+				"keywords": "go, microformats",
+				"author": {"@type": "Person", "name": "Jane", "url": "https://jane.example/"}
+			},
+			{"@type": "WebSite", "name": "Example"}
+		]
+	}
+	</script></head><body><p>no microformats</p></body></html>`
+
+	wantArticle := []*Microformat{{
+		ID:   "post",
+		Type: []string{"h-entry"},
+		Properties: map[string][]any{
+			"name":      {"Hello"},
+			"summary":   {"A post"},
+			"content":   {"Hello world"},
+			"published": {"2024-01-02T03:04:05Z"},
+			"url":       {"http://example.com/hello"},
+			"photo":     {"http://example.com/hello.jpg"},
+			"category":  {"go", "microformats"},
+			"author": {&Microformat{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"url":  {"https://jane.example/"},
+				},
+				Value: "Jane",
+			}},
+		},
+	}}
+
+	tests := []struct {
+		description string
+		html        string
+		opts        []Option
+		want        []*Microformat
+	}{
+		{"without option", article, nil, []*Microformat{}},
+		{"article", article, []Option{WithJSONLDFallback()}, wantArticle},
+		{
+			"microformats present",
+			article + `<div class="h-card">Jane</div>`,
+			[]Option{WithJSONLDFallback()},
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+		{
+			"array of person and event",
+			`<script type="application/ld+json">[
+				{"@type": "Person", "name": "Jane", "worksFor": {"@type": "Organization", "name": "Example"}},
+				{"@type": ["Event"], "name": "Meetup", "startDate": "2024-05-01T18:00", "location": {"@type": "Place", "name": "Library"}}
+			]</script>`,
+			[]Option{WithJSONLDFallback()},
+			[]*Microformat{
+				{
+					Type:       []string{"h-card"},
+					Properties: map[string][]any{"name": {"Jane"}, "org": {"Example"}},
+				},
+				{
+					Type: []string{"h-event"},
+					Properties: map[string][]any{
+						"name":     {"Meetup"},
+						"start":    {"2024-05-01T18:00"},
+						"location": {"Library"},
+					},
+				},
+			},
+		},
+		{
+			"invalid JSON",
+			`<script type="application/ld+json">{"@type": "Person",</script>`,
+			[]Option{WithJSONLDFallback()},
+			[]*Microformat{},
+		},
+	}
+
+	for _, tt := range tests {
+		got := parseItemsWith(tt.html, tt.opts...)
+		if diff := cmp.Diff(tt.want, got, ignoreParseState); diff != "" {
+			t.Errorf("%s: Parse mismatch (-want +got):\n%s", tt.description, diff)
+		}
+	}
+}
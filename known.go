@@ -0,0 +1,133 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes lists of the known microformats vocabularies, for tools
+// that enumerate or validate against them.
+
+package microformats
+
+import "sort"
+
+// knownProperties maps the known microformats2 root types to the property
+// class names defined for them, including their prefixes.  The properties
+// are those listed on the microformats wiki, including common draft
+// properties.
+var knownProperties = map[string][]string{
+	"h-adr": {
+		"p-post-office-box", "p-extended-address", "p-street-address",
+		"p-locality", "p-region", "p-postal-code", "p-country-name", "p-label",
+		"p-geo", "u-geo", "p-latitude", "p-longitude", "p-altitude",
+	},
+	"h-app": {"p-name", "u-url", "u-logo", "u-photo", "p-summary"},
+	"h-card": {
+		"p-name", "p-honorific-prefix", "p-given-name", "p-additional-name",
+		"p-family-name", "p-sort-string", "p-honorific-suffix", "p-nickname",
+		"u-email", "u-logo", "u-photo", "u-url", "u-uid", "p-category", "p-adr",
+		"p-post-office-box", "p-extended-address", "p-street-address",
+		"p-locality", "p-region", "p-postal-code", "p-country-name", "p-label",
+		"p-geo", "u-geo", "p-latitude", "p-longitude", "p-altitude", "p-tel",
+		"p-note", "dt-bday", "u-key", "p-org", "p-job-title", "p-role",
+		"u-impp", "p-sex", "p-gender-identity", "dt-anniversary", "p-pronouns",
+	},
+	"h-cite": {
+		"p-name", "dt-published", "p-author", "u-url", "u-uid",
+		"p-publication", "dt-accessed", "p-content",
+	},
+	"h-entry": {
+		"p-name", "p-summary", "e-content", "dt-published", "dt-updated",
+		"p-author", "p-category", "u-url", "u-uid", "p-location",
+		"u-syndication", "u-in-reply-to", "p-rsvp", "u-like-of", "u-repost-of",
+		"u-bookmark-of", "u-photo", "u-video", "u-audio", "u-featured",
+		"p-comment",
+	},
+	"h-event": {
+		"p-name", "p-summary", "dt-start", "dt-end", "dt-duration", "e-content",
+		"u-url", "p-category", "p-location", "p-attendee",
+	},
+	"h-feed":    {"p-name", "p-author", "u-url", "u-photo", "p-summary"},
+	"h-geo":     {"p-latitude", "p-longitude", "p-altitude"},
+	"h-item":    {"p-name", "u-url", "u-photo"},
+	"h-measure": {"p-num", "p-unit", "p-name"},
+	"h-news": {
+		"p-entry", "p-source-org", "p-dateline", "p-geo", "u-principles",
+	},
+	"h-product": {
+		"p-name", "u-photo", "p-brand", "p-category", "e-description", "u-url",
+		"u-identifier", "p-review", "p-price",
+	},
+	"h-recipe": {
+		"p-name", "p-ingredient", "p-yield", "e-instructions", "dt-duration",
+		"u-photo", "p-summary", "p-author", "dt-published", "p-nutrition",
+		"p-category",
+	},
+	"h-resume": {
+		"p-name", "p-summary", "p-contact", "p-education", "p-experience",
+		"p-skill", "p-affiliation",
+	},
+	"h-review": {
+		"p-name", "p-item", "p-author", "dt-published", "p-rating", "p-best",
+		"p-worst", "e-content", "p-category", "u-url",
+	},
+	"h-review-aggregate": {
+		"p-name", "p-item", "p-rating", "p-best", "p-worst", "p-count",
+		"p-votes",
+	},
+}
+
+// KnownRoots returns the known microformats2 root types, such as "h-card" and
+// "h-entry", in sorted order.  The returned slice is a copy, and may be
+// modified by the caller.
+func KnownRoots() []string {
+	roots := make([]string, 0, len(knownProperties))
+	for root := range knownProperties {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// KnownProperties returns the property class names defined for the known
+// microformats2 root type root, including their prefixes, such as "p-name"
+// and "dt-published", in sorted order.  A property that may be parsed in more
+// than one way, such as h-card's "p-geo" and "u-geo", is listed once for each
+// prefix.  The returned slice is a copy, and may be modified by the caller.
+// KnownProperties returns nil if root is not a known root type.
+func KnownProperties(root string) []string {
+	props, ok := knownProperties[root]
+	if !ok {
+		return nil
+	}
+	props = append([]string(nil), props...)
+	sort.Strings(props)
+	return props
+}
+
+// V1RootClasses returns the classic microformats root class names recognized
+// by the parser, such as "vcard" and "hentry", mapped to the microformats2
+// root type they are parsed as, such as "h-card" and "h-entry".  The returned
+// map is a copy, and may be modified by the caller.
+func V1RootClasses() map[string]string {
+	classes := make(map[string]string, len(backcompatRootMap))
+	for k, v := range backcompatRootMap {
+		classes[k] = v
+	}
+	return classes
+}
+
+// V1PropertyClasses returns the classic microformats property class names
+// recognized within the microformats2 root type root, such as "fn" within
+// "h-card", mapped to the microformats2 property class they are parsed as,
+// such as "p-name".  The returned map is a copy, and may be modified by the
+// caller.  V1PropertyClasses returns nil if root has no classic property
+// classes.
+func V1PropertyClasses(root string) map[string]string {
+	props, ok := backcompatPropertyMap[root]
+	if !ok {
+		return nil
+	}
+	classes := make(map[string]string, len(props))
+	for k, v := range props {
+		classes[k] = v
+	}
+	return classes
+}
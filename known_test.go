@@ -0,0 +1,92 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"sort"
+	"testing"
+)
+
+func Test_KnownRoots(t *testing.T) {
+	roots := KnownRoots()
+	if !sort.StringsAreSorted(roots) {
+		t.Errorf("KnownRoots() returned %v, want sorted", roots)
+	}
+	for _, want := range []string{"h-card", "h-entry", "h-event", "h-feed", "h-review-aggregate"} {
+		if i := sort.SearchStrings(roots, want); i == len(roots) || roots[i] != want {
+			t.Errorf("KnownRoots() returned %v, want it to include %q", roots, want)
+		}
+	}
+
+	// every classic root class is parsed as a known root type
+	for class, root := range V1RootClasses() {
+		if KnownProperties(root) == nil {
+			t.Errorf("V1RootClasses()[%q] is %q, which is not a known root", class, root)
+		}
+	}
+
+	roots[0] = "h-modified"
+	if got := KnownRoots()[0]; got == "h-modified" {
+		t.Errorf("modifying the result of KnownRoots changed later results")
+	}
+}
+
+func Test_KnownProperties(t *testing.T) {
+	tests := []struct {
+		root string
+		want []string // properties that must be included
+	}{
+		{"h-card", []string{"p-name", "u-url", "p-geo", "u-geo", "dt-bday"}},
+		{"h-entry", []string{"e-content", "dt-published", "u-in-reply-to"}},
+		{"h-geo", []string{"p-latitude", "p-longitude", "p-altitude"}},
+		{"h-unknown", nil},
+	}
+
+	for _, tt := range tests {
+		got := KnownProperties(tt.root)
+		if tt.want == nil {
+			if got != nil {
+				t.Errorf("KnownProperties(%q) returned %v, want nil", tt.root, got)
+			}
+			continue
+		}
+		if !sort.StringsAreSorted(got) {
+			t.Errorf("KnownProperties(%q) returned %v, want sorted", tt.root, got)
+		}
+		for _, want := range tt.want {
+			if i := sort.SearchStrings(got, want); i == len(got) || got[i] != want {
+				t.Errorf("KnownProperties(%q) returned %v, want it to include %q", tt.root, got, want)
+			}
+		}
+	}
+
+	props := KnownProperties("h-card")
+	props[0] = "p-modified"
+	if got := KnownProperties("h-card")[0]; got == "p-modified" {
+		t.Errorf("modifying the result of KnownProperties changed later results")
+	}
+}
+
+func Test_V1Classes(t *testing.T) {
+	if got := V1RootClasses()["vcard"]; got != "h-card" {
+		t.Errorf("V1RootClasses()[\"vcard\"] is %q, want %q", got, "h-card")
+	}
+	if got := V1PropertyClasses("h-card")["fn"]; got != "p-name" {
+		t.Errorf("V1PropertyClasses(\"h-card\")[\"fn\"] is %q, want %q", got, "p-name")
+	}
+	if got := V1PropertyClasses("h-unknown"); got != nil {
+		t.Errorf("V1PropertyClasses(\"h-unknown\") returned %v, want nil", got)
+	}
+
+	// modifying the returned maps does not change how documents are parsed
+	roots := V1RootClasses()
+	delete(roots, "vcard")
+	props := V1PropertyClasses("h-card")
+	props["fn"] = "p-modified"
+
+	items := parseItems(`<div class="vcard"><span class="fn">Jane</span></div>`)
+	if len(items) != 1 || !items[0].hasType("h-card") || firstString(items[0], "name") != "Jane" {
+		t.Errorf("parsing after modifying V1RootClasses and V1PropertyClasses returned %v", items)
+	}
+}
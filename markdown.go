@@ -0,0 +1,299 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for converting e-* property values to Markdown.
+
+package microformats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// WithContentMarkdown adds a "markdown" member to the values of e-*
+// properties, holding their content converted to Markdown, in addition to the
+// "value" and "html" members:
+//
+//	"content": [{
+//		"value": "Hello world",
+//		"html": "<p>Hello <a href=\"http://example.com/\">world</a></p>",
+//		"markdown": "Hello [world](http://example.com/)"
+//	}]
+//
+// Only a small, safe subset of HTML is converted: links, <strong> and <b>,
+// <em> and <i>, <code>, paragraphs, ordered and unordered lists, and
+// blockquotes.  Other elements are replaced with their text, and other
+// block-level elements, such as headings, become plain paragraphs.  Images
+// are replaced with their alt text.  Whitespace is collapsed as it is when
+// rendered, except that <br> elements become hard line breaks, and characters
+// that Markdown would interpret as formatting are escaped.  URLs are resolved
+// the same as in the html value.
+//
+// This is not part of the microformats2 parsing specification, and since the
+// markdown member is included when Data is marshaled to JSON, output parsed
+// with this option is not canonical microformats2 JSON.  Nested microformats
+// that are e-* properties are not given a markdown member.
+func WithContentMarkdown() Option {
+	return func(p *parser) {
+		p.contentMarkdown = true
+	}
+}
+
+// markdownBlock is a block of Markdown, such as a paragraph or a list.
+type markdownBlock struct {
+	text string
+	list bool
+}
+
+// markdown returns the content of node converted to Markdown, as described
+// in WithContentMarkdown.
+func (p *parser) markdown(node *html.Node) string {
+	var text []string
+	for _, b := range p.markdownBlocks(node) {
+		text = append(text, b.text)
+	}
+	return strings.Join(text, "\n\n")
+}
+
+// markdownBlocks returns the Markdown blocks for the children of node.  Runs
+// of inline content between block elements become paragraphs.
+func (p *parser) markdownBlocks(node *html.Node) []markdownBlock {
+	var blocks []markdownBlock
+	var inline strings.Builder
+	flush := func() {
+		if text := markdownParagraph(inline.String()); text != "" {
+			blocks = append(blocks, markdownBlock{text: text})
+		}
+		inline.Reset()
+	}
+
+	skip := p.skipFunc()
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if !isMarkdownBlock(c) {
+			p.writeMarkdownInline(&inline, c)
+			continue
+		}
+		if skip != nil && skip(c) {
+			continue
+		}
+		flush()
+		switch c.DataAtom {
+		case atom.Ul, atom.Ol:
+			if text := p.markdownList(c); text != "" {
+				blocks = append(blocks, markdownBlock{text: text, list: true})
+			}
+		case atom.Blockquote:
+			if text := p.markdown(c); text != "" {
+				blocks = append(blocks, markdownBlock{text: prefixLines(text, "> ", ">")})
+			}
+		default:
+			blocks = append(blocks, p.markdownBlocks(c)...)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// isMarkdownBlock returns whether node is a block element, including the
+// table sections that separate table rows.
+func isMarkdownBlock(node *html.Node) bool {
+	if node.Type != html.ElementNode || node.Namespace != "" {
+		return false
+	}
+	return blockElements[node.DataAtom] || isAtom(node, atom.Tbody, atom.Thead, atom.Tfoot)
+}
+
+// markdownList returns the items of node, a <ul> or <ol> element, as a
+// Markdown list.  Ordered lists are numbered from their start attribute.
+func (p *parser) markdownList(node *html.Node) string {
+	n := 1
+	if start, err := strconv.Atoi(strings.TrimSpace(getAttr(node, "start"))); err == nil {
+		n = start
+	}
+	skip := p.skipFunc()
+
+	var items []string
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if !isAtom(c, atom.Li) || skip != nil && skip(c) {
+			continue
+		}
+		marker := "-"
+		if isAtom(node, atom.Ol) {
+			marker = fmt.Sprintf("%d.", n)
+			n++
+		}
+
+		var item strings.Builder
+		for i, b := range p.markdownBlocks(c) {
+			switch {
+			case i == 0:
+			case b.list: // nested lists don't need a blank line
+				item.WriteString("\n")
+			default:
+				item.WriteString("\n\n")
+			}
+			item.WriteString(b.text)
+		}
+		indent := strings.Repeat(" ", len(marker)+1)
+		body := strings.TrimPrefix(prefixLines(item.String(), indent, ""), indent)
+		items = append(items, strings.TrimRight(marker+" "+body, " "))
+	}
+	return strings.Join(items, "\n")
+}
+
+// writeMarkdownInline writes node, which is inline content, to buf as
+// Markdown.  Whitespace is collapsed later by markdownParagraph, but line
+// breaks in the source are written as spaces so that only <br> elements
+// break lines.
+func (p *parser) writeMarkdownInline(buf *strings.Builder, node *html.Node) {
+	switch {
+	case node.Type == html.TextNode:
+		buf.WriteString(escapeMarkdown(strings.NewReplacer("\n", " ", "\r", " ").Replace(node.Data)))
+		return
+	case node.Type != html.ElementNode || isAtom(node, atom.Script, atom.Style, atom.Template):
+		return
+	}
+	if skip := p.skipFunc(); skip != nil && skip(node) {
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Br:
+		buf.WriteString("\n")
+	case atom.Img:
+		buf.WriteString(escapeMarkdown(getAttr(node, "alt")))
+	case atom.Strong, atom.B:
+		buf.WriteString(wrapMarkdown(p.markdownInline(node), "**"))
+	case atom.Em, atom.I:
+		buf.WriteString(wrapMarkdown(p.markdownInline(node), "*"))
+	case atom.Code:
+		buf.WriteString(codeSpan(strings.Join(strings.Fields(getTextContent(node, imageAltValue)), " ")))
+	case atom.A:
+		text := strings.TrimSpace(p.markdownInline(node))
+		href := markdownURL.Replace(strings.TrimSpace(getAttr(node, "href")))
+		switch {
+		case href == "":
+			buf.WriteString(text)
+		case text == "":
+			buf.WriteString("<" + href + ">")
+		default:
+			buf.WriteString("[" + text + "](" + href + ")")
+		}
+	default:
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			p.writeMarkdownInline(buf, c)
+		}
+	}
+}
+
+// markdownInline returns the children of node as inline Markdown.
+func (p *parser) markdownInline(node *html.Node) string {
+	var buf strings.Builder
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		p.writeMarkdownInline(&buf, c)
+	}
+	return buf.String()
+}
+
+// wrapMarkdown returns s wrapped in the emphasis marker, moving any leading
+// and trailing whitespace outside of the markers, where Markdown requires it.
+func wrapMarkdown(s, marker string) string {
+	text := strings.TrimSpace(s)
+	if text == "" {
+		return s
+	}
+	i := strings.Index(s, text)
+	return s[:i] + marker + text + marker + s[i+len(text):]
+}
+
+// codeSpan returns s as a Markdown code span, delimited by a run of backticks
+// longer than any in s.
+func codeSpan(s string) string {
+	if s == "" {
+		return ""
+	}
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// markdownParagraph returns the inline Markdown s as a paragraph, with runs of
+// whitespace collapsed into a single space on each line, empty lines removed,
+// and the start of each line escaped if it would otherwise begin a block.
+// Lines are separated by hard line breaks (a backslash at the end of the
+// line), since a bare newline is only a soft break that is rendered as a
+// space.
+func markdownParagraph(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, escapeLineStart(line))
+		}
+	}
+	return strings.Join(lines, "\\\n")
+}
+
+// prefixLines returns s with prefix added to the start of each line, or
+// emptyPrefix for empty lines.
+func prefixLines(s, prefix, emptyPrefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = emptyPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	// markdownEscaper escapes the characters of text that Markdown would
+	// interpret as inline formatting.
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`,
+	)
+
+	// markdownURL escapes the characters of URLs that would end a Markdown
+	// link destination.
+	markdownURL = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+)
+
+// escapeMarkdown returns s with Markdown formatting characters escaped.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeLineStart returns line with its first character escaped if Markdown
+// would otherwise interpret it as the start of a heading, blockquote, list
+// item, or thematic break.
+func escapeLineStart(line string) string {
+	if strings.IndexByte("#>+-", line[0]) >= 0 {
+		return `\` + line
+	}
+	digits := strings.IndexFunc(line, func(r rune) bool { return r < '0' || r > '9' })
+	if digits <= 0 || line[digits] != '.' && line[digits] != ')' {
+		return line
+	}
+	if rest := line[digits+1:]; rest == "" || rest[0] == ' ' {
+		return line[:digits] + `\` + line[digits:]
+	}
+	return line
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Markdown(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{"", ""},
+		{"Hello   world\n again", "Hello world again"},
+		{"<p>One</p>\n<p>Two</p>", "One\n\nTwo"},
+		{"Before<p>inside</p>after", "Before\n\ninside\n\nafter"},
+		{"line one<br>line two", "line one\\\nline two"},
+		{"a<br><br>b<br>", "a\\\nb"},
+		{`ends in \<br>next`, `ends in \\` + "\\\nnext"},
+		{"<ul><li>a<br>b</li></ul>", "- a\\\n  b"},
+
+		// inline formatting
+		{"<strong>bold</strong> and <b>b</b>", "**bold** and **b**"},
+		{"<em>em</em> and <i>i</i>", "*em* and *i*"},
+		{"a<strong> spaced </strong>b", "a **spaced** b"},
+		{"<strong></strong>empty", "empty"},
+		{"<em>a <strong>b</strong></em>", "*a **b***"},
+		{"use <code>go  test</code>", "use `go test`"},
+		{"<code>a`b</code>", "``a`b``"},
+		{"<code>`a</code>", "`` `a ``"},
+
+		// links
+		{`<a href="http://example.com/">Example</a>`, "[Example](http://example.com/)"},
+		{`<a href="http://example.com/"><em>Example</em></a>`, "[*Example*](http://example.com/)"},
+		{`<a href="http://example.com/a (b)">x</a>`, "[x](http://example.com/a%20%28b%29)"},
+		{`<a href="http://example.com/"></a>`, "<http://example.com/>"},
+		{`<a>no href</a>`, "no href"},
+		{`<a href="/relative">rel</a>`, "[rel](http://example.com/relative)"},
+
+		// lists
+		{"<ul><li>one</li><li>two</li></ul>", "- one\n- two"},
+		{`<ol start="3"><li>three</li><li>four</li></ol>`, "3. three\n4. four"},
+		{"<ul><li>a<ul><li>b</li></ul></li><li>c</li></ul>", "- a\n  - b\n- c"},
+		{"<ol><li><p>a</p><p>b</p></li></ol>", "1. a\n\n   b"},
+		{"<ul><li></li></ul>", "-"},
+
+		// blockquotes
+		{"<blockquote><p>a</p><p>b</p></blockquote>", "> a\n>\n> b"},
+		{"<blockquote>q<blockquote>nested</blockquote></blockquote>", "> q\n>\n> > nested"},
+
+		// escaping
+		{"2 * 3 = 6_ [x]", `2 \* 3 = 6\_ \[x\]`},
+		{"<p># not a heading</p><p>- not a list</p><p>1. not a list</p>", "\\# not a heading\n\n\\- not a list\n\n1\\. not a list"},
+		{"1.5 million", "1.5 million"},
+		{"&lt;b&gt;", `\<b>`},
+
+		// unsupported elements
+		{"<h1>Title</h1><p>Text</p>", "Title\n\nText"},
+		{`<span class="x">span</span> <img src="a.jpg" alt="an image">`, "span an image"},
+		{"<pre>a\n  b</pre>", "a b"},
+		{"<script>x</script><style>y</style>text", "text"},
+		{"<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>", "ab\n\nc"},
+	}
+
+	for _, tt := range tests {
+		doc := `<div class="h-entry"><div class="e-content">` + tt.html + `</div></div>`
+		items := parseItemsWith(doc, WithContentMarkdown())
+		content := items[0].Properties["content"][0].(map[string]string)
+		if got := content["markdown"]; got != tt.want {
+			t.Errorf("WithContentMarkdown markdown of %q returned %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
+
+func Test_WithContentMarkdown(t *testing.T) {
+	doc := `<div class="h-entry"><div class="e-content"><p>Hello <a href="/world">world</a></p></div>
+		<span hidden class="e-summary">hidden</span></div>`
+
+	items := parseItemsWith(doc)
+	if _, ok := items[0].Properties["content"][0].(map[string]string)["markdown"]; ok {
+		t.Errorf("Parse without WithContentMarkdown returned markdown")
+	}
+
+	items = parseItemsWith(doc, WithContentMarkdown())
+	content := items[0].Properties["content"][0].(map[string]string)
+	want := map[string]string{
+		"value":    "Hello world",
+		"html":     `<p>Hello <a href="http://example.com/world">world</a></p>`,
+		"markdown": "Hello [world](http://example.com/world)",
+	}
+	for k, v := range want {
+		if content[k] != v {
+			t.Errorf("Parse with WithContentMarkdown returned %s %q, want %q", k, content[k], v)
+		}
+	}
+
+	items = parseItemsWith(`<div class="h-entry"><div class="e-content">a <span hidden>b</span> c</div></div>`,
+		WithContentMarkdown(), WithSkipHidden())
+	if got, want := items[0].Properties["content"][0].(map[string]string)["markdown"], "a c"; got != want {
+		t.Errorf("Parse with WithSkipHidden returned markdown %q, want %q", got, want)
+	}
+
+	if err := ValidateSchema(Parse(strings.NewReader(doc), nil, WithContentMarkdown())); err != nil {
+		t.Errorf("ValidateSchema with WithContentMarkdown returned error: %v", err)
+	}
+}
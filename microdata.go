@@ -0,0 +1,170 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for converting schema.org microdata into
+// microformats.
+
+package microformats
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// WithMicrodataFallback converts schema.org microdata items (elements with
+// itemscope and itemtype attributes) into microformats, if the document
+// contains no microformats of its own (and no JSON-LD converted by
+// WithJSONLDFallback).  The same types and properties are supported as for
+// WithJSONLDFallback.  Items of other types are ignored, though supported
+// items nested within them are converted.  The itemref attribute is not
+// supported.
+//
+// Microdata attributes never affect the parsing of microformats, so pages
+// that mark up the same content with both are parsed the same with or
+// without this option.
+func WithMicrodataFallback() Option {
+	return func(p *parser) {
+		p.microdataFallback = true
+	}
+}
+
+// microdataItems returns the microformats converted from the microdata items
+// in the tree rooted at node, in document order.
+func (p *parser) microdataItems(node *html.Node) []*Microformat {
+	if node.Type == html.ElementNode && hasAttr(node, "itemscope") {
+		if m := p.microdataObject(node); m != nil {
+			return []*Microformat{m}
+		}
+	}
+	var items []*Microformat
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if !isAtom(c, atom.Template, atom.Script, atom.Style) {
+			items = append(items, p.microdataItems(c)...)
+		}
+	}
+	return items
+}
+
+// microdataObject converts the microdata item node to a microformat, or
+// returns nil if it is not of a supported type.
+func (p *parser) microdataObject(node *html.Node) *Microformat {
+	var mfType string
+	for _, t := range splitTokens(getAttr(node, "itemtype")) {
+		t = strings.TrimPrefix(strings.TrimPrefix(t, "http://schema.org/"), "https://schema.org/")
+		if mfType = jsonldTypes[t]; mfType != "" {
+			break
+		}
+	}
+	if mfType == "" {
+		return nil
+	}
+
+	m := &Microformat{Type: []string{mfType}, Properties: make(map[string][]any)}
+	m.ID = getAttr(node, "id")
+
+	props := microdataProperties(node)
+	// iterate in a stable order, as for JSON-LD
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		prop := jsonldProperties[mfType][k]
+		if prop == "" || len(m.Properties[prop]) > 0 {
+			continue
+		}
+		for _, n := range props[k] {
+			if prop == "category" && !hasAttr(n, "itemscope") {
+				for _, kw := range strings.Split(p.microdataString(prop, n), ",") {
+					if kw = strings.TrimSpace(kw); kw != "" {
+						m.Properties[prop] = append(m.Properties[prop], kw)
+					}
+				}
+				continue
+			}
+			if value := p.microdataValue(prop, n); value != nil {
+				m.Properties[prop] = append(m.Properties[prop], value)
+			}
+		}
+	}
+	return m
+}
+
+// microdataValue converts the element node, a microdata property of the
+// microformat property prop, to a microformat property value.  Nested items
+// of a supported type are converted to microformats, and other items use
+// their name, or url for URL properties.
+func (p *parser) microdataValue(prop string, node *html.Node) any {
+	if hasAttr(node, "itemscope") {
+		if m := p.microdataObject(node); m != nil {
+			m.Value = valueString(m)
+			return m
+		}
+		key := "name"
+		if jsonldURLProperties[prop] {
+			key = "url"
+		}
+		if nodes := microdataProperties(node)[key]; len(nodes) > 0 {
+			return p.microdataValue(prop, nodes[0])
+		}
+		return nil
+	}
+	if s := p.microdataString(prop, node); s != "" {
+		return s
+	}
+	return nil
+}
+
+// microdataString returns the value of the microdata property node, which is
+// not an item, following the microdata rules for each element.
+func (p *parser) microdataString(prop string, node *html.Node) string {
+	var s string
+	switch {
+	case isAtom(node, atom.Meta):
+		s = getAttr(node, "content")
+	case isAtom(node, atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Source, atom.Track, atom.Video):
+		s = expandURL(getAttr(node, "src"), p.base)
+	case isAtom(node, atom.A, atom.Area, atom.Link):
+		s = expandURL(getAttr(node, "href"), p.base)
+	case isAtom(node, atom.Object):
+		s = expandURL(getAttr(node, "data"), p.base)
+	case isAtom(node, atom.Data, atom.Meter):
+		s = getAttr(node, "value")
+	case isAtom(node, atom.Time) && hasAttr(node, "datetime"):
+		s = getAttr(node, "datetime")
+	default:
+		s = getTextContent(node, nil)
+	}
+	s = strings.TrimSpace(s)
+	if jsonldURLProperties[prop] && s != "" {
+		s = expandURL(s, p.base)
+	}
+	return s
+}
+
+// microdataProperties returns the property elements of the microdata item
+// node, keyed by property name, in document order.  Properties of nested
+// items are not included.
+func microdataProperties(node *html.Node) map[string][]*html.Node {
+	props := make(map[string][]*html.Node)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || isAtom(c, atom.Template) {
+				continue
+			}
+			for _, name := range splitTokens(getAttr(c, "itemprop")) {
+				props[name] = append(props[name], c)
+			}
+			if !hasAttr(c, "itemscope") {
+				walk(c)
+			}
+		}
+	}
+	walk(node)
+	return props
+}
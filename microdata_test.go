@@ -0,0 +1,89 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_WithMicrodataFallback(t *testing.T) {
+	microdata := `<div itemscope itemtype="https://schema.org/WebPage">
+		<article itemscope itemtype="https://schema.org/BlogPosting" id="post">
+			<h1 itemprop="headline">Hello</h1>
+			<meta itemprop="name" content="ignored">
+			<a itemprop="url" href="/hello">permalink</a>
+			<img itemprop="image" src="/hello.jpg" alt="">
+			<time itemprop="datePublished" datetime="2024-01-02T03:04:05Z">January 2</time>
+			<meta itemprop="keywords" content="go, microformats">
+			<div itemprop="author" itemscope itemtype="http://schema.org/Person">
+				<a itemprop="url" href="https://jane.example/"><span itemprop="name">Jane</span></a>
+			</div>
+			<div itemprop="publisher" itemscope itemtype="https://schema.org/Organization"><span itemprop="name">Acme</span></div>
+			<div itemprop="articleBody">Hello <b>world</b></div>
+		</article>
+	</div>`
+
+	wantMicrodata := []*Microformat{{
+		ID:   "post",
+		Type: []string{"h-entry"},
+		Properties: map[string][]any{
+			"name":      {"Hello"},
+			"content":   {"Hello world"},
+			"published": {"2024-01-02T03:04:05Z"},
+			"url":       {"http://example.com/hello"},
+			"photo":     {"http://example.com/hello.jpg"},
+			"category":  {"go", "microformats"},
+			"author": {&Microformat{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"url":  {"https://jane.example/"},
+				},
+				Value: "Jane",
+			}},
+		},
+	}}
+
+	// microdata attributes do not interfere with microformats
+	both := `<article class="h-entry" itemscope itemtype="https://schema.org/BlogPosting" itemref="summary">
+		<h1 class="p-name" itemprop="headline">Hello</h1>
+		<div class="p-author h-card" itemprop="author" itemscope itemtype="https://schema.org/Person">
+			<span class="p-name" itemprop="name">Jane</span>
+		</div>
+	</article>
+	<p id="summary" itemprop="description">A post</p>`
+
+	wantBoth := []*Microformat{{
+		Type: []string{"h-entry"},
+		Properties: map[string][]any{
+			"name": {"Hello"},
+			"author": {&Microformat{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+				Value:      "Jane",
+			}},
+		},
+	}}
+
+	tests := []struct {
+		description string
+		html        string
+		opts        []Option
+		want        []*Microformat
+	}{
+		{"disabled", microdata, nil, []*Microformat{}},
+		{"enabled", microdata, []Option{WithMicrodataFallback()}, wantMicrodata},
+		{"with microformats", both, nil, wantBoth},
+		{"with microformats and fallback", both, []Option{WithMicrodataFallback()}, wantBoth},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(tt.html, tt.opts...)
+		if diff := cmp.Diff(tt.want, items, ignoreParseState); diff != "" {
+			t.Errorf("Parse %s mismatch (-want +got):\n%s", tt.description, diff)
+		}
+	}
+}
@@ -14,6 +14,18 @@
 // To parse only a section of an HTML document, use a package like goquery to
 // select the root node to parse from.  For example, see cmd/gomf/main.go.
 //
+// Microformats classes within inline SVG and MathML content are parsed the
+// same as in HTML content.  The element-specific rules for resolving property
+// values only apply to HTML elements, with the exception that the href (or
+// xlink:href) attribute of SVG <a> and <image> elements is used for u-*
+// properties.  All other foreign elements use their text content.
+//
+// Documents are parsed using the HTML tree construction algorithm, so
+// properties are scoped to the same elements a browser would build.  For
+// example, missing <tbody> elements are implied, and content that is not
+// allowed directly within a <table> is moved before it, outside of any
+// microformat rooted on the table.
+//
 // See also: http://microformats.org/wiki/microformats2
 package microformats // import "willnorris.com/go/microformats"
 
@@ -25,9 +37,12 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"unicode"
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
+	"golang.org/x/text/unicode/norm"
 )
 
 var (
@@ -38,6 +53,11 @@ var (
 // Microformat specifies a single microformat object and its properties.  It
 // may represent a person, an address, a blog post, etc.
 type Microformat struct {
+	// ID is the value of the id attribute of the microformat's root
+	// element, if any.  It is set for top-level, child, and property
+	// microformats, but not for v1 microformats parsed in backwards
+	// compatible mode, for which the parsing specification does not
+	// include an id.
 	ID         string           `json:"id,omitempty"`
 	Value      string           `json:"value,omitempty"`
 	HTML       string           `json:"html,omitempty"`
@@ -47,6 +67,33 @@ type Microformat struct {
 	Coords     string           `json:"coords,omitempty"`
 	Children   []*Microformat   `json:"children,omitempty"`
 
+	// RawURLs holds the values of u-* properties as written in the
+	// document, before they were resolved to absolute URLs, if parsed with
+	// WithRawURLs.  For each property name, it has one value for each u-*
+	// value of the property, in order.  Implied properties are not
+	// included.  RawURLs is not part of the canonical JSON representation.
+	RawURLs map[string][]string `json:"-"`
+
+	// Commands holds the Micropub commands found within the microformat,
+	// such as "mp-slug", if parsed with WithMicropubProperties.  Commands is
+	// not part of the canonical JSON representation.
+	Commands map[string][]string `json:"-"`
+
+	// TypeLabels holds the vCard type labels of the tel and email values of
+	// microformats v1 hCards, such as "work" in
+	// <span class="tel"><span class="type">work</span> ...</span>, which
+	// are otherwise not parsed.  For each property name, it has the labels
+	// of each value of the property, in order, but may have fewer entries
+	// than there are values if the last values have no labels.  TypeLabels
+	// is not part of the canonical JSON representation.
+	TypeLabels map[string][][]string `json:"-"`
+
+	// DOMPath is the path through the document tree to the microformat's
+	// root element, such as "html>body>div.h-feed>article.h-entry", if
+	// parsed with WithDOMPath.  DOMPath is not part of the canonical JSON
+	// representation.
+	DOMPath string `json:"-"`
+
 	// track whether this microformat has various types of properties or
 	// nested microformats. Used in processing implied property values.
 	hasNestedMicroformats bool
@@ -79,9 +126,33 @@ type Data struct {
 
 	// RelURLs maps related URLs found on the page to additional metadata
 	// about that relationship. If a URL is linked to more than once, only
-	// the metadata for the first link is included here.  Relative URL
-	// values are resolved to absolute URLs using the base URL of the page.
+	// the metadata for the first link is included here, except that the
+	// rels of all links are merged, as the parsing specification requires.
+	// Relative URL values are resolved to absolute URLs using the base URL
+	// of the page.
 	RelURLs map[string]*RelURL `json:"rel-urls"`
+
+	// BaseURL is the base URL used to resolve relative URLs on the page.
+	// This is the base URL provided to Parse, as updated by the first
+	// <base> element with an href attribute.  It is nil if no base URL was
+	// provided and the page has no <base> element.  BaseURL is not part of
+	// the canonical JSON representation.
+	BaseURL *url.URL `json:"-"`
+
+	// HeaderRels maps rel values to the URLs advertised for them by the
+	// Link headers of the HTTP response the page was fetched from, resolved
+	// against the URL of the response.  The legacy X-Pingback header is
+	// included as a "pingback" rel.  It is only set by ParseURL and
+	// ParseRequest, and is not part of the canonical JSON representation.
+	HeaderRels map[string][]string `json:"-"`
+
+	// Debug describes how this Data was produced, if parsed with
+	// WithDebugInfo.  It is not part of the canonical JSON representation.
+	Debug *DebugInfo `json:"-"`
+
+	// urls are the URLs found while parsing, with the position in the
+	// document of the element each was found on, for AllURLs.
+	urls []documentURL
 }
 
 // RelURL represents the attributes of a URL.  The URL value itself is the map
@@ -93,6 +164,15 @@ type RelURL struct {
 	HrefLang string   `json:"hreflang,omitempty"`
 	Title    string   `json:"title,omitempty"`
 	Type     string   `json:"type,omitempty"`
+
+	// Sizes is the sizes attribute of icon links.  It is not part of the
+	// canonical JSON representation.
+	Sizes string `json:"-"`
+}
+
+// relKey identifies a url stored for a rel value.
+type relKey struct {
+	rel, url string
 }
 
 // parser parses a single HTML page for microformats.  parser is not thread
@@ -105,39 +185,356 @@ type parser struct {
 
 	// root node of the parsed document
 	root *html.Node
+
+	// rel and url pairs already stored in curData.Rels
+	relSeen map[relKey]bool
+
+	// text of nested microformats used as p-* property values, reused for the
+	// text of their ancestors so that deeply nested microformats are not
+	// traversed once for each level
+	textCache map[*html.Node]string
+
+	// number of elements with each id, counted when first needed by the
+	// include pattern
+	idCounts map[string]int
+
+	// custom property prefixes registered with WithPropertyPrefix
+	prefixes map[string]PropertyFunc
+
+	// whether to insert line breaks in e-* text values, set by WithBlockText
+	blockText bool
+
+	// whether to convert e-* values to Markdown, set by WithContentMarkdown
+	contentMarkdown bool
+
+	// how to remove whitespace from text, set by WithWhitespacePolicy
+	whitespace WhitespacePolicy
+
+	// whether to ignore hidden elements in text, set by WithSkipHidden
+	skipHidden bool
+
+	// whether to ignore <del> elements in e-* text, set by
+	// WithExcludeDeletedText
+	excludeDeleted bool
+
+	// whether to parse known property names without a prefix, set by
+	// WithLegacyBareProperties
+	bareProperties bool
+
+	// whether to match v1 class names case insensitively, set by
+	// WithCaseInsensitiveV1
+	caseInsensitiveV1 bool
+
+	// whether to match microformats2 prefixes case insensitively, set by
+	// WithCaseInsensitivePrefixes
+	caseInsensitivePrefixes bool
+
+	// whether to normalize email addresses, set by WithLowercaseEmails
+	lowercaseEmails bool
+
+	// whether to parse documents as XHTML, set by WithXHTML
+	xhtml bool
+
+	// maximum length of property values, set by WithMaxValueLength
+	maxValueLength int
+
+	// problems found while parsing, returned by ParseWithWarnings
+	warnings []Warning
+
+	// number of elements walked so far, which is the position in the
+	// document of the element being walked
+	elements int
+
+	// whether to record the positions of elements, set by WithPositions, and
+	// the positions recorded
+	trackPositions bool
+	positions      map[*html.Node]position
+
+	// Unicode normalization form for text, set by WithUnicodeNormalization
+	normForm *norm.Form
+
+	// whether to use SVG titles for p-* properties, set by WithSVGTitles
+	svgTitles bool
+
+	// whether to include srcset candidates of images, set by WithSrcset
+	srcset bool
+
+	// whether to detect the character encoding of documents, set by
+	// WithCharsetDetection, and the Content-Type header used to detect it
+	charsetDetection bool
+	contentType      string
+
+	// whether to record the raw values of u-* properties, set by
+	// WithRawURLs
+	rawURLs bool
+
+	// whether to collect Micropub commands, set by WithMicropubProperties
+	micropub bool
+
+	// options for parsing HTML, set by WithHTMLParseOptions
+	htmlOptions []html.ParseOption
+
+	// whether to parse declarative shadow roots, set by
+	// WithDeclarativeShadowDOM
+	shadowDOM bool
+
+	// whether to skip the document <head>, set by WithoutHead
+	withoutHead bool
+
+	// attribute marking elements to skip, set by WithIgnoreAttribute
+	ignoreAttr string
+
+	// types of <script> elements to parse as templates, set by
+	// WithScriptTemplates
+	scriptTypes map[string]bool
+
+	// rel values to collect, set by WithRels.  If nil, all are collected.
+	relFilter map[string]bool
+
+	// whether to normalize rel URLs, and remove trailing slashes, set by
+	// WithNormalizedRelURLs
+	normalizeRels    bool
+	collapseRelSlash bool
+
+	// query parameters to remove from URLs, set by WithStripParams
+	stripParams []string
+
+	// URL schemes allowed in u-* values, set by WithAllowedSchemes.  If
+	// nil, all are allowed.
+	allowedSchemes map[string]bool
+
+	// whether to ignore anchor links for implied urls, set by
+	// WithSkipAnchorLinks
+	skipAnchorLinks bool
+
+	// whether to convert Unix timestamps in dt-* values, set by
+	// WithUnixTimestamps
+	unixTimestamps bool
+
+	// whether to convert JSON-LD if no microformats are found, set by
+	// WithJSONLDFallback
+	jsonldFallback bool
+
+	// whether to convert microdata if no microformats are found, set by
+	// WithMicrodataFallback
+	microdataFallback bool
+
+	// whether to convert ARIA feeds and articles if no microformats are
+	// found, set by WithARIAFeed
+	ariaFeed bool
+
+	// whether to use aria-label for empty p-* values, set by
+	// WithAriaLabelFallback
+	ariaLabelFallback bool
+
+	// callbacks for parsed microformats and rels, set by OnMicroformat and
+	// OnRel, and the number of microformats passed to onMicroformat
+	onMicroformat func(*Microformat)
+	onRel         func(rel, url string)
+	handledItems  int
+
+	// whether to record the paths of microformats, set by WithDOMPath
+	domPath bool
+
+	// whether to record DebugInfo, set by WithDebugInfo
+	debugInfo bool
+
+	// called at key decision points, set by WithTracer
+	tracer func(event string, node *html.Node)
+
+	// resolver for the documents of iframes, set by WithIframeResolver, and
+	// the URLs of those being walked
+	iframeResolver func(src string) io.Reader
+	iframeSrcs     map[string]bool
+
+	// type of microformat to find, and the first one found, for FirstOfType
+	findType string
+	found    *Microformat
 }
 
 // Parse the microformats found in the HTML document read from r.  baseURL is
 // the URL this document was retrieved from and is used to expand any
 // relative URLs.  If baseURL is nil and the base URL is not referenced in the
-// document, relative URLs are not expanded.
-func Parse(r io.Reader, baseURL *url.URL) *Data {
-	doc, _ := html.Parse(r)
-	return ParseNode(doc, baseURL)
+// document, relative URLs are not expanded.  opts configure optional parsing
+// behavior.
+//
+// r must provide the decoded document: if it is the body of an HTTP response
+// with a Content-Encoding such as gzip, decompressing it is the caller's
+// responsibility.  ParseURL fetches and decodes documents itself.
+func Parse(r io.Reader, baseURL *url.URL, opts ...Option) *Data {
+	data, _ := ParseWithWarnings(r, baseURL, opts...)
+	return data
+}
+
+// ParseWithError parses the microformats found in the HTML document read from
+// r, the same as Parse, and additionally returns any error encountered while
+// reading r.  If reading fails partway through the document, such as when a
+// connection is dropped and r returns io.ErrUnexpectedEOF, the content read
+// up to that point is still parsed, and the returned Data holds the
+// microformats found in the partial document.
+func ParseWithError(r io.Reader, baseURL *url.URL, opts ...Option) (*Data, error) {
+	er := &errReader{r: r}
+	data := Parse(er, baseURL, opts...)
+	return data, er.err
+}
+
+// FirstOfType returns the first microformat of type t found in the HTML
+// document read from r, such as "h-card".  baseURL is used to expand relative
+// URLs, as described in Parse.  Microformats are matched in the order their
+// parsing completes, so a microformat nested within another of the same
+// type is found before its parent.  Once a match is found and fully parsed,
+// including its children, the rest of the document is not walked.  Rels are
+// not collected.
+//
+// If no microformat of type t is found, FirstOfType returns nil.  The
+// returned error is any error encountered while reading r, as described in
+// ParseWithError.
+func FirstOfType(r io.Reader, baseURL *url.URL, t string, opts ...Option) (*Microformat, error) {
+	er := &errReader{r: r}
+	doc, _ := parseDocument(er, opts)
+	if doc == nil {
+		return nil, er.err
+	}
+	p := newParser(doc, baseURL, opts)
+	p.findType = t
+	for _, n := range p.starts(doc) {
+		if p.found == nil {
+			p.walk(n)
+		}
+	}
+	return p.found, er.err
+}
+
+// parseDocument parses the document read from r as HTML, or as XHTML if
+// WithXHTML is included in opts.
+func parseDocument(r io.Reader, opts []Option) (*html.Node, map[*html.Node]position) {
+	config := newParser(nil, nil, opts)
+	if config.charsetDetection {
+		r = decodeCharset(r, config.contentType)
+	}
+	if config.xhtml {
+		doc, _ := parseXHTML(r, config.htmlOptions)
+		return doc, nil
+	}
+	parse := func(r io.Reader) *html.Node {
+		doc, _ := html.ParseWithOptions(r, config.htmlOptions...)
+		return doc
+	}
+	if config.trackPositions {
+		return parseWithPositions(r, parse)
+	}
+	return parse(r), nil
+}
+
+// errReader wraps an io.Reader, converting any read error into io.EOF so that
+// a partial document is parsed rather than discarded.  The original error is
+// recorded in err.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if err != nil && err != io.EOF {
+		r.err = err
+		err = io.EOF
+	}
+	return n, err
 }
 
 // ParseNode parses the microformats found in doc.  baseURL is the URL this
 // document was retrieved from and is used to expand any relative URLs. If
 // baseURL is nil and the base URL is not referenced in the document,
-// relative URLs are not expanded.
-func ParseNode(doc *html.Node, baseURL *url.URL) *Data {
+// relative URLs are not expanded.  opts configure optional parsing behavior.
+func ParseNode(doc *html.Node, baseURL *url.URL, opts ...Option) *Data {
 	if doc == nil { // makes no sense to go further
 		return nil
 	}
+	p := newParser(doc, baseURL, opts)
+	p.parse(doc)
+	return p.curData
+}
+
+// ExtractProperties returns the properties found in the descendants of n, as
+// if n were the root element of a microformat, for fragments of property
+// elements whose container has already been identified but has no h-* root
+// class of its own.  Classes on n itself are ignored.  baseURL is used to
+// expand relative URLs, as described in Parse.
+//
+// Only explicit p-*, u-*, dt-*, and e-* properties are extracted: no
+// properties are implied, and microformats v1 property classes are not
+// recognized.  Nested microformat roots within n are still treated as nested
+// microformats, so that a "p-author h-card" element becomes an h-card
+// property value, and its own properties are not added to the result.  Nested
+// microformats that are not property values are ignored.
+func ExtractProperties(n *html.Node, baseURL *url.URL, opts ...Option) map[string][]any {
+	if n == nil {
+		return nil
+	}
+	p := newParser(n, baseURL, opts)
+	p.curItem = &Microformat{Properties: make(map[string][]any)}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		p.walk(c)
+	}
+	return p.curItem.Properties
+}
+
+// parse walks doc for microformats, applying any fallbacks enabled by options
+// once the walk is complete.
+func (p *parser) parse(doc *html.Node) {
+	for _, n := range p.starts(doc) {
+		p.walk(n)
+	}
+	if *p.base != (url.URL{}) {
+		base := *p.base
+		p.curData.BaseURL = &base
+	}
+	if len(p.curData.Items) == 0 && p.handledItems == 0 {
+		var items []*Microformat
+		if p.jsonldFallback {
+			items = p.jsonldItems(doc)
+		}
+		if len(items) == 0 && p.microdataFallback {
+			items = p.microdataItems(doc)
+		}
+		if len(items) == 0 && p.ariaFeed {
+			items = p.ariaItems(doc)
+		}
+		for _, item := range items {
+			if p.onMicroformat != nil {
+				p.handledItems++
+				p.onMicroformat(item)
+			} else {
+				p.curData.Items = append(p.curData.Items, item)
+			}
+		}
+	}
+	if p.debugInfo {
+		p.curData.Debug = p.debug()
+	}
+}
+
+// newParser returns a parser for the document rooted at doc, which resolves
+// relative URLs against baseURL and is configured with opts.
+func newParser(doc *html.Node, baseURL *url.URL, opts []Option) *parser {
 	p := new(parser)
 	p.curData = &Data{
 		Items:   make([]*Microformat, 0),
 		Rels:    make(map[string][]string),
 		RelURLs: make(map[string]*RelURL),
 	}
+	p.relSeen = make(map[relKey]bool)
 	p.base = baseURL
 	if p.base == nil { // can make sense if base can be inferred from contents
 		p.base = &url.URL{}
 	}
 	p.baseFound = false
 	p.root = doc
-	p.walk(doc)
-	return p.curData
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // expandAttrURLs expands relative URLs in attributes to be absolute URLs.
@@ -162,10 +559,11 @@ func (p *parser) expandAttrURLs(node *html.Node) {
 	if isAtom(node, atom.A, atom.Area) {
 		attr = append(attr, "ping")
 	}
-	if isAtom(node, atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Input, atom.Script, atom.Source, atom.Track, atom.Video) {
+	if isAtom(node, atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Input, atom.Script, atom.Source, atom.Track, atom.Video) ||
+		isAMPElement(node, ampImages) || isAMPElement(node, ampMedia) {
 		attr = append(attr, "src")
 	}
-	if isAtom(node, atom.Video) {
+	if isAtom(node, atom.Video) || isAMPElement(node, ampMedia) {
 		attr = append(attr, "poster")
 	}
 
@@ -181,6 +579,30 @@ func (p *parser) expandAttrURLs(node *html.Node) {
 	}
 }
 
+// innerHTML returns the serialized HTML of the children of node, as used for
+// the html value of e-* properties.  Relative URLs in the children are
+// expanded first.
+func (p *parser) innerHTML(node *html.Node) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		p.expandAttrURLs(c) // microformats/microformats2-parsing#38
+
+		// ignore errors from html.Render which nearly always result from being unable
+		// to write to the underlying io.Writer, which never happens with bytes.Buffer.
+		_ = html.Render(buf, c)
+	}
+	htmlbody := strings.TrimSpace(buf.String())
+
+	// HTML spec: Serializing HTML Fragments algorithm does not include
+	// a trailing slash, so remove it.  Nor should apostrophes be
+	// encoded, which golang.org/x/net/html is doing.
+	htmlbody = strings.ReplaceAll(htmlbody, `/>`, `>`)
+	htmlbody = strings.ReplaceAll(htmlbody, `&#39;`, `'`)
+	return htmlbody
+}
+
 // expandURL expands relative URL r into an absolute URL by resolving it relative to
 // base. If r is not a valid URL or base is nil, the original r value is returned.
 func expandURL(r string, base *url.URL) string {
@@ -193,11 +615,100 @@ func expandURL(r string, base *url.URL) string {
 	return r
 }
 
+// expandUID expands a u-uid value r relative to base.  Unique identifiers are
+// often not dereferenceable URLs, so r is returned unchanged if it has a
+// scheme other than http or https (such as "tag:" or "urn:uuid:"), or if it
+// contains whitespace and so is plain text rather than a URL reference.
+// Otherwise, r is expanded like any other URL.
+func expandUID(r string, base *url.URL) string {
+	if strings.IndexFunc(r, unicode.IsSpace) >= 0 {
+		return r
+	}
+	if scheme := uriScheme(r); scheme != "" && !strings.EqualFold(scheme, "http") && !strings.EqualFold(scheme, "https") {
+		return r
+	}
+	return expandURL(r, base)
+}
+
+// expandKey expands a u-key value r, such as the URL of a PGP key or its
+// fingerprint, relative to base.  Fingerprints and key IDs, as described in
+// isKeyFingerprint, are returned unchanged, as are values that expandUID does
+// not expand; otherwise, r is expanded like any other URL.
+func expandKey(r string, base *url.URL) string {
+	if isKeyFingerprint(r) {
+		return r
+	}
+	return expandUID(r, base)
+}
+
+// isKeyFingerprint returns whether s is a key fingerprint or ID: at least 8
+// hexadecimal digits, optionally grouped with spaces or colons, or prefixed
+// with "0x".
+func isKeyFingerprint(s string) bool {
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		s = s[2:]
+	}
+	digits := 0
+	for _, c := range s {
+		switch {
+		case '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F':
+			digits++
+		case c == ' ' || c == ':':
+		default:
+			return false
+		}
+	}
+	return digits >= 8
+}
+
+// uriScheme returns the scheme of the URI r, or an empty string if r does not
+// begin with a scheme as defined by RFC 3986.
+func uriScheme(r string) string {
+	for i := 0; i < len(r); i++ {
+		c := r[i]
+		switch {
+		case 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' || c == '+' || c == '-' || c == '.':
+			if i == 0 {
+				return ""
+			}
+		case c == ':' && i > 0:
+			return r[:i]
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
 // walk the DOM rooted at node, storing parsed microformats in p.
 //
 //nolint:gocyclo,funlen // maybe we'll refactor it one day
 func (p *parser) walk(node *html.Node) {
-	if isAtom(node, atom.Template) {
+	if node.Type == html.ElementNode {
+		p.elements++
+	}
+	index := p.elements
+	if isAtom(node, atom.Template) && !(p.shadowDOM && isShadowRoot(node)) {
+		return
+	}
+	if p.isIgnored(node) {
+		return
+	}
+	if base, ok := p.xmlBase(node); ok {
+		prior := p.base
+		p.base = base
+		defer func() { p.base = prior }()
+	}
+	if nodes := p.scriptTemplate(node); nodes != nil {
+		for i := 0; i < len(nodes) && p.found == nil; i++ {
+			p.walk(nodes[i])
+		}
+		return
+	}
+	if isAtom(node, atom.Script, atom.Style) {
+		// the content of scripts and stylesheets is not rendered, so any
+		// microformats classes on them are ignored
 		return
 	}
 
@@ -206,15 +717,24 @@ func (p *parser) walk(node *html.Node) {
 	var rootclasses []string
 
 	classes := getClasses(node)
+	if p.caseInsensitivePrefixes {
+		classes = lowercasePrefixes(classes)
+	}
 	for _, class := range classes {
-		if rootClassNames.MatchString(class) {
+		if strings.HasPrefix(class, "h-") && rootClassNames.MatchString(class) {
 			rootclasses = append(rootclasses, class)
 		}
 	}
 
+	// v1 class names, which may be matched case insensitively
+	v1classes := classes
+	if p.caseInsensitiveV1 {
+		v1classes = lowercaseAll(classes)
+	}
+
 	var backcompat bool
 	if len(rootclasses) == 0 {
-		rootclasses = backcompatRootClasses(classes, p.curItem)
+		rootclasses = backcompatRootClasses(v1classes, p.curItem)
 		if len(rootclasses) > 0 {
 			backcompat = true
 		}
@@ -230,13 +750,19 @@ func (p *parser) walk(node *html.Node) {
 		if !backcompat {
 			curItem.ID = getAttr(node, "id")
 		}
+		if p.domPath {
+			curItem.DOMPath = domPath(node)
+		}
 		if p.curItem == nil {
-			p.curData.Items = append(p.curData.Items, curItem)
+			if p.onMicroformat == nil {
+				p.curData.Items = append(p.curData.Items, curItem)
+			}
 		} else {
 			p.curItem.hasNestedMicroformats = true
 		}
 		priorItem = p.curItem
 		p.curItem = curItem
+		p.trace(TraceRoot, node)
 	}
 
 	// handle backcompat include pattern
@@ -253,44 +779,71 @@ func (p *parser) walk(node *html.Node) {
 				newbase = p.base.ResolveReference(newbase)
 				p.base = newbase
 				p.baseFound = true
+				p.textCache = nil // image URLs in cached text may change
 			}
 		}
 	}
 
 	var rels []string
 	if isAtom(node, atom.A, atom.Link) {
+		// most links have no rel value, so avoid any work for them
 		if rel := getAttr(node, "rel"); rel != "" {
+			rels = splitTokens(rel)
+		}
+		if collected := p.collectedRels(rels); len(collected) > 0 {
 			urlVal := getAttr(node, "href")
-			urlVal = expandURL(urlVal, p.base)
-
-			rels = strings.Fields(rel)
-			for _, relval := range rels {
-				var seen bool // whether we've already stored this url for this rel
-				for _, u := range p.curData.Rels[relval] {
-					if u == urlVal {
-						seen = true
-					}
-				}
-				if !seen {
+			urlVal = p.stripURLParams(expandURL(urlVal, p.base))
+			if p.normalizeRels {
+				urlVal = normalizeURL(urlVal, p.collapseRelSlash)
+			}
+			p.recordURL(urlVal, index)
+
+			for _, relval := range collected {
+				// only store each url once for each rel
+				key := relKey{relval, urlVal}
+				if !p.relSeen[key] {
+					p.relSeen[key] = true
 					p.curData.Rels[relval] = append(p.curData.Rels[relval], urlVal)
+					if p.onRel != nil {
+						p.onRel(relval, urlVal)
+					}
 				}
 			}
 
-			if _, ok := p.curData.RelURLs[urlVal]; !ok {
-				sort.Strings(rels)
+			if relURL, ok := p.curData.RelURLs[urlVal]; !ok {
+				sort.Strings(collected)
 				p.curData.RelURLs[urlVal] = &RelURL{
 					Text:     getTextContent(node, nil),
-					Rels:     rels,
+					Rels:     collected,
 					Media:    getAttr(node, "media"),
 					HrefLang: getAttr(node, "hreflang"),
 					Title:    getAttr(node, "title"),
 					Type:     getAttr(node, "type"),
+					Sizes:    getAttr(node, "sizes"),
+				}
+			} else {
+				// the rels of later links to the same url are merged with
+				// the existing rels, as described in "parse a hyperlink
+				// element for rel microformats" in the parsing spec, so
+				// that Rels and RelURLs agree
+				var added bool
+				for _, relval := range collected {
+					if !containsString(relURL.Rels, relval) {
+						relURL.Rels = append(relURL.Rels, relval)
+						added = true
+					}
+				}
+				if added {
+					sort.Strings(relURL.Rels)
 				}
 			}
 		}
 	}
 
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
+	if body, u := p.iframeDocument(node); body != nil && p.found == nil {
+		p.walkIframe(body, u)
+	}
+	for c := node.FirstChild; c != nil && p.found == nil; c = c.NextSibling {
 		p.walk(c)
 	}
 
@@ -300,40 +853,65 @@ func (p *parser) walk(node *html.Node) {
 
 		// Process implied date for 'end' property.
 		implyEndDate(curItem)
+		if curItem.hasType("h-event") {
+			p.checkEventDates(node, curItem)
+		}
 
 		if p.curItem == nil || !p.curItem.backcompat {
 			// Now process implied property values.
 			if _, ok := curItem.Properties["name"]; !ok {
 				if !curItem.hasNestedMicroformats && !curItem.hasPProperties && !curItem.hasEProperties {
-					name := getImpliedName(node)
+					name := p.trimText(getImpliedName(node, p.skipFunc(), p.lineBreak()))
+					if p.normForm != nil {
+						name = p.normForm.String(name)
+					}
 					if name != "" {
 						curItem.Properties["name"] = append(curItem.Properties["name"], name)
+						p.trace(TraceImpliedName, node)
 					}
 				}
 			}
 			if _, ok := curItem.Properties["photo"]; !ok {
 				if !curItem.hasNestedMicroformats && !curItem.hasUProperties {
 					photo, alt := getImpliedPhoto(node, p.base)
+					photo = p.stripURLParams(photo)
+					if !p.allowURL(node, "photo", photo) {
+						photo, alt = "", ""
+					}
+					p.recordURL(photo, index)
 					if alt != "" {
 						curItem.Properties["photo"] = append(curItem.Properties["photo"], map[string]string{
 							"alt":   alt,
 							"value": photo,
 						})
+						p.trace(TraceImpliedPhoto, node)
 					} else if photo != "" {
 						curItem.Properties["photo"] = append(curItem.Properties["photo"], photo)
+						p.trace(TraceImpliedPhoto, node)
 					}
 				}
 			}
 			if _, ok := curItem.Properties["url"]; !ok {
 				if !curItem.hasNestedMicroformats && !curItem.hasUProperties {
-					url := getImpliedURL(node, p.base)
-					if url != "" {
+					url := p.stripURLParams(getImpliedURL(node, p.base, p.skipAnchorLinks))
+					if url != "" && p.allowURL(node, "url", url) {
 						curItem.Properties["url"] = append(curItem.Properties["url"], url)
+						p.recordURL(url, index)
+						p.trace(TraceImpliedURL, node)
 					}
 				}
 			}
 		}
 		p.curItem = priorItem
+
+		if priorItem == nil && p.onMicroformat != nil {
+			p.handledItems++
+			p.onMicroformat(curItem)
+		}
+
+		if p.findType != "" && p.found == nil && curItem.hasType(p.findType) {
+			p.found = curItem
+		}
 	}
 
 	var propertyclasses []string
@@ -342,28 +920,45 @@ func (p *parser) walk(node *html.Node) {
 		if p.curItem != nil {
 			itemType = p.curItem.Type
 		}
-		propertyclasses = backcompatPropertyClasses(classes, rels, itemType)
+		propertyclasses = backcompatPropertyClasses(v1classes, rels, itemType)
 	} else {
 		for _, class := range classes {
-			match := propertyClassNames.FindStringSubmatch(class)
-			if match != nil {
-				propertyclasses = append(propertyclasses, match[0])
+			if hasPropertyPrefix(class) && propertyClassNames.MatchString(class) {
+				propertyclasses = append(propertyclasses, class)
+			} else if p.prefixes != nil {
+				if m := customPropertyClassNames.FindStringSubmatch(class); m != nil && p.prefixes[m[1]] != nil {
+					propertyclasses = append(propertyclasses, m[0])
+				}
 			}
 		}
+		if p.bareProperties && p.curItem != nil {
+			propertyclasses = append(propertyclasses, barePropertyClasses(classes, propertyclasses)...)
+		}
+	}
+	if p.micropub && p.curItem != nil {
+		p.addCommands(node, classes)
 	}
+
 	if len(propertyclasses) > 0 {
 		for _, prop := range propertyclasses {
 			parts := strings.SplitN(prop, "-", 2)
 			prefix, name := parts[0], parts[1]
 
-			var value, embedValue *string
+			var value, embedValue, rawValue *string
+			var custom any
 			var propData = make(map[string]string)
 			switch prefix {
 			case "p":
 				if p.curItem != nil {
 					p.curItem.hasPProperties = true
 				}
+				if name == "content" && curItem == nil && hasChildElement(node) {
+					p.warn(node, "p-content on <%s> contains markup, which is not included in its value; e-content may have been intended", node.Data)
+				}
 				value = getValueClassPattern(node)
+				if value != nil {
+					p.trace(TraceValueClass, node)
+				}
 				if value == nil && isAtom(node, atom.Abbr, atom.Link) {
 					value = getAttrPtr(node, "title")
 				}
@@ -373,9 +968,33 @@ func (p *parser) walk(node *html.Node) {
 				if value == nil && isAtom(node, atom.Img, atom.Area) {
 					value = getAttrPtr(node, "alt")
 				}
+				if value == nil && isAtom(node, atom.Select) {
+					value = getSelectedOption(node)
+				}
+				// <meta> is not in the parsing spec either, but it has no
+				// text, so its content attribute is used instead.
+				if value == nil && isAtom(node, atom.Meta) {
+					value = getAttrPtr(node, "content")
+				}
+				if value == nil && p.svgTitles && node.Namespace == "svg" {
+					value = getSVGTitle(node)
+				}
 				if value == nil {
 					value = new(string)
-					*value = strings.TrimSpace(getTextContent(node, p.imageAltSrcValue))
+					*value = p.trimText(p.propertyText(node, curItem != nil))
+				}
+				// <meter> and <progress> are not in the parsing spec, so their
+				// fallback text is used like any other element, but without
+				// fallback text their value attribute is better than nothing.
+				if *value == "" && isAtom(node, atom.Meter, atom.Progress) {
+					if v := getAttrPtr(node, "value"); v != nil {
+						value = v
+					}
+				}
+				if *value == "" && p.ariaLabelFallback {
+					if label := strings.TrimSpace(getAttr(node, "aria-label")); label != "" {
+						value = &label
+					}
 				}
 				if curItem != nil && p.curItem != nil {
 					embedValue = getFirstPropValue(curItem, "name")
@@ -387,7 +1006,7 @@ func (p *parser) walk(node *html.Node) {
 				if value == nil && isAtom(node, atom.A, atom.Area, atom.Link) {
 					value = getAttrPtr(node, "href")
 				}
-				if value == nil && isAtom(node, atom.Img) {
+				if value == nil && (isAtom(node, atom.Img) || isAMPElement(node, ampImages)) {
 					value = getAttrPtr(node, "src")
 					if p.curItem != nil && !p.curItem.backcompat {
 						if alt := imageAltValue(node); alt != "" {
@@ -395,17 +1014,44 @@ func (p *parser) walk(node *html.Node) {
 						}
 					}
 				}
-				if value == nil && isAtom(node, atom.Audio, atom.Video, atom.Source) {
+				if value == nil && isAtom(node, atom.Picture) {
+					// use the fallback image of responsive images
+					if img := getOnlyChildAtom(node, atom.Img); img != nil {
+						value = getAttrPtr(img, "src")
+						if p.curItem != nil && !p.curItem.backcompat {
+							if alt := imageAltValue(img); alt != "" {
+								propData["alt"] = alt
+							}
+						}
+					}
+				}
+				if p.srcset && value != nil && isAtom(node, atom.Img, atom.Picture) && p.curItem != nil && !p.curItem.backcompat {
+					if srcset := p.srcsetValue(node); srcset != "" {
+						propData["srcset"] = srcset
+					}
+				}
+				if value == nil && node.Namespace == "svg" && isAtom(node, atom.Image) {
+					value = getAttrPtr(node, "href")
+				}
+				if value == nil && (isAtom(node, atom.Audio, atom.Video, atom.Source) || isAMPElement(node, ampMedia)) {
 					value = getAttrPtr(node, "src")
 				}
 				if value == nil && isAtom(node, atom.Object) {
 					value = getAttrPtr(node, "data")
 				}
-				if value == nil && isAtom(node, atom.Video) {
+				if value == nil && (isAtom(node, atom.Video) || isAMPElement(node, ampMedia)) {
 					value = getAttrPtr(node, "poster")
 				}
+				// cite is not in the parsing spec, but the text of a quotation
+				// or edit is not a URL, and its cite attribute is.
+				if value == nil && isAtom(node, atom.Blockquote, atom.Q, atom.Ins, atom.Del) {
+					value = getAttrPtr(node, "cite")
+				}
 				if value == nil {
-					value = getValueClassPattern(node)
+					value = getURLValueClassPattern(node)
+					if value != nil {
+						p.trace(TraceValueClass, node)
+					}
 				}
 				if value == nil && isAtom(node, atom.Abbr) {
 					value = getAttrPtr(node, "title")
@@ -417,8 +1063,21 @@ func (p *parser) walk(node *html.Node) {
 					value = new(string)
 					*value = strings.TrimSpace(getTextContent(node, nil))
 				}
-				if value != nil {
+				if p.rawURLs && value != nil {
+					raw := strings.TrimSpace(*value)
+					rawValue = &raw
+				}
+				if value != nil && name == "uid" {
+					*value = strings.TrimSpace(expandUID(strings.TrimSpace(*value), p.base))
+				} else if value != nil && name == "key" {
+					*value = strings.TrimSpace(expandKey(strings.TrimSpace(*value), p.base))
+				} else if value != nil {
 					*value = strings.TrimSpace(expandURL(*value, p.base))
+					if p.stripParams != nil {
+						// keep the parameters in the attribute, which may be in e-* html
+						stripped := p.stripURLParams(*value)
+						value = &stripped
+					}
 				}
 				if curItem != nil && p.curItem != nil {
 					embedValue = getFirstPropValue(curItem, "url")
@@ -433,27 +1092,25 @@ func (p *parser) walk(node *html.Node) {
 					p.curItem.hasEProperties = true
 				}
 				value = new(string)
-				*value = strings.TrimSpace(getTextContent(node, p.imageAltSrcValue))
-				var buf bytes.Buffer
-
-				for c := node.FirstChild; c != nil; c = c.NextSibling {
-					p.expandAttrURLs(c) // microformats/microformats2-parsing#38
-
-					// ignore errors from html.Render which nearly always result from being unable
-					// to write to the underlying io.Writer, which never happens with bytes.Buffer.
-					_ = html.Render(&buf, c)
+				if p.blockText {
+					*value = getBlockTextContent(node, p.imageAltSrcValue, p.contentSkipFunc())
+				} else {
+					*value = strings.TrimSpace(textContent(node, p.imageAltSrcValue, p.contentSkipFunc()))
+				}
+				propData["html"] = p.innerHTML(node)
+				p.recordContentURLs(node, index)
+				if p.contentMarkdown {
+					propData["markdown"] = p.markdown(node)
 				}
-				htmlbody := strings.TrimSpace(buf.String())
-
-				// HTML spec: Serializing HTML Fragments algorithm does not include
-				// a trailing slash, so remove it.  Nor should apostrophes be
-				// encoded, which golang.org/x/net/html is doing.
-				htmlbody = strings.ReplaceAll(htmlbody, `/>`, `>`)
-				htmlbody = strings.ReplaceAll(htmlbody, `&#39;`, `'`)
-				propData["html"] = htmlbody
 			case "dt":
 				if value == nil {
 					value = getDateTimeValue(node)
+					if value != nil {
+						p.trace(TraceValueClass, node)
+					}
+				}
+				if value == nil && containsString(classes, "value-title") {
+					value = getDateTimeTitle(node)
 				}
 				if value == nil && isAtom(node, atom.Time, atom.Ins, atom.Del) {
 					value = getAttrPtr(node, "datetime")
@@ -468,11 +1125,33 @@ func (p *parser) walk(node *html.Node) {
 					value = new(string)
 					*value = strings.TrimSpace(getTextContent(node, nil))
 				}
+				if p.unixTimestamps {
+					if ts := unixTimestamp(*value); ts != *value {
+						value = &ts
+					}
+				}
+			default:
+				custom = p.prefixes[prefix](node, p.base)
+				if s, ok := custom.(string); ok {
+					value = &s
+				}
+			}
+			if p.normForm != nil && value != nil && (prefix == "p" || prefix == "e") {
+				*value = p.normForm.String(*value)
+			}
+			if p.maxValueLength > 0 {
+				value = p.limitValue(node, prefix, name, value, propData)
+			}
+			if prefix == "u" && name != "uid" && name != "key" && value != nil && !p.allowURL(node, name, *value) {
+				value = nil
 			}
 			if curItem != nil && p.curItem != nil {
 				if embedValue == nil {
 					embedValue = value
 				}
+				if embedValue == nil {
+					embedValue = new(string)
+				}
 				p.curItem.Properties[name] = append(p.curItem.Properties[name], &Microformat{
 					ID:         curItem.ID,
 					Type:       curItem.Type,
@@ -481,14 +1160,37 @@ func (p *parser) walk(node *html.Node) {
 					Shape:      curItem.Shape,
 					Value:      *embedValue,
 					HTML:       propData["html"],
+					RawURLs:    curItem.RawURLs,
+					Commands:   curItem.Commands,
+					TypeLabels: curItem.TypeLabels,
+					DOMPath:    curItem.DOMPath,
 				})
+				if prefix == "u" {
+					p.recordURL(*embedValue, index)
+				}
+				p.addRawURL(name, rawValue)
+				p.trace(TraceProperty, node)
+			} else if custom != nil && value == nil && p.curItem != nil {
+				p.curItem.Properties[name] = append(p.curItem.Properties[name], custom)
+				p.trace(TraceProperty, node)
 			} else if value != nil && p.curItem != nil {
+				if p.lowercaseEmails && name == "email" {
+					*value = lowercaseEmail(*value)
+				}
 				if len(propData) > 0 {
 					propData["value"] = *value
 					p.curItem.Properties[name] = append(p.curItem.Properties[name], propData)
 				} else {
 					p.curItem.Properties[name] = append(p.curItem.Properties[name], *value)
 				}
+				if p.curItem.backcompat && (name == "tel" || name == "email") {
+					p.addTypeLabels(name, node)
+				}
+				if prefix == "u" {
+					p.recordURL(*value, index)
+				}
+				p.addRawURL(name, rawValue)
+				p.trace(TraceProperty, node)
 			}
 		}
 	} else {
@@ -499,12 +1201,96 @@ func (p *parser) walk(node *html.Node) {
 	}
 }
 
-// getClasses returns all of the classes on node.
+// getSelectedOption returns the label of the option displayed by node, a
+// <select> element, as the value of p-* properties.  This is not in the
+// parsing spec, but is a better value than the text of every option.  The
+// displayed option is the first one with a selected attribute, or else the
+// first option.  Its label is its label attribute, or else its text with
+// whitespace collapsed.  If node has no options, an empty string is
+// returned.
+func getSelectedOption(node *html.Node) *string {
+	var first, selected *html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		for c := n.FirstChild; c != nil && selected == nil; c = c.NextSibling {
+			switch {
+			case isAtom(c, atom.Option):
+				if first == nil {
+					first = c
+				}
+				if hasAttr(c, "selected") {
+					selected = c
+				}
+			case isAtom(c, atom.Optgroup):
+				find(c)
+			}
+		}
+	}
+	find(node)
+	if selected == nil {
+		selected = first
+	}
+	label := ""
+	if selected != nil {
+		label = getAttr(selected, "label")
+		if label == "" {
+			label = strings.Join(strings.Fields(getTextContent(selected, nil)), " ")
+		}
+	}
+	return &label
+}
+
+// hasPropertyPrefix returns whether class begins with one of the
+// microformats2 property prefixes, as a quick check before matching
+// propertyClassNames.
+func hasPropertyPrefix(class string) bool {
+	return strings.HasPrefix(class, "p-") || strings.HasPrefix(class, "u-") ||
+		strings.HasPrefix(class, "e-") || strings.HasPrefix(class, "dt-")
+}
+
+// getClasses returns all of the classes on node.  Duplicate classes are
+// only included once, in the position of their first occurrence.
 func getClasses(node *html.Node) []string {
-	if c := getAttrPtr(node, "class"); c != nil {
-		return strings.Fields(*c)
+	c := getAttrPtr(node, "class")
+	if c == nil {
+		return nil
 	}
-	return nil
+	classes := splitTokens(*c)
+	for i := 1; i < len(classes); i++ {
+		for j := 0; j < i; j++ {
+			if classes[i] == classes[j] {
+				classes = append(classes[:i], classes[i+1:]...)
+				i--
+				break
+			}
+		}
+	}
+	return classes
+}
+
+// splitTokens splits s into tokens separated by ASCII whitespace, as used by
+// HTML attributes such as class and rel.  Other Unicode whitespace, such as
+// non-breaking spaces, is part of a token.  No empty tokens are returned.
+func splitTokens(s string) []string {
+	var tokens []string
+	start := -1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', '\f', '\r':
+			if start >= 0 {
+				tokens = append(tokens, s[start:i])
+				start = -1
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
 }
 
 // hasMatchingClass whether node contains a class that matches regex.
@@ -533,7 +1319,9 @@ func getAttrPtr(node *html.Node, name string) *string {
 		return nil
 	}
 	for i, attr := range node.Attr {
-		if strings.EqualFold(attr.Key, name) {
+		// attribute keys are lowercased by the HTML parser, so EqualFold
+		// is rarely needed
+		if attr.Key == name || strings.EqualFold(attr.Key, name) {
 			return &node.Attr[i].Val
 		}
 	}
@@ -545,6 +1333,16 @@ func hasAttr(node *html.Node, name string) bool {
 	return getAttrPtr(node, name) != nil
 }
 
+// hasChildElement returns whether node has any child elements.
+func hasChildElement(node *html.Node) bool {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return true
+		}
+	}
+	return false
+}
+
 // isAtom returns whether node's atom is one of atoms.
 func isAtom(node *html.Node, atoms ...atom.Atom) bool {
 	if node == nil {
@@ -560,26 +1358,120 @@ func isAtom(node *html.Node, atoms ...atom.Atom) bool {
 
 // getTextContent returns the text content of node, following the common
 // microformats v2 algorithm.  Nested script and style elements are ignored,
-// and img elements are run through imgFn.  If imgFn is nil, img elements are
-// ignored as well.
+// and nested img elements are run through imgFn.  If imgFn is nil, img
+// elements are ignored as well.  If node is itself an img element, imgFn is
+// not applied to it, since only nested images contribute to text content.
 func getTextContent(node *html.Node, imgFn func(*html.Node) string) string {
+	return textContent(node, imgFn, nil)
+}
+
+// textContent returns the text content of node like getTextContent, but
+// additionally ignores nested elements for which skip returns true.  If skip
+// is nil, no additional elements are ignored.
+func textContent(node *html.Node, imgFn func(*html.Node) string, skip func(*html.Node) bool) string {
+	return lineText(node, imgFn, skip, "")
+}
+
+// lineText returns the text content of node like textContent, but writes br
+// for each nested <br> element, so that the lines of multiline text are not
+// run together.
+func lineText(node *html.Node, imgFn func(*html.Node) string, skip func(*html.Node) bool, br string) string {
 	if node == nil {
 		return ""
 	}
 	if isAtom(node, atom.Script, atom.Style, atom.Template) {
 		return ""
 	}
-	if isAtom(node, atom.Img) && imgFn != nil {
-		return imgFn(node)
-	}
 	if node.Type == html.TextNode {
 		return node.Data
 	}
-	var buf bytes.Buffer
+	buf := getBuffer()
+	defer putBuffer(buf)
+	writeTextContent(buf, node, imgFn, skip, br)
+	return buf.String()
+}
+
+// writeTextContent writes the text content of the children of node to buf,
+// as described in lineText.
+func writeTextContent(buf *bytes.Buffer, node *html.Node, imgFn func(*html.Node) string, skip func(*html.Node) bool, br string) {
 	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		buf.WriteString(getTextContent(c, imgFn))
+		switch {
+		case c.Type == html.TextNode:
+			buf.WriteString(c.Data)
+		case skip != nil && c.Type == html.ElementNode && skip(c):
+		case isAtom(c, atom.Img) && imgFn != nil:
+			buf.WriteString(imgFn(c))
+		case isAtom(c, atom.Br):
+			buf.WriteString(br)
+		case isAtom(c, atom.Script, atom.Style, atom.Template):
+		default:
+			writeTextContent(buf, c, imgFn, skip, br)
+		}
 	}
-	return buf.String()
+}
+
+// propertyText returns the text content of node for its p-* property value,
+// the same as getTextContent with imageAltSrcValue, except that <br> elements
+// are written as p.lineBreak, so that the lines of a multiline value such as
+// an address are not run together, as other parsers do.  The text of node is
+// cached for reuse by its ancestors if cache is true, as it is for nested
+// microformats, whose ancestors are often properties too.
+func (p *parser) propertyText(node *html.Node, cache bool) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	p.writePropertyText(buf, node)
+	text := buf.String()
+	if cache {
+		if p.textCache == nil {
+			p.textCache = make(map[*html.Node]string)
+		}
+		p.textCache[node] = text
+	}
+	return text
+}
+
+// writePropertyText writes the text content of the children of node to buf,
+// as described in propertyText.
+func (p *parser) writePropertyText(buf *bytes.Buffer, node *html.Node) {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			buf.WriteString(c.Data)
+		case isAtom(c, atom.Img):
+			buf.WriteString(p.imageAltSrcValue(c))
+		case isAtom(c, atom.Br):
+			buf.WriteString(p.lineBreak())
+		case isAtom(c, atom.Script, atom.Style, atom.Template):
+		default:
+			if text, ok := p.textCache[c]; ok {
+				buf.WriteString(text)
+			} else {
+				p.writePropertyText(buf, c)
+			}
+		}
+	}
+}
+
+// bufferPool holds scratch buffers for building text and html values, which
+// are reused to reduce allocations when parsing many documents.
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// maxPooledBuffer is the largest buffer capacity returned to bufferPool, so
+// that a single large document does not pin memory indefinitely.
+const maxPooledBuffer = 64 << 10
+
+// getBuffer returns an empty buffer from bufferPool.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer returns buf to bufferPool.  buf must not be used afterwards.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
 }
 
 // imageAltValue returns the value of node's alt attribute.
@@ -638,10 +1530,13 @@ func getOnlyChildAtom(node *html.Node, atom atom.Atom) *html.Node {
 	return n
 }
 
-// getImpliedName gets the implied name value for node.
+// getImpliedName gets the implied name value for node.  Nested elements for
+// which skip returns true are ignored in the text content of node, and br is
+// written for each nested <br> element, as for p-* properties.  Leading and
+// trailing whitespace is not removed, which is left to trimText.
 //
 // See http://microformats.org/wiki/microformats2-parsing
-func getImpliedName(node *html.Node) string {
+func getImpliedName(node *html.Node, skip func(*html.Node) bool, br string) string {
 	var name *string
 
 	switch {
@@ -680,10 +1575,10 @@ func getImpliedName(node *html.Node) string {
 
 	if name == nil {
 		name = new(string)
-		*name = getTextContent(node, imageAltValue)
+		*name = lineText(node, imageAltValue, skip, br)
 	}
 
-	return strings.TrimSpace(*name)
+	return *name
 }
 
 // getImpliedPhoto gets the implied photo value for node.
@@ -740,10 +1635,13 @@ func getImpliedPhoto(node *html.Node, baseURL *url.URL) (src, alt string) {
 	return expandURL(*photo, baseURL), alt
 }
 
-// getImpliedURL gets the implied url value for node.
+// getImpliedURL gets the implied url value for node.  If skipAnchors is true,
+// nested links that only decorate the page, as described in isAnchorLink, are
+// ignored, as set by WithSkipAnchorLinks.  The href of node itself is always
+// used.
 //
 // See http://microformats.org/wiki/microformats2-parsing
-func getImpliedURL(node *html.Node, baseURL *url.URL) string {
+func getImpliedURL(node *html.Node, baseURL *url.URL, skipAnchors bool) string {
 	var value *string
 	if value == nil && isAtom(node, atom.A, atom.Area) {
 		value = getAttrPtr(node, "href")
@@ -752,13 +1650,13 @@ func getImpliedURL(node *html.Node, baseURL *url.URL) string {
 	if value == nil {
 		subnode := getOnlyChildAtom(node, atom.A)
 		if subnode != nil && !hasMatchingClass(subnode, rootClassNames) {
-			value = getAttrPtr(subnode, "href")
+			value = getImpliedHref(subnode, skipAnchors)
 		}
 	}
 	if value == nil {
 		subnode := getOnlyChildAtom(node, atom.Area)
 		if subnode != nil && !hasMatchingClass(subnode, rootClassNames) {
-			value = getAttrPtr(subnode, "href")
+			value = getImpliedHref(subnode, skipAnchors)
 		}
 	}
 
@@ -767,7 +1665,7 @@ func getImpliedURL(node *html.Node, baseURL *url.URL) string {
 		if subnode != nil && !hasMatchingClass(subnode, rootClassNames) {
 			subsubnode := getOnlyChildAtom(subnode, atom.A)
 			if subsubnode != nil && !hasMatchingClass(subsubnode, rootClassNames) {
-				value = getAttrPtr(subsubnode, "href")
+				value = getImpliedHref(subsubnode, skipAnchors)
 			}
 		}
 	}
@@ -776,7 +1674,7 @@ func getImpliedURL(node *html.Node, baseURL *url.URL) string {
 		if subnode != nil && !hasMatchingClass(subnode, rootClassNames) {
 			subsubnode := getOnlyChildAtom(subnode, atom.Area)
 			if subsubnode != nil && !hasMatchingClass(subsubnode, rootClassNames) {
-				value = getAttrPtr(subsubnode, "href")
+				value = getImpliedHref(subsubnode, skipAnchors)
 			}
 		}
 	}
@@ -787,11 +1685,57 @@ func getImpliedURL(node *html.Node, baseURL *url.URL) string {
 	return expandURL(*value, baseURL)
 }
 
+// getImpliedHref returns the href attribute of node for an implied url, or
+// nil if it has none, or if skipAnchors is true and it is an anchor link.
+func getImpliedHref(node *html.Node, skipAnchors bool) *string {
+	if skipAnchors && isAnchorLink(node) {
+		return nil
+	}
+	return getAttrPtr(node, "href")
+}
+
+// isAnchorLink returns whether node is a link to a fragment of the page that
+// only decorates it, such as the heading anchors generated when rendering
+// Markdown (<a class="anchor" href="#slug">#</a>), rather than representing
+// a microformat.  These are links to fragments that are hidden from
+// assistive technology, or <a> elements with no text or images other than
+// symbols such as "#" or "¶".
+func isAnchorLink(node *html.Node) bool {
+	href := getAttrPtr(node, "href")
+	if href == nil || !strings.HasPrefix(strings.TrimSpace(*href), "#") {
+		return false
+	}
+	if strings.EqualFold(getAttr(node, "aria-hidden"), "true") {
+		return true
+	}
+	if !isAtom(node, atom.A) {
+		return false
+	}
+	text := getTextContent(node, func(img *html.Node) string {
+		if alt := getAttrPtr(img, "alt"); alt != nil || !hasAttr(img, "src") {
+			return imageAltValue(img)
+		}
+		return "image" // an image without alt text is still content
+	})
+	return strings.IndexFunc(text, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) < 0
+}
+
 // getValueClassPattern gets the value of node using the value class pattern.
-//
-// See http://microformats.org/wiki/value-class-pattern
 func getValueClassPattern(node *html.Node) *string {
-	values := parseValueClassPattern(node, false)
+	return joinValues(parseValueClassPattern(node, vcpText))
+}
+
+// getURLValueClassPattern gets the value of node using the value class
+// pattern, with the rules for u-* properties.
+func getURLValueClassPattern(node *html.Node) *string {
+	return joinValues(parseValueClassPattern(node, vcpURL))
+}
+
+// joinValues joins values into a single value, or returns nil if there are
+// no values.
+func joinValues(values []string) *string {
 	if len(values) > 0 {
 		val := strings.Join(values, "")
 		return &val
@@ -799,9 +1743,19 @@ func getValueClassPattern(node *html.Node) *string {
 	return nil
 }
 
-// parseValueClassPattern parses node for values using the value class pattern.
-// If dt is true, the rules for date and time parsing will be used.
-func parseValueClassPattern(node *html.Node, dt bool) []string {
+// vcpKind identifies the kind of property being parsed with the value class
+// pattern, which determines how value elements are read.
+type vcpKind int
+
+const (
+	vcpText     vcpKind = iota // p-* properties
+	vcpURL                     // u-* properties
+	vcpDateTime                // dt-* properties
+)
+
+// parseValueClassPattern parses node for values using the value class pattern,
+// following the rules for the specified kind of property.
+func parseValueClassPattern(node *html.Node, kind vcpKind) []string {
 	if node == nil {
 		return nil
 	}
@@ -818,16 +1772,20 @@ func parseValueClassPattern(node *html.Node, dt bool) []string {
 			}
 		}
 		if valueTitleClass {
+			// an empty or missing title is still a value, so that the
+			// property is empty rather than falling back to its text
 			values = append(values, getAttr(c, "title"))
 		} else if valueClass {
 			switch {
+			case kind == vcpURL && isAtom(c, atom.Img) && hasAttr(c, "src"):
+				values = append(values, getAttr(c, "src"))
 			case isAtom(c, atom.Img, atom.Area) && hasAttr(c, "alt"):
 				values = append(values, getAttr(c, "alt"))
 			case isAtom(c, atom.Data) && hasAttr(c, "value"):
 				values = append(values, getAttr(c, "value"))
 			case isAtom(c, atom.Abbr) && hasAttr(c, "title"):
 				values = append(values, getAttr(c, "title"))
-			case dt && isAtom(c, atom.Del, atom.Ins, atom.Time) && hasAttr(c, "datetime"):
+			case kind == vcpDateTime && isAtom(c, atom.Del, atom.Ins, atom.Time) && hasAttr(c, "datetime"):
 				values = append(values, getAttr(c, "datetime"))
 			default:
 				values = append(values, strings.TrimSpace(getTextContent(c, nil)))
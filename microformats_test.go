@@ -5,12 +5,15 @@ package microformats
 
 import (
 	"bytes"
+	"io"
 	"net/url"
 	"regexp"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
@@ -83,6 +86,7 @@ func Test_ExpandAttrURLs(t *testing.T) {
 
 func Test_ExpandURL(t *testing.T) {
 	example, _ := url.Parse("http://example.com/base/")
+	secure, _ := url.Parse("https://example.com/base/")
 	tests := []struct {
 		relative string
 		base     *url.URL
@@ -94,6 +98,17 @@ func Test_ExpandURL(t *testing.T) {
 		{"/", example, "http://example.com/"},
 		{"foo", example, "http://example.com/base/foo"},
 		{"/foo", example, "http://example.com/foo"},
+
+		// protocol-relative URLs inherit the scheme of base
+		{"//cdn.example.com/a.jpg", nil, "//cdn.example.com/a.jpg"},
+		{"//cdn.example.com/a.jpg", example, "http://cdn.example.com/a.jpg"},
+		{"//cdn.example.com/a.jpg", secure, "https://cdn.example.com/a.jpg"},
+		{"//cdn.example.com", secure, "https://cdn.example.com"},
+
+		// fragments replace any fragment of base, keeping its path and query
+		{"#post-1", nil, "#post-1"},
+		{"#post-1", example, "http://example.com/base/#post-1"},
+		{"#post-1", &url.URL{Scheme: "http", Host: "example.com", Path: "/page", RawQuery: "a=1", Fragment: "top"}, "http://example.com/page?a=1#post-1"},
 	}
 
 	for _, tt := range tests {
@@ -104,6 +119,77 @@ func Test_ExpandURL(t *testing.T) {
 	}
 }
 
+func Test_ExpandUID(t *testing.T) {
+	example, _ := url.Parse("http://example.com/base/")
+	tests := []struct {
+		uid  string
+		want string
+	}{
+		{"", "http://example.com/base/"},
+		{"/posts/1", "http://example.com/posts/1"},
+		{"12345", "http://example.com/base/12345"},
+		{"HTTPS://example.com/1", "https://example.com/1"},
+		{"tag:example.com,2024:post-1", "tag:example.com,2024:post-1"},
+		{"TAG:Example.com,2024:post-1#a b", "TAG:Example.com,2024:post-1#a b"},
+		{"urn:uuid:6e8bc430-9c3a-11d9-9669-0800200c9a66", "urn:uuid:6e8bc430-9c3a-11d9-9669-0800200c9a66"},
+		{"post 42", "post 42"},
+	}
+
+	for _, tt := range tests {
+		got := expandUID(tt.uid, example)
+		if got != tt.want {
+			t.Errorf("expandUID(%q) returned %q, want %q", tt.uid, got, tt.want)
+		}
+	}
+}
+
+func Test_Parse_UID(t *testing.T) {
+	tests := []struct {
+		html string
+		want []any
+	}{
+		{`<div class="h-entry"><a class="u-uid" href="tag:example.com,2024:post-1">x</a></div>`, []any{"tag:example.com,2024:post-1"}},
+		{`<div class="h-entry"><data class="u-uid" value="urn:uuid:6e8bc430-9c3a-11d9-9669-0800200c9a66">x</data></div>`, []any{"urn:uuid:6e8bc430-9c3a-11d9-9669-0800200c9a66"}},
+		{`<div class="h-entry"><span class="u-uid"> post 42 </span></div>`, []any{"post 42"}},
+		{`<div class="h-entry"><a class="u-uid" href="/posts/1">x</a></div>`, []any{"http://example.com/posts/1"}},
+	}
+
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		if len(items) != 1 {
+			t.Fatalf("Parse(%q) returned %d items, want 1", tt.html, len(items))
+		}
+		if got := items[0].Properties["uid"]; !cmp.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) returned uid %v, want %v", tt.html, got, tt.want)
+		}
+	}
+}
+
+func Test_Parse_BaseURL(t *testing.T) {
+	example, _ := url.Parse("http://example.com/dir/page")
+	tests := []struct {
+		html string
+		base *url.URL
+		want string
+	}{
+		{`<p>no base</p>`, nil, ""},
+		{`<p>no base</p>`, example, "http://example.com/dir/page"},
+		{`<base href="/other/"><base href="/ignored/">`, example, "http://example.com/other/"},
+		{`<base target="_blank"><base href="https://example.net/">`, nil, "https://example.net/"},
+	}
+
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(tt.html), tt.base)
+		var got string
+		if data.BaseURL != nil {
+			got = data.BaseURL.String()
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q, %v) returned BaseURL %q, want %q", tt.html, tt.base, got, tt.want)
+		}
+	}
+}
+
 func Test_GetClasses(t *testing.T) {
 	tests := []struct {
 		html    string
@@ -118,6 +204,10 @@ func Test_GetClasses(t *testing.T) {
 		{`<img class=" a
 		b	c ">`, []string{"a", "b", "c"}},
 		{`<img class="http://example.com/ b">`, []string{"http://example.com/", "b"}},
+		{`<img class="a b a">`, []string{"a", "b"}},
+		{"<img class=\"  h-card \t\r\n p-name\f \">", []string{"h-card", "p-name"}},
+		{"<img class=\"a\u00a0b c\">", []string{"a\u00a0b", "c"}},
+		{`<img class="   ">`, nil},
 
 		{`<img CLASS="a">`, []string{"a"}},
 	}
@@ -259,6 +349,8 @@ func Test_GetTextContent(t *testing.T) {
 		{"<a><b>foo</b><i>bar</i></a>", nil, "foobar"},
 		{"<a> <b>foo</b> <i>bar</i> </a>", nil, " foo bar "},
 		{"<a><b><i>foo</i></b>bar</a>", nil, "foobar"},
+		{"<a><!-- comment --></a>", nil, ""},
+		{"<a>foo<!-- comment -->bar</a>", nil, "foobar"},
 
 		// test image functions
 		{"<a><img alt='foo'></a>", nil, ""},
@@ -396,7 +488,7 @@ func Test_GetImpliedName(t *testing.T) {
 			t.Fatalf("Error parsing HTML: %v", err)
 		}
 
-		if got, want := getImpliedName(n), tt.name; got != want {
+		if got, want := strings.TrimSpace(getImpliedName(n, nil, "")), tt.name; got != want {
 			t.Errorf("getImpliedName(%q) returned %v, want %v", tt.html, got, want)
 		}
 	}
@@ -488,12 +580,50 @@ func Test_GetImpliedURL(t *testing.T) {
 			t.Fatalf("Error parsing HTML: %v", err)
 		}
 
-		if got, want := getImpliedURL(n, tt.base), tt.url; got != want {
+		if got, want := getImpliedURL(n, tt.base, false), tt.url; got != want {
 			t.Errorf("getImpliedURL(%q, %s) returned %v, want %v", tt.html, tt.base, got, want)
 		}
 	}
 }
 
+func Test_GetImpliedURL_SkipAnchors(t *testing.T) {
+	base, _ := url.Parse("http://example.com/")
+	tests := []struct {
+		html    string
+		url     string // by default
+		skipped string // when skipping anchor links
+	}{
+		// nested anchor links are skipped, but other fragment links are not
+		{`<p><a href="#">#</a></p>`, "http://example.com/", ""},
+		{`<p><a href="#slug">#</a></p>`, "http://example.com/#slug", ""},
+		{`<p><a class="headerlink" href="#slug">¶</a></p>`, "http://example.com/#slug", ""},
+		{`<p><a aria-hidden="true" href="#slug">Link to heading</a></p>`, "http://example.com/#slug", ""},
+		{`<p><area aria-hidden="true" href="#slug"></p>`, "http://example.com/#slug", ""},
+		{`<p><a href="#post-1">Post 1</a></p>`, "http://example.com/#post-1", "http://example.com/#post-1"},
+		{`<p><a href="#post-1"><img src="1.jpg"></a></p>`, "http://example.com/#post-1", "http://example.com/#post-1"},
+		{`<p><area href="#post-1"></p>`, "http://example.com/#post-1", "http://example.com/#post-1"},
+		{`<p><a href="/">#</a></p>`, "http://example.com/", "http://example.com/"},
+
+		// the element's own href is always used
+		{`<a href="#slug">#</a>`, "http://example.com/#slug", "http://example.com/#slug"},
+		{`<area aria-hidden="true" href="#slug">`, "http://example.com/#slug", "http://example.com/#slug"},
+	}
+
+	for _, tt := range tests {
+		n, err := parseNode(tt.html)
+		if err != nil {
+			t.Fatalf("Error parsing HTML: %v", err)
+		}
+
+		if got, want := getImpliedURL(n, base, false), tt.url; got != want {
+			t.Errorf("getImpliedURL(%q, %s, false) returned %v, want %v", tt.html, base, got, want)
+		}
+		if got, want := getImpliedURL(n, base, true), tt.skipped; got != want {
+			t.Errorf("getImpliedURL(%q, %s, true) returned %v, want %v", tt.html, base, got, want)
+		}
+	}
+}
+
 func Test_GetValueClassPattern(t *testing.T) {
 	tests := []struct {
 		html  string
@@ -523,6 +653,22 @@ func Test_GetValueClassPattern(t *testing.T) {
 		// value-title
 		{`<p><img alt="v" class="value-title" title="t"></p>`, ptr("t")},
 		{`<p><img alt="v" class="value" title="t"><img alt="v" class="value-title" title="t"></p>`, ptr("vt")},
+
+		// an empty or missing title contributes an empty string, rather than
+		// being skipped
+		{`<p><span class="value-title" title="">v</span></p>`, ptr("")},
+		{`<p><span class="value-title">v</span></p>`, ptr("")},
+		{`<p><span class="value-title" title="">v</span><b class="value">b</b></p>`, ptr("b")},
+
+		// mixed value and value-title elements contribute in document order,
+		// the text of value elements and the title of value-title elements,
+		// ignoring text outside of them
+		{`<p><b class="value">+1</b> <span class="value-title" title="555"></span> ext. <b class="value">0100</b></p>`, ptr("+15550100")},
+		{`<p><span class="value-title" title="a"></span><b class="value">b</b><span class="value-title" title="c">x</span></p>`, ptr("abc")},
+		// a value-title element is not searched for value elements
+		{`<p><span class="value-title" title="a"><b class="value">x</b></span><b class="value">b</b></p>`, ptr("ab")},
+		// an element with both classes is a value-title
+		{`<p><b class="value value-title" title="a">x</b><b class="value">b</b></p>`, ptr("ab")},
 	}
 
 	for _, tt := range tests {
@@ -537,6 +683,158 @@ func Test_GetValueClassPattern(t *testing.T) {
 	}
 }
 
+func Test_GetURLValueClassPattern(t *testing.T) {
+	tests := []struct {
+		html  string
+		value *string
+	}{
+		{"", nil},
+		{`<p><img src="s" alt="a"></p>`, nil},
+		{`<p><img class="value" src="s" alt="a"></p>`, ptr("s")},
+		{`<p><img class="value" alt="a"></p>`, ptr("a")},
+		{`<p><span class="value">/a</span><img class="value" src="b.png"></p>`, ptr("/ab.png")},
+	}
+
+	for _, tt := range tests {
+		n, err := parseNode(tt.html)
+		if err != nil {
+			t.Fatalf("Error parsing HTML: %v", err)
+		}
+
+		if got, want := getURLValueClassPattern(n), tt.value; !cmp.Equal(got, want) {
+			t.Errorf("getURLValueClassPattern(%q) returned %v, want %v", tt.html, got, want)
+		}
+	}
+}
+
+func Test_Parse_EmptyValueTitle(t *testing.T) {
+	doc := `<div class="h-card"><span class="p-name">Jane</span>
+		<span class="p-note"><span class="value-title" title="">not the note</span></span>
+		<span class="p-org"><span class="value-title" title="">x</span><span class="value">Acme</span> Inc</span>
+	</div>`
+	want := map[string][]any{
+		"name": {"Jane"},
+		"note": {""},
+		"org":  {"Acme"},
+	}
+	items := parseItems(doc)
+	if diff := cmp.Diff(want, items[0].Properties); diff != "" {
+		t.Errorf("Parse mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_Parse_ValueClassImages(t *testing.T) {
+	doc := `<div class="h-card">
+		<span class="p-country-name"><img class="value" src="/flags/fr.png" alt="France"> (EU)</span>
+		<span class="u-logo"><img class="value" src="/logo.png" alt="Logo"></span>
+		<span class="p-name"><span class="value">Jane</span></span>
+	</div>`
+
+	want := map[string][]any{
+		"country-name": {"France"},
+		"logo":         {"http://example.com/logo.png"},
+		"name":         {"Jane"},
+	}
+	items := parseItems(doc)
+	if diff := cmp.Diff(want, items[0].Properties); diff != "" {
+		t.Errorf("Parse(%q) mismatch (-want +got):\n%s", doc, diff)
+	}
+}
+
+func Test_Parse_MixedValueClass(t *testing.T) {
+	doc := `<div class="h-card"><span class="p-name">Jane</span>
+		<span class="p-tel">Call <span class="value">+1</span>
+			<span class="value-title" title="555"> (five five five) </span>
+			<abbr class="value" title="0100">one hundred</abbr> today</span>
+	</div>`
+
+	want := map[string][]any{
+		"name": {"Jane"},
+		"tel":  {"+15550100"},
+	}
+	items := parseItems(doc)
+	if diff := cmp.Diff(want, items[0].Properties); diff != "" {
+		t.Errorf("Parse(%q) mismatch (-want +got):\n%s", doc, diff)
+	}
+}
+
+func Test_Parse_AbbrTitle(t *testing.T) {
+	tests := []struct {
+		html string
+		want map[string][]any
+	}{
+		{`<div class="h-card"><abbr class="p-tz" title="America/New_York">EST</abbr></div>`,
+			map[string][]any{"tz": {"America/New_York"}}},
+		{`<div class="h-card"><abbr class="p-tz">EST</abbr></div>`,
+			map[string][]any{"tz": {"EST"}}},
+		// an empty title is still used over the text
+		{`<div class="h-card"><abbr class="p-tz" title="">EST</abbr></div>`,
+			map[string][]any{"tz": {""}}},
+		// the value class pattern takes precedence over title
+		{`<div class="h-card"><abbr class="p-tz" title="America/New_York"><span class="value">EST</span></abbr></div>`,
+			map[string][]any{"tz": {"EST"}}},
+		// title is not used for other elements
+		{`<div class="h-card"><span class="p-tz" title="America/New_York">EST</span></div>`,
+			map[string][]any{"tz": {"EST"}}},
+
+		// microformats v1
+		{`<div class="vcard"><abbr class="tz" title="-05:00">EST</abbr></div>`,
+			map[string][]any{"tz": {"-05:00"}}},
+		{`<div class="vcard"><span class="geo"><abbr class="latitude" title="37.77">N 37°</abbr>
+			<abbr class="longitude" title="-122.41">W 122°</abbr></span></div>`,
+			map[string][]any{"geo": {&Microformat{
+				Type:       []string{"h-geo"},
+				Properties: map[string][]any{"latitude": {"37.77"}, "longitude": {"-122.41"}},
+				Value:      "N 37°\n\t\t\tW 122°",
+			}}}},
+	}
+
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		props := items[0].Properties
+		delete(props, "name")
+		if diff := cmp.Diff(tt.want, props, ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_MetaContent(t *testing.T) {
+	tests := []struct {
+		html string
+		want map[string][]any
+	}{
+		{`<div class="h-entry"><meta class="p-category" content="indieweb"></div>`,
+			map[string][]any{"category": {"indieweb"}}},
+		// with or without a name attribute
+		{`<div class="h-entry"><meta name="keywords" class="p-category" content="indieweb"></div>`,
+			map[string][]any{"category": {"indieweb"}}},
+		// alongside visible properties
+		{`<div class="h-entry"><p class="p-name">Hello</p><meta class="p-summary" content="A greeting"></div>`,
+			map[string][]any{"name": {"Hello"}, "summary": {"A greeting"}}},
+		// in a nested microformat
+		{`<div class="h-entry"><div class="p-author h-card"><meta class="p-name" content="Jane"></div></div>`,
+			map[string][]any{"author": {&Microformat{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+				Value:      "Jane",
+			}}}},
+		// without content, the value is empty
+		{`<div class="h-entry"><meta class="p-category"></div>`,
+			map[string][]any{"category": {""}}},
+	}
+
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		if len(items) != 1 {
+			t.Fatalf("Parse(%q) returned %d items, want 1", tt.html, len(items))
+		}
+		if diff := cmp.Diff(tt.want, items[0].Properties, ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) properties mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
 func Test_GetFirstPropValue(t *testing.T) {
 	tests := []struct {
 		properties map[string][]any
@@ -581,3 +879,1070 @@ func Test_ParseNodeNil(t *testing.T) {
 		})
 	}
 }
+
+func Test_ExtractProperties(t *testing.T) {
+	n, err := parseNode(`<div class="p-ignored comment">
+		<span class="p-name">Reply</span>
+		<a class="u-url" href="/reply">permalink</a>
+		<time class="dt-published" datetime="2024-01-02">Jan 2</time>
+		<div class="p-author h-card"><a class="p-name u-url" href="/jane">Jane</a></div>
+		<div class="h-cite"><span class="p-name">ignored</span></div>
+		<span class="fn">v1</span>
+	</div>`)
+	if err != nil {
+		t.Fatalf("Error parsing HTML: %v", err)
+	}
+	base, _ := url.Parse("http://example.com/")
+
+	want := map[string][]any{
+		"name":      {"Reply"},
+		"url":       {"http://example.com/reply"},
+		"published": {"2024-01-02"},
+		"author": {&Microformat{
+			Type: []string{"h-card"},
+			Properties: map[string][]any{
+				"name": {"Jane"},
+				"url":  {"http://example.com/jane"},
+			},
+			Value: "Jane",
+		}},
+	}
+	if diff := cmp.Diff(want, ExtractProperties(n, base), ignoreParseState); diff != "" {
+		t.Errorf("ExtractProperties mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := ExtractProperties(nil, base); got != nil {
+		t.Errorf("ExtractProperties(nil) returned %v, want nil", got)
+	}
+}
+
+// parseItems parses the HTML document s with a base URL of
+// http://example.com/ and returns the top-level microformats found.
+func parseItems(s string) []*Microformat {
+	base, _ := url.Parse("http://example.com/")
+	return Parse(strings.NewReader(s), base).Items
+}
+
+// ignore unexported fields that track parse state when comparing microformats.
+var ignoreParseState = cmpopts.IgnoreUnexported(Microformat{}, Data{})
+
+func Test_Parse_DuplicateClasses(t *testing.T) {
+	tests := []struct {
+		html string
+		want []*Microformat
+	}{
+		{
+			`<div class="h-card h-card"><span class="p-name p-name">Jane</span></div>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+		{
+			`<div class="h-card"><a class="p-name u-url p-name" href="/jane">Jane</a></div>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"url":  {"http://example.com/jane"},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, parseItems(tt.html), ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_MultipleRootClasses(t *testing.T) {
+	tests := []struct {
+		html string
+		want []*Microformat
+	}{
+		{
+			`<div class="h-entry h-card"><span class="p-name">Jane</span><a class="u-url" href="/jane">home</a></div>`,
+			[]*Microformat{{
+				Type: []string{"h-card", "h-entry"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"url":  {"http://example.com/jane"},
+				},
+			}},
+		},
+		{
+			`<div class="h-entry"><div class="p-author h-entry h-card"><span class="p-name">Jane</span></div></div>`,
+			[]*Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"author": {&Microformat{
+						Value:      "Jane",
+						Type:       []string{"h-card", "h-entry"},
+						Properties: map[string][]any{"name": {"Jane"}},
+					}},
+				},
+			}},
+		},
+		{
+			// v1 classes are ignored alongside v2 root classes
+			`<div class="h-card vcard h-entry hentry"><span class="p-name fn">Jane</span></div>`,
+			[]*Microformat{{
+				Type:       []string{"h-card", "h-entry"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, parseItems(tt.html), ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_FormElementProperties(t *testing.T) {
+	tests := []struct {
+		html string
+		want map[string][]any
+	}{
+		{`<meter class="p-rating" min="0" max="5" value="4">4 out of 5</meter>`, map[string][]any{"rating": {"4 out of 5"}}},
+		{`<meter class="p-rating" min="0" max="5" value="4"></meter>`, map[string][]any{"rating": {"4"}}},
+		{`<progress class="p-progress" max="100" value="70"> </progress>`, map[string][]any{"progress": {"70"}}},
+		{`<progress class="p-progress"></progress>`, map[string][]any{"progress": {""}}},
+		{`<output class="p-total" name="total" for="a b">42</output>`, map[string][]any{"total": {"42"}}},
+		{`<meter class="dt-start" value="4"></meter>`, map[string][]any{"start": {""}}},
+		{`<output class="u-url">/total</output>`, map[string][]any{"url": {"http://example.com/total"}}},
+		{`<select class="p-category"><option>one</option><option selected> two
+			options</option></select>`, map[string][]any{"category": {"two options"}}},
+		{`<select class="p-category"><optgroup label="a"><option value="1">one</option></optgroup><option>two</option></select>`, map[string][]any{"category": {"one"}}},
+		{`<select class="p-category"><option label="One" value="1">1</option></select>`, map[string][]any{"category": {"One"}}},
+		{`<select class="p-category" multiple><option>one</option><option selected>two</option><option selected>three</option></select>`, map[string][]any{"category": {"two"}}},
+		{`<select class="p-category"></select>`, map[string][]any{"category": {""}}},
+	}
+
+	for _, tt := range tests {
+		doc := `<div class="h-review"><span class="p-name">Review</span>` + tt.html + `</div>`
+		items := parseItems(doc)
+		props := items[0].Properties
+		delete(props, "name")
+		if diff := cmp.Diff(tt.want, props); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_NestedPropertyValue(t *testing.T) {
+	tests := []struct {
+		html string
+		want *Microformat
+	}{
+		{
+			// the explicit p-name, including its value class pattern, is the value
+			`<div class="p-author h-card"><span class="p-name"><span class="value">Jane</span> <span class="value">Doe</span></span> <span>extra</span></div>`,
+			&Microformat{
+				Value:      "JaneDoe",
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"JaneDoe"}},
+			},
+		},
+		{
+			// the first p-name is used, even if other properties come first
+			`<div class="p-author h-card"><span class="p-nickname">JD</span> <span class="p-name">Jane</span> <span class="p-name">Doe</span></div>`,
+			&Microformat{
+				Value: "Jane",
+				Type:  []string{"h-card"},
+				Properties: map[string][]any{
+					"name":     {"Jane", "Doe"},
+					"nickname": {"JD"},
+				},
+			},
+		},
+		{
+			// without a name, the p-* value of the element is used, including
+			// value classes on its descendants
+			`<div class="p-author h-card"><span class="value">Jane</span> <span class="p-org">Org</span></div>`,
+			&Microformat{
+				Value:      "Jane",
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"org": {"Org"}},
+			},
+		},
+		{
+			// u-* properties use the url, not the name
+			`<a class="u-author h-card" href="/jane"><span class="p-name">Jane</span></a>`,
+			&Microformat{
+				Value: "http://example.com/jane",
+				Type:  []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"url":  {"http://example.com/jane"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		doc := `<div class="h-entry">` + tt.html + `</div>`
+		items := parseItems(doc)
+		if diff := cmp.Diff([]any{tt.want}, items[0].Properties["author"], ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_NestedPlacement(t *testing.T) {
+	card := func(name string) *Microformat {
+		return &Microformat{Value: name, Type: []string{"h-card"}, Properties: map[string][]any{"name": {name}}}
+	}
+	child := func(name string) *Microformat {
+		return &Microformat{Type: []string{"h-card"}, Properties: map[string][]any{"name": {name}}}
+	}
+
+	tests := []struct {
+		html     string
+		props    map[string][]any
+		children []*Microformat
+	}{
+		{
+			// nested roots with a property class are only property values,
+			// and those without are only children
+			`<div class="h-entry"><div class="p-author h-card">A</div><div class="h-card">B</div></div>`,
+			map[string][]any{"author": {card("A")}},
+			[]*Microformat{child("B")},
+		},
+		{
+			// a root with several property classes is a value of each
+			`<div class="h-entry"><div class="p-author p-x-contact h-card">A</div></div>`,
+			map[string][]any{"author": {card("A")}, "x-contact": {card("A")}},
+			nil,
+		},
+		{
+			// a root inside a plain property is a child of the microformat
+			`<div class="h-entry"><div class="p-note"><div class="h-card">C</div></div></div>`,
+			map[string][]any{"note": {"C"}},
+			[]*Microformat{child("C")},
+		},
+		{
+			`<div class="h-entry"><div class="e-content"><div class="h-card">D</div></div></div>`,
+			map[string][]any{"content": {map[string]string{"value": "D", "html": `<div class="h-card">D</div>`}}},
+			[]*Microformat{child("D")},
+		},
+		{
+			// v1 property classes are only properties of v1 roots
+			`<div class="hentry"><span class="entry-title">t</span><div class="author vcard"><span class="fn">A</span></div>
+				<div class="p-author h-card">B</div></div>`,
+			map[string][]any{"name": {"t"}, "author": {card("A")}},
+			[]*Microformat{child("B")},
+		},
+		{
+			`<div class="h-entry"><div class="author vcard"><span class="fn">A</span></div></div>`,
+			map[string][]any{},
+			[]*Microformat{child("A")},
+		},
+	}
+
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		if len(items) != 1 {
+			t.Fatalf("Parse(%q) returned %d items, want 1", tt.html, len(items))
+		}
+		if diff := cmp.Diff(tt.props, items[0].Properties, ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) properties mismatch (-want +got):\n%s", tt.html, diff)
+		}
+		if diff := cmp.Diff(tt.children, items[0].Children, ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) children mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_TopLevelPropertyClasses(t *testing.T) {
+	// property classes on a top-level root have no parent to be a property
+	// of, so the root is a top-level item and they are ignored
+	tests := []struct {
+		html string
+		want []*Microformat
+	}{
+		{
+			`<div class="h-card p-author"><span class="p-name">Jane</span></div>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+		{
+			// including properties that would otherwise be implied
+			`<a class="h-card u-url p-name e-content dt-published" href="/jane"><span class="p-nickname">jj</span></a>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"nickname": {"jj"}, "url": {"http://example.com/jane"}},
+			}},
+		},
+		{
+			// nested roots with property classes are still properties
+			`<div class="h-entry p-author"><p class="p-name">Post</p>
+				<div class="p-author h-card">Jane</div></div>`,
+			[]*Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"name": {"Post"},
+					"author": {&Microformat{
+						Type:       []string{"h-card"},
+						Properties: map[string][]any{"name": {"Jane"}},
+						Value:      "Jane",
+					}},
+				},
+			}},
+		},
+		{
+			`<div class="vcard author"><span class="fn">Jane</span></div>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := parseItems(tt.html)
+		if diff := cmp.Diff(tt.want, got, ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_ScriptAndStyle(t *testing.T) {
+	tests := []struct {
+		html string
+		want []*Microformat
+	}{
+		{`<script class="h-card">Jane</script><style class="h-entry">.x {}</style>`, []*Microformat{}},
+		{`<svg><script class="h-card">Jane</script><style class="h-card">.x {}</style></svg>`, []*Microformat{}},
+		{
+			`<div class="h-card"><span class="p-name">Jane</span><script class="p-note u-url">x</script><style class="p-org h-card">y</style></div>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, parseItems(tt.html), ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_ImagePProperties(t *testing.T) {
+	tests := []struct {
+		html string
+		want []*Microformat
+	}{
+		{
+			`<div class="h-card"><img class="p-name" src="logo.png" alt="Company"></div>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name":  {"Company"},
+					"photo": {map[string]string{"value": "http://example.com/logo.png", "alt": "Company"}},
+				},
+			}},
+		},
+		{
+			`<div class="h-card"><img class="p-name u-logo" src="logo.png" alt="Company"></div>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Company"},
+					"logo": {map[string]string{"value": "http://example.com/logo.png", "alt": "Company"}},
+				},
+			}},
+		},
+		{
+			`<div class="h-card"><map><area class="p-name" href="/" alt="Company" title="Title"></map></div>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Company"},
+					"url":  {"http://example.com/"},
+				},
+			}},
+		},
+		{
+			// without an alt attribute, the (empty) text content is used
+			`<div class="h-card"><img class="p-name" src="logo.png"><span class="p-org">Org</span></div>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name":  {""},
+					"org":   {"Org"},
+					"photo": {"http://example.com/logo.png"},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, parseItems(tt.html), ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_ForeignContent(t *testing.T) {
+	tests := []struct {
+		html string
+		want []*Microformat
+	}{
+		{
+			`<div class="h-card"><svg viewBox="0 0 10 10">
+				<a class="u-url" href="/jane"><text class="p-name">Jane</text></a>
+				<image class="u-photo" href="photo.png"/>
+				<image class="u-logo" xlink:href="logo.png"/>
+				<foreignObject><span class="p-org">Org</span></foreignObject>
+			</svg><math class="p-note"><mi>x</mi></math></div>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name":  {"Jane"},
+					"url":   {"http://example.com/jane"},
+					"photo": {"http://example.com/photo.png"},
+					"logo":  {"http://example.com/logo.png"},
+					"org":   {"Org"},
+					"note":  {"x"},
+				},
+			}},
+		},
+		{
+			// microformat roots within SVG
+			`<svg><g class="h-card"><text class="p-name">Jane</text></g></svg>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, parseItems(tt.html), ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_DocumentOrder(t *testing.T) {
+	doc := `<div class="h-feed">
+		<span class="p-category">one</span>
+		<div class="h-entry"><span class="p-name">first</span></div>
+		<div><p><span class="p-category">two</span></p></div>
+		<div class="h-entry"><span class="p-name">second</span></div>
+		<footer><ul><li><a class="p-category" href="/3">three</a></li></ul></footer>
+		<div class="h-entry"><span class="p-name">third</span></div>
+		<span class="p-category">four</span>
+	</div>`
+
+	items := parseItems(doc)
+	if len(items) != 1 {
+		t.Fatalf("Parse returned %d items, want 1", len(items))
+	}
+	feed := items[0]
+
+	if got, want := feed.Properties["category"], []any{"one", "two", "three", "four"}; !cmp.Equal(got, want) {
+		t.Errorf("category property returned %v, want %v", got, want)
+	}
+
+	var names []any
+	for _, child := range feed.Children {
+		names = append(names, child.Properties["name"]...)
+	}
+	if want := []any{"first", "second", "third"}; !cmp.Equal(names, want) {
+		t.Errorf("children names returned %v, want %v", names, want)
+	}
+}
+
+func Test_Parse_IndieWebProperties(t *testing.T) {
+	tests := []struct {
+		html string
+		prop string
+		want []any
+	}{
+		{
+			`<div class="h-entry"><img class="u-featured" src="/featured.jpg" alt=""></div>`,
+			"featured", []any{"http://example.com/featured.jpg"},
+		},
+		{
+			`<div class="h-entry"><img class="u-featured" src="/featured.jpg" alt="A view"></div>`,
+			"featured", []any{map[string]string{"value": "http://example.com/featured.jpg", "alt": "A view"}},
+		},
+		{
+			`<div class="h-entry"><data class="p-rsvp" value="yes">I'll be there</data></div>`,
+			"rsvp", []any{"yes"},
+		},
+		{
+			`<div class="h-entry"><span class="p-rsvp">maybe</span></div>`,
+			"rsvp", []any{"maybe"},
+		},
+		{
+			`<div class="h-entry"><data class="p-rsvp" value="Interested">?</data></div>`,
+			"rsvp", []any{"Interested"},
+		},
+		{
+			`<div class="h-entry">
+				<a class="u-syndication" href="https://social.example/1">one</a>
+				<a class="u-syndication" href="https://social.example/2">two</a>
+				<a class="u-syndication" href="https://social.example/1">one again</a>
+			</div>`,
+			"syndication", []any{"https://social.example/1", "https://social.example/2", "https://social.example/1"},
+		},
+	}
+
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		if len(items) != 1 {
+			t.Fatalf("Parse(%q) returned %d items, want 1", tt.html, len(items))
+		}
+		if got := items[0].Properties[tt.prop]; !cmp.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) %s property returned %v, want %v", tt.html, tt.prop, got, tt.want)
+		}
+	}
+}
+
+func Test_Parse_CommentsAndWhitespace(t *testing.T) {
+	tests := []struct {
+		html string
+		want []*Microformat
+	}{
+		{
+			`<div class="h-card"><span class="p-name"><!-- comment --></span></div>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {""}},
+			}},
+		},
+		{
+			"<div class=\"h-card\"><span class=\"p-name\"> <!-- a --> \n\t <!-- b --> </span></div>",
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {""}},
+			}},
+		},
+		{
+			`<div class="h-card"><span class="p-name"> Jane <!-- middle -->Doe </span></div>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane Doe"}},
+			}},
+		},
+		{
+			// comments are ignored in the text value, but preserved in the html
+			`<div class="h-entry"><div class="e-content"> <!-- c --> <p>hi<!-- d --></p> </div></div>`,
+			[]*Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{"content": {map[string]string{
+					"value": "hi",
+					"html":  "<!-- c --> <p>hi<!-- d --></p>",
+				}}},
+			}},
+		},
+		{
+			`<div class="h-entry"><div class="e-content"><!-- only --></div></div>`,
+			[]*Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{"content": {map[string]string{
+					"value": "",
+					"html":  "<!-- only -->",
+				}}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, parseItems(tt.html), ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_IDs(t *testing.T) {
+	doc := `<div class="h-feed" id="feed">
+		<div class="h-entry" id="first">
+			<a class="p-author h-card" id="author" href="/jane">Jane</a>
+			<div class="h-cite" id="cite"><span class="p-name">cited</span></div>
+		</div>
+		<div class="h-entry"><span class="p-name">no id</span></div>
+		<div class="hentry" id="legacy"><span class="entry-title">legacy</span></div>
+	</div>`
+
+	items := parseItems(doc)
+	if len(items) != 1 {
+		t.Fatalf("Parse returned %d items, want 1", len(items))
+	}
+	feed := items[0]
+	if got, want := feed.ID, "feed"; got != want {
+		t.Errorf("feed ID is %q, want %q", got, want)
+	}
+	if len(feed.Children) != 3 {
+		t.Fatalf("feed has %d children, want 3", len(feed.Children))
+	}
+
+	tests := []struct {
+		item *Microformat
+		want string
+	}{
+		{feed.Children[0], "first"},
+		{feed.Children[0].Properties["author"][0].(*Microformat), "author"},
+		{feed.Children[0].Children[0], "cite"},
+		{feed.Children[1], ""},
+		{feed.Children[2], ""}, // backcompat
+	}
+	for _, tt := range tests {
+		if got := tt.item.ID; got != tt.want {
+			t.Errorf("%v ID is %q, want %q", tt.item.Type, got, tt.want)
+		}
+	}
+}
+
+func Test_Parse_ProtocolRelativeURLs(t *testing.T) {
+	doc := `<div class="h-card">
+		<a class="u-url p-name" href="//example.org/jane">Jane</a>
+		<img class="u-photo" src="//cdn.example.com/a.jpg" alt="">
+	</div>`
+
+	tests := []struct {
+		base string
+		want map[string][]any
+	}{
+		{"http://example.com/", map[string][]any{
+			"name":  {"Jane"},
+			"url":   {"http://example.org/jane"},
+			"photo": {"http://cdn.example.com/a.jpg"},
+		}},
+		{"https://example.com/", map[string][]any{
+			"name":  {"Jane"},
+			"url":   {"https://example.org/jane"},
+			"photo": {"https://cdn.example.com/a.jpg"},
+		}},
+	}
+
+	for _, tt := range tests {
+		base, _ := url.Parse(tt.base)
+		items := Parse(strings.NewReader(doc), base).Items
+		if len(items) != 1 {
+			t.Fatalf("Parse returned %d items, want 1", len(items))
+		}
+		if diff := cmp.Diff(tt.want, items[0].Properties); diff != "" {
+			t.Errorf("Parse with base %q mismatch (-want +got):\n%s", tt.base, diff)
+		}
+	}
+}
+
+func Test_Parse_UppercaseSchemeURLs(t *testing.T) {
+	doc := `<div class="h-entry">
+		<a class="u-url" href="HTTP://Example.COM/Path?Q=A#Frag">permalink</a>
+		<span class="u-uid">HTTPS://Example.COM/UID</span>
+		<img class="u-photo" src="HTTP://Example.COM:80/Photo.JPG" alt="">
+		<div class="e-content"><a href="HTTPS://Example.COM/Content">link</a></div>
+	</div>
+	<a rel="me" href="HTTPS://Social.EXAMPLE:443/@Jane">me</a>`
+	base, _ := url.Parse("https://base.example/dir/")
+
+	// absolute URLs are not resolved against the base URL again, and keep
+	// the case of their host and path, although the scheme is lowercased
+	want := map[string][]any{
+		"url":   {"http://Example.COM/Path?Q=A#Frag"},
+		"uid":   {"https://Example.COM/UID"},
+		"photo": {"http://Example.COM:80/Photo.JPG"},
+		"content": {map[string]string{
+			"value": "link",
+			"html":  `<a href="https://Example.COM/Content">link</a>`,
+		}},
+	}
+	tests := []struct {
+		opts     []Option
+		wantRels map[string][]string
+	}{
+		{nil, map[string][]string{"me": {"https://Social.EXAMPLE:443/@Jane"}}},
+		// normalized rel URLs have their host lowercased, but not their path
+		{[]Option{WithNormalizedRelURLs(true)}, map[string][]string{"me": {"https://social.example/@Jane"}}},
+	}
+
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(doc), base, tt.opts...)
+		props := data.Items[0].Properties
+		delete(props, "name")
+		if diff := cmp.Diff(want, props); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+		if diff := cmp.Diff(tt.wantRels, data.Rels); diff != "" {
+			t.Errorf("Parse with %d options rels mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+	}
+}
+
+func Test_Parse_FragmentURLs(t *testing.T) {
+	doc := `<base href="/blog/">
+	<div class="h-feed">
+		<article class="h-entry" id="post-1"><a class="u-url p-name" href="#post-1">One</a></article>
+		<article class="h-entry" id="post-2"><a href="#post-2">Two</a></article>
+	</div>`
+
+	base, _ := url.Parse("http://example.com/page?view=all#top")
+	items := Parse(strings.NewReader(doc), base).Items
+	if len(items) != 1 || len(items[0].Children) != 2 {
+		t.Fatalf("Parse returned %d items, want 1 with 2 children", len(items))
+	}
+	for i, want := range []string{"http://example.com/blog/#post-1", "http://example.com/blog/#post-2"} {
+		entry := items[0].Children[i]
+		if got := entry.Properties["url"]; !cmp.Equal(got, []any{want}) {
+			t.Errorf("Parse returned url %v for %v, want %q", got, entry.ID, want)
+		}
+	}
+}
+
+func Test_Parse_PosterAndCite(t *testing.T) {
+	doc := `<div class="h-entry">
+		<video class="u-featured" poster="poster.jpg"></video>
+		<blockquote class="u-quotation-of" cite="/quoted">Quoted text</blockquote>
+		<q class="u-x-q" cite="q">quote</q>
+		<ins class="u-x-ins" cite="ins">added</ins>
+		<del class="u-x-del" cite="del">removed</del>
+		<blockquote class="u-x-text">/text</blockquote>
+		<div class="e-content"><video src="v.mp4" poster="p.jpg"></video><blockquote cite="/c">c</blockquote><q cite="q">q</q></div>
+	</div>`
+	base, _ := url.Parse("http://example.com/dir/")
+	data := Parse(strings.NewReader(doc), base)
+
+	want := map[string][]any{
+		"featured":     {"http://example.com/dir/poster.jpg"},
+		"quotation-of": {"http://example.com/quoted"},
+		"x-q":          {"http://example.com/dir/q"},
+		"x-ins":        {"http://example.com/dir/ins"},
+		"x-del":        {"http://example.com/dir/del"},
+		// without cite, the text is used
+		"x-text": {"http://example.com/text"},
+		"content": {map[string]string{
+			"value": "cq",
+			"html": `<video src="http://example.com/dir/v.mp4" poster="http://example.com/dir/p.jpg"></video>` +
+				`<blockquote cite="http://example.com/c">c</blockquote><q cite="http://example.com/dir/q">q</q>`,
+		}},
+	}
+	got := data.Items[0].Properties
+	delete(got, "name")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_Parse_HeadingAnchors(t *testing.T) {
+	// markup resembling rendered Markdown, with heading anchor links
+	doc := `<div class="h-entry"><h2 id="intro"><a class="anchor" aria-hidden="true" href="#intro"><svg class="octicon"></svg></a></h2></div>
+	<div class="h-entry"><h2><a class="anchor" href="#usage">#</a></h2></div>
+	<div class="h-card"><a class="headerlink" href="#jane">¶</a></div>`
+
+	for _, item := range parseItems(doc) {
+		if got := item.Properties["url"]; got == nil {
+			t.Errorf("Parse returned no implied url for %v, want one", item.Type)
+		}
+	}
+	for _, item := range parseItemsWith(doc, WithSkipAnchorLinks()) {
+		if got := item.Properties["url"]; got != nil {
+			t.Errorf("Parse with WithSkipAnchorLinks returned implied url %v for %v, want none", got, item.Type)
+		}
+	}
+
+	// the href of the microformat itself is always used
+	items := parseItemsWith(`<a class="h-card" href="#jane">¶</a>`, WithSkipAnchorLinks())
+	if got, want := firstString(items[0], "url"), "http://example.com/#jane"; got != want {
+		t.Errorf("Parse with WithSkipAnchorLinks returned url %q, want %q", got, want)
+	}
+}
+
+func Test_Parse_Tables(t *testing.T) {
+	tests := []struct {
+		html string
+		want []*Microformat
+	}{
+		{
+			// properties in header and body sections of a table root
+			`<table class="h-card">
+				<thead><tr><th class="p-name">Jane</th></tr></thead>
+				<tbody><tr><td class="p-org">Example</td></tr></tbody>
+				<tfoot><tr><td><a class="u-url" href="/jane">home</a></td></tr></tfoot>
+			</table>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"org":  {"Example"},
+					"url":  {"http://example.com/jane"},
+				},
+			}},
+		},
+		{
+			// implied tbody
+			`<table class="h-card"><tr><td class="p-name">Jane</td></tr></table>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+		{
+			// roots on rows, properties on cells
+			`<table><tbody>
+				<tr class="h-entry"><td class="p-name">one</td><td class="dt-published">2024-01-01</td></tr>
+				<tr class="h-entry"><td class="p-name">two</td><td class="dt-published">2024-01-02</td></tr>
+			</tbody></table>`,
+			[]*Microformat{
+				{
+					Type:       []string{"h-entry"},
+					Properties: map[string][]any{"name": {"one"}, "published": {"2024-01-01"}},
+				},
+				{
+					Type:       []string{"h-entry"},
+					Properties: map[string][]any{"name": {"two"}, "published": {"2024-01-02"}},
+				},
+			},
+		},
+		{
+			// v1 markup on rows and cells
+			`<table><tr class="vcard"><td class="fn">Jane</td><td class="org">Example</td></tr></table>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}, "org": {"Example"}},
+			}},
+		},
+		{
+			// classes on col and colgroup elements have no content
+			`<table class="h-card"><colgroup class="p-note"><col class="p-nickname"></colgroup>
+				<tr><td class="p-name">Jane</td></tr></table>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name":     {"Jane"},
+					"note":     {""},
+					"nickname": {""},
+				},
+			}},
+		},
+		{
+			// content that is not allowed in a table is moved before it
+			// by the HTML parser, and so is outside of a table root
+			`<table class="h-card"><span class="p-org">Example</span><tr><td class="p-name">Jane</td></tr></table>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, parseItems(tt.html), ignoreParseState); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_MultipleEProperties(t *testing.T) {
+	doc := `<div class="h-entry">
+		<p class="p-name">Title</p>
+		<div class="e-content"><p>First part</p></div>
+		<aside>not content</aside>
+		<div class="e-content">Second <b>part</b></div>
+		<div class="e-content"></div>
+	</div>`
+
+	want := []any{
+		map[string]string{"value": "First part", "html": "<p>First part</p>"},
+		map[string]string{"value": "Second part", "html": "Second <b>part</b>"},
+		map[string]string{"value": "", "html": ""},
+	}
+	items := parseItems(doc)
+	if diff := cmp.Diff(want, items[0].Properties["content"]); diff != "" {
+		t.Errorf("Parse(%q) content mismatch (-want +got):\n%s", doc, diff)
+	}
+}
+
+func Test_Parse_ClassWhitespace(t *testing.T) {
+	doc := "<div class=\"\n\t h-card  \"><span class=\"  p-name\t\tp-nickname \r\n\">Jane</span>" +
+		"<a class=\"\fu-url\" rel=\" me\tauthor \" href=\"/jane\">home</a></div>"
+
+	base, _ := url.Parse("http://example.com/")
+	want := &Data{
+		Items: []*Microformat{{
+			Type: []string{"h-card"},
+			Properties: map[string][]any{
+				"name":     {"Jane"},
+				"nickname": {"Jane"},
+				"url":      {"http://example.com/jane"},
+			},
+		}},
+		Rels: map[string][]string{
+			"me":     {"http://example.com/jane"},
+			"author": {"http://example.com/jane"},
+		},
+		RelURLs: map[string]*RelURL{
+			"http://example.com/jane": {Rels: []string{"author", "me"}, Text: "home"},
+		},
+		BaseURL: base,
+	}
+	if diff := cmp.Diff(want, Parse(strings.NewReader(doc), base), ignoreParseState); diff != "" {
+		t.Errorf("Parse(%q) mismatch (-want +got):\n%s", doc, diff)
+	}
+}
+
+func Test_Parse_NestedPropertyText(t *testing.T) {
+	// the text of nested microformats is reused for the text of their
+	// ancestors, which must give the same values as computing it afresh
+	doc := `<div class="h-entry"><div class="p-comment h-cite">One <img src="a.png">` +
+		`<div class="p-comment h-cite">Two <img src="b.png" alt="B"><script>x</script> ` +
+		`<div class="p-comment h-cite">Three</div></div> <span>after</span></div></div>`
+
+	items := parseItems(doc)
+	var values []string
+	for m := items[0]; len(m.Properties["comment"]) > 0; {
+		m = m.Properties["comment"][0].(*Microformat)
+		values = append(values, m.Value)
+	}
+	want := []string{
+		"One  http://example.com/a.png Two B Three after",
+		"Two B Three",
+		"Three",
+	}
+	if diff := cmp.Diff(want, values); diff != "" {
+		t.Errorf("Parse(%q) comment values mismatch (-want +got):\n%s", doc, diff)
+	}
+}
+
+func Test_ParseWithError(t *testing.T) {
+	doc := `<div class="h-entry"><span class="p-name">Title</span><div class="e-content">Hello <b>wor`
+	base, _ := url.Parse("http://example.com/")
+
+	tests := []struct {
+		description string
+		r           io.Reader
+		wantErr     error
+	}{
+		{"complete", strings.NewReader(doc), nil},
+		{"truncated", io.MultiReader(strings.NewReader(doc), iotest.ErrReader(io.ErrUnexpectedEOF)), io.ErrUnexpectedEOF},
+	}
+
+	want := []*Microformat{{
+		Type: []string{"h-entry"},
+		Properties: map[string][]any{
+			"name": {"Title"},
+			"content": {map[string]string{
+				"value": "Hello wor",
+				"html":  "Hello <b>wor</b>",
+			}},
+		},
+	}}
+	for _, tt := range tests {
+		data, err := ParseWithError(tt.r, base)
+		if err != tt.wantErr {
+			t.Errorf("ParseWithError(%s) returned error %v, want %v", tt.description, err, tt.wantErr)
+		}
+		if data == nil {
+			t.Fatalf("ParseWithError(%s) returned nil Data", tt.description)
+		}
+		if diff := cmp.Diff(want, data.Items, ignoreParseState); diff != "" {
+			t.Errorf("ParseWithError(%s) mismatch (-want +got):\n%s", tt.description, diff)
+		}
+	}
+
+	// Parse ignores the error, but still returns partial results
+	r := io.MultiReader(strings.NewReader(doc), iotest.ErrReader(io.ErrUnexpectedEOF))
+	if data := Parse(r, base); data == nil || len(data.Items) != 1 {
+		t.Errorf("Parse of truncated document returned %v, want 1 item", data)
+	}
+}
+
+func Test_ParseWithWarnings_PContent(t *testing.T) {
+	tests := []struct {
+		html    string
+		content []any
+		want    []Warning
+	}{
+		{
+			`<div class="h-entry"><div class="p-content"><p>Hello <a href="/world">world</a></p></div></div>`,
+			[]any{"Hello world"},
+			[]Warning{{Message: "p-content on <div> contains markup, which is not included in its value; e-content may have been intended"}},
+		},
+		{
+			`<div class="h-entry"><p class="p-content">Hello world</p></div>`,
+			[]any{"Hello world"},
+			nil,
+		},
+		{
+			`<div class="h-entry"><div class="e-content"><p>Hello world</p></div></div>`,
+			[]any{map[string]string{"value": "Hello world", "html": "<p>Hello world</p>"}},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		data, warnings := ParseWithWarnings(strings.NewReader(tt.html), nil)
+		if diff := cmp.Diff(tt.want, warnings); diff != "" {
+			t.Errorf("ParseWithWarnings(%q) warnings mismatch (-want +got):\n%s", tt.html, diff)
+		}
+		if got := data.Items[0].Properties["content"]; !cmp.Equal(got, tt.content) {
+			t.Errorf("ParseWithWarnings(%q) returned content %v, want %v", tt.html, got, tt.content)
+		}
+	}
+}
+
+func Test_FirstOfType(t *testing.T) {
+	doc := `<a rel="me" href="/me">me</a>
+	<div class="h-entry">
+		<span class="p-name">Entry</span>
+		<div class="p-author h-card"><span class="p-name">Author</span></div>
+	</div>
+	<div class="h-card">
+		<span class="p-name">Jane</span>
+		<div class="h-card"><span class="p-name">Child</span></div>
+	</div>
+	<div class="vcard"><span class="fn">Legacy</span></div>`
+	base, _ := url.Parse("http://example.com/")
+
+	tests := []struct {
+		t    string
+		name string
+	}{
+		{"h-card", "Author"},
+		{"h-entry", "Entry"},
+		{"h-event", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := FirstOfType(strings.NewReader(doc), base, tt.t)
+		if err != nil {
+			t.Errorf("FirstOfType(%q) returned error: %v", tt.t, err)
+		}
+		if tt.name == "" {
+			if got != nil {
+				t.Errorf("FirstOfType(%q) returned %v, want nil", tt.t, got)
+			}
+			continue
+		}
+		if got == nil {
+			t.Errorf("FirstOfType(%q) returned nil, want %q", tt.t, tt.name)
+			continue
+		}
+		if name := got.Properties["name"]; !cmp.Equal(name, []any{tt.name}) {
+			t.Errorf("FirstOfType(%q) returned name %v, want %q", tt.t, name, tt.name)
+		}
+	}
+
+	// children of the found microformat are included
+	got, _ := FirstOfType(strings.NewReader(`<div class="h-feed"><div class="h-entry">a</div><div class="h-entry">b</div></div>`), base, "h-feed")
+	if got == nil || len(got.Children) != 2 {
+		t.Errorf("FirstOfType(h-feed) returned %v, want 2 children", got)
+	}
+
+	// backcompat roots are matched by their v2 type
+	got, _ = FirstOfType(strings.NewReader(`<div class="vcard"><span class="fn">Legacy</span></div>`), base, "h-card")
+	if got == nil || !cmp.Equal(got.Properties["name"], []any{"Legacy"}) {
+		t.Errorf("FirstOfType(h-card) on vcard returned %v, want Legacy", got)
+	}
+}
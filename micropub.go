@@ -0,0 +1,59 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for collecting Micropub commands.
+
+package microformats
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// micropubClassNames matches the class names of Micropub commands.
+var micropubClassNames = regexp.MustCompile(`^mp-[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// WithMicropubProperties collects Micropub commands, such as mp-slug and
+// mp-syndicate-to, from elements with mp-* class names into the Commands
+// field of the enclosing microformat, as might be found in markup generated
+// from a Micropub request.  Commands are instructions to a Micropub server
+// rather than properties, and are not part of the microformats2 parsing
+// specification, so by default mp-* classes are ignored like any other
+// class.
+//
+// Command values are taken from the value attribute of <data>, <input>, and
+// <option> elements, the title attribute of <abbr> elements, and otherwise
+// the text of the element, with surrounding whitespace removed.  URLs are
+// not resolved, since commands such as mp-syndicate-to identify targets
+// rather than link to them.
+func WithMicropubProperties() Option {
+	return func(p *parser) {
+		p.micropub = true
+	}
+}
+
+// addCommands adds the Micropub commands named by the classes of node to the
+// current microformat, as described in WithMicropubProperties.
+func (p *parser) addCommands(node *html.Node, classes []string) {
+	for _, class := range classes {
+		if !micropubClassNames.MatchString(class) {
+			continue
+		}
+		var value string
+		switch {
+		case isAtom(node, atom.Data, atom.Input, atom.Option) && hasAttr(node, "value"):
+			value = getAttr(node, "value")
+		case isAtom(node, atom.Abbr) && hasAttr(node, "title"):
+			value = getAttr(node, "title")
+		default:
+			value = getTextContent(node, imageAltValue)
+		}
+		if p.curItem.Commands == nil {
+			p.curItem.Commands = make(map[string][]string)
+		}
+		p.curItem.Commands[class] = append(p.curItem.Commands[class], strings.TrimSpace(value))
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func Test_WithMicropubProperties(t *testing.T) {
+	doc := `<div class="h-entry">
+		<span class="p-name">Hello</span>
+		<span class="mp-slug"> hello-world </span>
+		<ul><li class="mp-syndicate-to">https://social.example/</li>
+		<li><data class="mp-syndicate-to" value="https://other.example/">Other</data></li></ul>
+		<div class="p-author h-card"><span class="p-name">Jane</span><input class="mp-destination" value="blog"></div>
+	</div>`
+
+	want := []*Microformat{{
+		Type: []string{"h-entry"},
+		Properties: map[string][]any{
+			"name": {"Hello"},
+			"author": {&Microformat{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+				Value:      "Jane",
+			}},
+		},
+	}}
+	items := parseItemsWith(doc)
+	if diff := cmp.Diff(want, items, ignoreParseState); diff != "" {
+		t.Errorf("Parse without WithMicropubProperties mismatch (-want +got):\n%s", diff)
+	}
+
+	items = parseItemsWith(doc, WithMicropubProperties())
+	if diff := cmp.Diff(want, items, ignoreParseState, cmpopts.IgnoreFields(Microformat{}, "Commands")); diff != "" {
+		t.Errorf("Parse with WithMicropubProperties mismatch (-want +got):\n%s", diff)
+	}
+	wantCommands := map[string][]string{
+		"mp-slug":         {"hello-world"},
+		"mp-syndicate-to": {"https://social.example/", "https://other.example/"},
+	}
+	if diff := cmp.Diff(wantCommands, items[0].Commands); diff != "" {
+		t.Errorf("Parse with WithMicropubProperties commands mismatch (-want +got):\n%s", diff)
+	}
+	author := items[0].Properties["author"][0].(*Microformat)
+	if diff := cmp.Diff(map[string][]string{"mp-destination": {"blog"}}, author.Commands); diff != "" {
+		t.Errorf("Parse with WithMicropubProperties author commands mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for parsing several documents from one stream.
+
+package microformats
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+)
+
+// ParseMulti parses the microformats found in each of the HTML documents read
+// from r, which are separated by sep, such as a form feed ("\f").  sep is
+// matched exactly, wherever it appears in the stream, including inside a
+// document; it should be a byte sequence that cannot appear in the documents
+// themselves.  If sep is empty, r is parsed as a single document.
+//
+// Each document is parsed independently, the same as Parse, with the same
+// baseURL and opts: a <base> element in one document does not affect the
+// others.  Documents that are empty or contain only whitespace, such as after
+// a trailing separator, are skipped.  Data is returned in stream order.
+//
+// The returned error is any error encountered while reading r.  As for
+// ParseWithError, the content read up to that point is still parsed.
+func ParseMulti(r io.Reader, sep []byte, baseURL *url.URL, opts ...Option) ([]*Data, error) {
+	b, err := io.ReadAll(r)
+
+	chunks := [][]byte{b}
+	if len(sep) > 0 {
+		chunks = bytes.Split(b, sep)
+	}
+	var data []*Data
+	for _, chunk := range chunks {
+		if len(bytes.TrimSpace(chunk)) == 0 {
+			continue
+		}
+		data = append(data, Parse(bytes.NewReader(chunk), baseURL, opts...))
+	}
+	return data, err
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ParseMulti(t *testing.T) {
+	stream := `<base href="/one/"><div class="h-card"><a class="p-name u-url" href="jane">Jane</a></div>` + "\f" +
+		`<div class="h-entry"><a class="u-url" href="post">Post</a></div><a rel="me" href="me">me</a>` + "\f\n"
+	base, _ := url.Parse("http://example.com/")
+
+	data, err := ParseMulti(strings.NewReader(stream), []byte("\f"), base)
+	if err != nil {
+		t.Fatalf("ParseMulti returned error: %v", err)
+	}
+	if len(data) != 2 {
+		t.Fatalf("ParseMulti returned %d documents, want 2", len(data))
+	}
+	tests := []struct {
+		typ, url string
+		rels     map[string][]string
+	}{
+		{"h-card", "http://example.com/one/jane", map[string][]string{}},
+		{"h-entry", "http://example.com/post", map[string][]string{"me": {"http://example.com/me"}}},
+	}
+	for i, tt := range tests {
+		items := data[i].Items
+		if len(items) != 1 || !items[0].hasType(tt.typ) {
+			t.Errorf("ParseMulti document %d returned items %v, want one %s", i, items, tt.typ)
+			continue
+		}
+		if got := firstString(items[0], "url"); got != tt.url {
+			t.Errorf("ParseMulti document %d returned url %q, want %q", i, got, tt.url)
+		}
+		if diff := cmp.Diff(tt.rels, data[i].Rels); diff != "" {
+			t.Errorf("ParseMulti document %d rels mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+
+	data, _ = ParseMulti(strings.NewReader(stream), nil, base)
+	if len(data) != 1 || len(data[0].Items) != 2 {
+		t.Errorf("ParseMulti with empty separator returned %v, want one document with 2 items", data)
+	}
+}
+
+func Test_ParseMulti_Error(t *testing.T) {
+	r := io.MultiReader(strings.NewReader(`<div class="h-card">Jane</div>--<div class="h-ca`),
+		iotest.ErrReader(io.ErrUnexpectedEOF))
+
+	data, err := ParseMulti(r, []byte("--"), nil)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("ParseMulti returned error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if len(data) != 2 {
+		t.Errorf("ParseMulti returned %d documents, want 2", len(data))
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for normalizing parsed microformats.
+
+package microformats
+
+// NormalizeOptions configures the transformations made by Data.Normalize.
+// Each is off unless set, and they are applied in the order of the fields.
+type NormalizeOptions struct {
+	// PromoteValues gives each nested microformat that is a property value
+	// the property its value was taken from, if it doesn't have one:  a url
+	// property if the value is an absolute URL, as it is for u-* values,
+	// and otherwise a name property.  This lets consumers read the name or
+	// URL of, say, an author h-card from its properties alone.
+	PromoteValues bool
+
+	// DropEmpty removes top-level items and children that have no
+	// properties and no children, such as those created by a stray root
+	// class on an empty element.
+	DropEmpty bool
+
+	// InlineSingleChildren replaces each top-level item or child that has
+	// no properties and exactly one child, such as an h-feed wrapped around
+	// a single h-entry, with that child.
+	InlineSingleChildren bool
+}
+
+// Normalize returns a copy of d with the transformations configured by opts
+// applied to its microformats, at any depth.  d itself is not modified.  The
+// result is no longer exactly what the microformats2 parsing specification
+// describes, so it should not be used where canonical output is expected.
+func (d *Data) Normalize(opts NormalizeOptions) *Data {
+	if d == nil {
+		return nil
+	}
+	nd := *d
+	nd.Items = normalizeItems(d.Items, opts)
+	if nd.Items == nil {
+		nd.Items = make([]*Microformat, 0)
+	}
+	return &nd
+}
+
+// normalizeItems returns normalized copies of items, which are top-level
+// items or children, as described in Normalize.
+func normalizeItems(items []*Microformat, opts NormalizeOptions) []*Microformat {
+	var normalized []*Microformat
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		m := normalizeMicroformat(item, opts)
+		if opts.DropEmpty && len(m.Properties) == 0 && len(m.Children) == 0 {
+			continue
+		}
+		if opts.InlineSingleChildren && len(m.Properties) == 0 && len(m.Children) == 1 {
+			m = m.Children[0]
+		}
+		normalized = append(normalized, m)
+	}
+	return normalized
+}
+
+// normalizeMicroformat returns a normalized copy of m, as described in
+// Normalize.
+func normalizeMicroformat(m *Microformat, opts NormalizeOptions) *Microformat {
+	nm := *m
+	nm.Properties = make(map[string][]any, len(m.Properties))
+	for name, values := range m.Properties {
+		nv := make([]any, len(values))
+		for i, v := range values {
+			if v, ok := v.(*Microformat); ok && v != nil {
+				nested := normalizeMicroformat(v, opts)
+				if opts.PromoteValues {
+					promoteValue(nested)
+				}
+				nv[i] = nested
+				continue
+			}
+			nv[i] = v
+		}
+		nm.Properties[name] = nv
+	}
+	nm.Children = normalizeItems(m.Children, opts)
+	return &nm
+}
+
+// promoteValue adds the value of m, a nested microformat, to its properties,
+// as described in NormalizeOptions.PromoteValues.
+func promoteValue(m *Microformat) {
+	if m.Value == "" {
+		return
+	}
+	prop := "name"
+	if isAbsoluteURL(m.Value) {
+		prop = "url"
+	}
+	if len(m.Properties[prop]) == 0 {
+		m.Properties[prop] = []any{m.Value}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Normalize(t *testing.T) {
+	tests := []struct {
+		html string
+		opts NormalizeOptions
+		want []*Microformat
+	}{
+		// no options
+		{
+			`<div class="h-entry"><span class="p-author h-card">Jane</span><div class="h-feed"></div></div>`,
+			NormalizeOptions{},
+			[]*Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"author": {&Microformat{
+						Type:       []string{"h-card"},
+						Properties: map[string][]any{"name": {"Jane"}},
+						Value:      "Jane",
+					}},
+				},
+				Children: []*Microformat{{
+					Type:       []string{"h-feed"},
+					Properties: map[string][]any{},
+				}},
+			}},
+		},
+
+		// PromoteValues
+		{
+			`<div class="h-entry">
+				<div class="p-author h-card"><span class="p-org">Acme</span><span class="p-name">Jane</span></div>
+				<data class="u-in-reply-to h-cite" value="https://other.example/"><span class="p-name">Other</span></data>
+			</div>`,
+			NormalizeOptions{PromoteValues: true},
+			[]*Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"author": {&Microformat{
+						Type:       []string{"h-card"},
+						Properties: map[string][]any{"name": {"Jane"}, "org": {"Acme"}},
+						Value:      "Jane",
+					}},
+					"in-reply-to": {&Microformat{
+						Type:       []string{"h-cite"},
+						Properties: map[string][]any{"name": {"Other"}, "url": {"https://other.example/"}},
+						Value:      "https://other.example/",
+					}},
+				},
+			}},
+		},
+		{
+			`<div class="h-entry"><div class="p-author h-card"><p class="p-note">Hi</p></div></div>`,
+			NormalizeOptions{PromoteValues: true},
+			[]*Microformat{{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"author": {&Microformat{
+						Type:       []string{"h-card"},
+						Properties: map[string][]any{"name": {"Hi"}, "note": {"Hi"}},
+						Value:      "Hi",
+					}},
+				},
+			}},
+		},
+
+		// DropEmpty
+		{
+			`<div class="h-feed"><div class="h-entry"></div><div class="h-entry"><p class="p-name">Post</p></div></div>
+			<div class="h-card"></div>`,
+			NormalizeOptions{DropEmpty: true},
+			[]*Microformat{{
+				Type:       []string{"h-feed"},
+				Properties: map[string][]any{},
+				Children: []*Microformat{{
+					Type:       []string{"h-entry"},
+					Properties: map[string][]any{"name": {"Post"}},
+				}},
+			}},
+		},
+
+		// InlineSingleChildren
+		{
+			`<div class="h-feed"><div class="h-entry"><p class="p-name">Post</p></div></div>`,
+			NormalizeOptions{InlineSingleChildren: true},
+			[]*Microformat{{
+				Type:       []string{"h-entry"},
+				Properties: map[string][]any{"name": {"Post"}},
+			}},
+		},
+		{
+			// feeds with properties or several children are kept
+			`<div class="h-feed"><p class="p-name">Feed</p><div class="h-entry"><p class="p-name">Post</p></div></div>
+			<div class="h-feed"><div class="h-entry"><p class="p-name">A</p></div><div class="h-entry"><p class="p-name">B</p></div></div>`,
+			NormalizeOptions{InlineSingleChildren: true},
+			[]*Microformat{
+				{
+					Type:       []string{"h-feed"},
+					Properties: map[string][]any{"name": {"Feed"}},
+					Children: []*Microformat{{
+						Type:       []string{"h-entry"},
+						Properties: map[string][]any{"name": {"Post"}},
+					}},
+				},
+				{
+					Type:       []string{"h-feed"},
+					Properties: map[string][]any{},
+					Children: []*Microformat{
+						{Type: []string{"h-entry"}, Properties: map[string][]any{"name": {"A"}}},
+						{Type: []string{"h-entry"}, Properties: map[string][]any{"name": {"B"}}},
+					},
+				},
+			},
+		},
+		{
+			// empty children are dropped before single children are inlined
+			`<div class="h-feed"><div class="h-entry"></div><div class="h-entry"><p class="p-name">Post</p></div></div>`,
+			NormalizeOptions{DropEmpty: true, InlineSingleChildren: true},
+			[]*Microformat{{
+				Type:       []string{"h-entry"},
+				Properties: map[string][]any{"name": {"Post"}},
+			}},
+		},
+	}
+
+	base, _ := url.Parse("http://example.com/")
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(tt.html), base)
+		got := data.Normalize(tt.opts)
+		if diff := cmp.Diff(tt.want, got.Items, ignoreParseState); diff != "" {
+			t.Errorf("Normalize(%+v) of %q mismatch (-want +got):\n%s", tt.opts, tt.html, diff)
+		}
+	}
+}
+
+func Test_Normalize_Copy(t *testing.T) {
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(`<div class="h-feed">
+		<div class="h-entry"><div class="p-author h-card"><p class="p-note">Hi</p></div></div>
+		<div class="h-entry"></div>
+	</div>`), base)
+	want := Parse(strings.NewReader(`<div class="h-feed">
+		<div class="h-entry"><div class="p-author h-card"><p class="p-note">Hi</p></div></div>
+		<div class="h-entry"></div>
+	</div>`), base)
+
+	data.Normalize(NormalizeOptions{PromoteValues: true, DropEmpty: true, InlineSingleChildren: true})
+	if diff := cmp.Diff(want, data, ignoreParseState); diff != "" {
+		t.Errorf("Normalize modified its receiver (-want +got):\n%s", diff)
+	}
+
+	if got := (*Data)(nil).Normalize(NormalizeOptions{}); got != nil {
+		t.Errorf("Normalize on nil Data returned %v, want nil", got)
+	}
+}
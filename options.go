@@ -0,0 +1,593 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes options for customizing parsing behavior.
+
+package microformats
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/text/unicode/norm"
+)
+
+// An Option configures optional parsing behavior.  Unless otherwise noted,
+// options enable behavior not defined by the microformats2 parsing
+// specification, and are off by default.
+type Option func(*parser)
+
+// PropertyFunc returns the value of a property with a custom prefix found on
+// node.  base is the base URL of the document, which can be used to resolve
+// relative URLs.  If PropertyFunc returns nil, no value is added for the
+// property.
+type PropertyFunc func(node *html.Node, base *url.URL) any
+
+// customPropertyClassNames matches property classes with any prefix.
+var customPropertyClassNames = regexp.MustCompile(`^([a-z]+)-(([a-z0-9]+-)?[a-z]+(-[a-z]+)*)$`)
+
+// builtinPrefixes are the class name prefixes defined by microformats2, which
+// cannot be overridden by WithPropertyPrefix.
+var builtinPrefixes = map[string]bool{"h": true, "p": true, "u": true, "dt": true, "e": true}
+
+// WithPropertyPrefix registers fn to parse property classes with the specified
+// prefix, which should not include the trailing hyphen.  This allows
+// experimenting with new property types, such as a "j-" prefix for JSON
+// values.  Properties with a custom prefix are parsed within microformats2
+// roots only, and do not affect implied property values.
+//
+// The built-in prefixes "h", "p", "u", "dt", and "e" cannot be overridden;
+// registering one of them has no effect.
+func WithPropertyPrefix(prefix string, fn PropertyFunc) Option {
+	return func(p *parser) {
+		if builtinPrefixes[prefix] || fn == nil {
+			return
+		}
+		if p.prefixes == nil {
+			p.prefixes = make(map[string]PropertyFunc)
+		}
+		p.prefixes[prefix] = fn
+	}
+}
+
+// WithLowercaseEmails normalizes the values of "email" properties by
+// lowercasing the "mailto:" scheme and the domain of the address, so that
+// "mailto:Jane@Example.COM" becomes "mailto:Jane@example.com".  The local part
+// of the address (before the "@") may be case sensitive, and is left as-is, as
+// are any query parameters.  No other properties are modified.
+func WithLowercaseEmails() Option {
+	return func(p *parser) {
+		p.lowercaseEmails = true
+	}
+}
+
+// lowercaseEmail normalizes the email address s, as described in
+// WithLowercaseEmails.  s may optionally include a "mailto:" scheme.
+func lowercaseEmail(s string) string {
+	var scheme string
+	if len(s) >= len("mailto:") && strings.EqualFold(s[:len("mailto:")], "mailto:") {
+		scheme, s = "mailto:", s[len("mailto:"):]
+	}
+	addr, query, hasQuery := strings.Cut(s, "?")
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		addr = addr[:i] + strings.ToLower(addr[i:])
+	}
+	if hasQuery {
+		addr += "?" + query
+	}
+	return scheme + addr
+}
+
+// WithMaxValueLength limits the length of property values to n bytes, to
+// defend against abusively large documents.  Values of p-* properties, and
+// the plain text, html, and markdown values of e-* properties, are truncated
+// to at most n bytes on a UTF-8 character boundary.  Truncated html may no
+// longer be well-formed.  Values of u-* properties longer than n bytes are
+// dropped, since a truncated URL is worse than none.  A warning is recorded
+// for each truncated or dropped value.  If n is zero or negative, values are
+// not limited, which is the default.
+func WithMaxValueLength(n int) Option {
+	return func(p *parser) {
+		p.maxValueLength = n
+	}
+}
+
+// limitValue applies the limit set by WithMaxValueLength to value, which is
+// the value of the property with the specified prefix and name on node, and
+// to the html and markdown in propData.  It returns the limited value, which
+// is nil if value was dropped.
+func (p *parser) limitValue(node *html.Node, prefix, name string, value *string, propData map[string]string) *string {
+	n := p.maxValueLength
+	switch prefix {
+	case "p", "e":
+		if value != nil && len(*value) > n {
+			p.warn(node, "truncated %s-%s value of %d bytes to %d bytes", prefix, name, len(*value), n)
+			*value = truncate(*value, n)
+		}
+		for _, key := range []string{"html", "markdown"} {
+			if s, ok := propData[key]; ok && len(s) > n {
+				p.warn(node, "truncated %s-%s %s of %d bytes to %d bytes", prefix, name, key, len(s), n)
+				propData[key] = truncate(s, n)
+			}
+		}
+	case "u":
+		if value != nil && len(*value) > n {
+			p.warn(node, "dropped %s-%s value of %d bytes, longer than %d bytes", prefix, name, len(*value), n)
+			return nil
+		}
+	}
+	return value
+}
+
+// truncate returns the longest prefix of s that is at most n bytes long and
+// does not split a UTF-8 encoded character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// WithRawURLs records the values of u-* properties as written in the
+// document in the RawURLs field of each microformat, alongside the resolved
+// absolute URLs in its properties.  This helps diagnose problems with base
+// URLs.
+func WithRawURLs() Option {
+	return func(p *parser) {
+		p.rawURLs = true
+	}
+}
+
+// addRawURL records raw, if not nil, as the raw value of the property name of
+// the current microformat.
+func (p *parser) addRawURL(name string, raw *string) {
+	if raw == nil {
+		return
+	}
+	if p.curItem.RawURLs == nil {
+		p.curItem.RawURLs = make(map[string][]string)
+	}
+	p.curItem.RawURLs[name] = append(p.curItem.RawURLs[name], *raw)
+}
+
+// WithStripParams removes the named query parameters, such as "fbclid", from
+// u-* property values (including implied url and photo values) and rel URLs,
+// after resolving them against the base URL, so that links that differ only
+// in tracking parameters can be compared.  A name ending in "*" matches any
+// parameter beginning with the rest of the name, such as "utm_*".  Names are
+// case sensitive.  Other parameters are kept in their original order and
+// encoding, and the query is removed entirely if no parameters remain.
+// u-uid and u-key values are identifiers rather than links, and are not
+// changed.
+func WithStripParams(params ...string) Option {
+	return func(p *parser) {
+		p.stripParams = params
+	}
+}
+
+// stripURLParams returns s with the query parameters set by WithStripParams
+// removed.  If s cannot be parsed as a URL, it is returned unchanged.
+func (p *parser) stripURLParams(s string) string {
+	if len(p.stripParams) == 0 || !strings.Contains(s, "?") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.RawQuery == "" {
+		return s
+	}
+	var kept []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		key, _, _ := strings.Cut(param, "=")
+		if k, err := url.QueryUnescape(key); err == nil {
+			key = k
+		}
+		if !matchParam(key, p.stripParams) {
+			kept = append(kept, param)
+		}
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return u.String()
+}
+
+// matchParam returns whether the query parameter key matches any of names,
+// as described in WithStripParams.
+func matchParam(key string, names []string) bool {
+	for _, name := range names {
+		if prefix := strings.TrimSuffix(name, "*"); prefix != name {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAllowedSchemes drops u-* property values (including implied url and
+// photo values) whose URL scheme is not one of schemes, such as javascript:
+// and data: URLs, so that extracted links are safer to render.  A warning is
+// recorded for each dropped value.  Schemes are compared case insensitively,
+// after removing the tabs, newlines, and surrounding control characters that
+// browsers ignore, so "java&#9;script:" is read as javascript:.  Values
+// without a scheme, which are relative URLs that could not be resolved, are
+// allowed, unless they could not be parsed as URLs and have a colon before
+// any path, query, or fragment.  If no schemes are given, http, https,
+// mailto, and tel are allowed.  u-uid and u-key values are identifiers rather
+// than links, and are not checked.  By default, values with any scheme are
+// kept.
+func WithAllowedSchemes(schemes ...string) Option {
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https", "mailto", "tel"}
+	}
+	return func(p *parser) {
+		p.allowedSchemes = make(map[string]bool, len(schemes))
+		for _, scheme := range schemes {
+			p.allowedSchemes[strings.ToLower(scheme)] = true
+		}
+	}
+}
+
+// allowURL returns whether the scheme of u is allowed by WithAllowedSchemes,
+// recording a warning about the value of the property name on node if not.
+func (p *parser) allowURL(node *html.Node, name, u string) bool {
+	if p.allowedSchemes == nil {
+		return true
+	}
+	cleaned := cleanURL(u)
+	scheme := uriScheme(cleaned)
+	if scheme == "" {
+		// a value that could not be parsed as a URL is left unresolved, so
+		// only allow it if nothing in it could be read as a scheme
+		if _, err := url.Parse(u); err != nil && looksLikeScheme(cleaned) {
+			p.warn(node, "dropped u-%s value with an invalid scheme", name)
+			return false
+		}
+		return true
+	}
+	if p.allowedSchemes[strings.ToLower(scheme)] {
+		return true
+	}
+	p.warn(node, "dropped u-%s value with %s: scheme, which is not allowed", name, strings.ToLower(scheme))
+	return false
+}
+
+// cleanURL returns u as browsers read it before finding its scheme, following
+// the WHATWG URL standard: with leading and trailing C0 control characters
+// and spaces trimmed, and all ASCII tabs and newlines removed.
+func cleanURL(u string) string {
+	u = strings.TrimFunc(u, func(r rune) bool { return r <= ' ' })
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, u)
+}
+
+// looksLikeScheme returns whether u has a colon before any "/", "?", or "#",
+// which would make a browser read the part before it as a URL scheme if it
+// were valid.
+func looksLikeScheme(u string) bool {
+	i := strings.IndexAny(u, ":/?#")
+	return i > 0 && u[i] == ':'
+}
+
+// WithSkipAnchorLinks ignores links that only decorate the page when implying
+// the url of a microformat from a nested <a> or <area> element, such as the
+// heading anchors generated when rendering Markdown:
+//
+//	<div class="h-entry"><h2><a class="anchor" href="#usage">#</a></h2></div>
+//
+// These are links to fragments of the page that are hidden from assistive
+// technology, or <a> elements with no text or images other than symbols such
+// as "#" or "¶".  The href of an <a> or <area> element that is itself the
+// microformat is always used, as in the parsing specification.
+func WithSkipAnchorLinks() Option {
+	return func(p *parser) {
+		p.skipAnchorLinks = true
+	}
+}
+
+// WithSrcset includes the responsive image candidates of u-* properties on
+// <img> and <picture> elements in their value.  Candidates are taken from the
+// srcset attributes of each <source> element of a <picture> and then of the
+// <img> element, with URLs resolved to absolute URLs.  They are included in
+// a "srcset" member of the property value, in the same format as the srcset
+// attribute, alongside "value" (and "alt", if present):
+//
+//	map[string]string{
+//		"value":  "http://example.com/a.jpg",
+//		"srcset": "http://example.com/a.webp 1x, http://example.com/a-2x.jpg 2x",
+//	}
+//
+// Media conditions and types of <source> elements are not included.
+func WithSrcset() Option {
+	return func(p *parser) {
+		p.srcset = true
+	}
+}
+
+// srcsetValue returns the srcset candidates of node, an <img> or <picture>
+// element, as described in WithSrcset.
+func (p *parser) srcsetValue(node *html.Node) string {
+	var attrs []string
+	if node.DataAtom == atom.Img {
+		attrs = append(attrs, getAttr(node, "srcset"))
+	} else {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.DataAtom == atom.Source {
+				attrs = append(attrs, getAttr(c, "srcset"))
+			}
+		}
+		if img := getOnlyChildAtom(node, atom.Img); img != nil {
+			attrs = append(attrs, getAttr(img, "srcset"))
+		}
+	}
+
+	var candidates []string
+	for _, attr := range attrs {
+		for _, candidate := range strings.Split(attr, ",") {
+			fields := splitTokens(candidate)
+			if len(fields) == 0 {
+				continue
+			}
+			fields[0] = expandURL(fields[0], p.base)
+			candidates = append(candidates, strings.Join(fields, " "))
+		}
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// WithUnicodeNormalization normalizes the text of p-* properties, the plain
+// text value of e-* properties, and implied names to the Unicode
+// normalization form f, typically norm.NFC.  This allows reliable comparison
+// of values that may be composed differently in the source, such as "é"
+// written as a single character or as "e" and a combining accent.  The html
+// value of e-* properties and the values of u-* and dt-* properties are not
+// modified.  By default, no normalization is performed, preserving the exact
+// source text.
+func WithUnicodeNormalization(f norm.Form) Option {
+	return func(p *parser) {
+		p.normForm = &f
+	}
+}
+
+// bareProperties are the property names recognized without a prefix by
+// WithLegacyBareProperties.  They are the text properties of h-card, h-entry,
+// h-event, h-adr, and h-cite.
+var bareProperties = map[string]bool{
+	"name": true, "honorific-prefix": true, "given-name": true,
+	"additional-name": true, "family-name": true, "honorific-suffix": true,
+	"nickname": true, "org": true, "job-title": true, "role": true,
+	"note": true, "summary": true, "author": true, "category": true,
+	"location": true, "street-address": true, "extended-address": true,
+	"locality": true, "region": true, "postal-code": true,
+	"country-name": true, "tel": true, "publication": true,
+}
+
+// WithLegacyBareProperties recovers properties from class names written
+// without a prefix, such as class="name" inside an h-card, which authors
+// sometimes use intending p-name.  Within a microformats2 root, a class that
+// is one of a fixed set of known text property names (name, nickname, org,
+// note, summary, locality, and similar) is parsed as if it had the "p-"
+// prefix.  Class names without a prefix are commonly used for styling, so
+// this can add properties the author never intended, and explicit p-*
+// properties with the same name on the same element take precedence as
+// usual.  This is a heuristic for extracting data from sloppy markup, and
+// should not be used when exact parsing results are required.  Microformats
+// v1 roots are not affected.
+func WithLegacyBareProperties() Option {
+	return func(p *parser) {
+		p.bareProperties = true
+	}
+}
+
+// barePropertyClasses returns the bare property names in classes as p-*
+// property classes, as described in WithLegacyBareProperties.  Classes whose
+// p-* form is already in props are skipped.
+func barePropertyClasses(classes, props []string) []string {
+	var bare []string
+	for _, class := range classes {
+		if !bareProperties[class] {
+			continue
+		}
+		prop := "p-" + class
+		if !containsString(props, prop) && !containsString(bare, prop) {
+			bare = append(bare, prop)
+		}
+	}
+	return bare
+}
+
+// WithCaseInsensitivePrefixes recovers microformats2 class names whose
+// prefix was written in uppercase, such as class="P-name" or class="H-card",
+// by matching the "h-", "p-", "u-", "dt-", and "e-" prefixes case
+// insensitively.  HTML class names are case sensitive, so these are not valid
+// microformats2 classes, and by default they are ignored.  Only the prefix is
+// recovered: the rest of the class name must still be lowercase, so
+// class="P-name" is parsed as p-name, but class="U-URL" is ignored, since
+// "URL" is not a valid property name.  Classes meant only for styling can
+// also be recovered this way, so a page using class="H-card" for a styled
+// box that is not a contact card gains an h-card root it never intended.
+func WithCaseInsensitivePrefixes() Option {
+	return func(p *parser) {
+		p.caseInsensitivePrefixes = true
+	}
+}
+
+// lowercasePrefixes returns a copy of classes with the prefix of each
+// microformats2 class name lowercased, as described in
+// WithCaseInsensitivePrefixes.
+func lowercasePrefixes(classes []string) []string {
+	lowered := make([]string, len(classes))
+	for i, class := range classes {
+		lowered[i] = lowercasePrefix(class)
+	}
+	return lowered
+}
+
+// lowercasePrefix returns class with its prefix lowercased, if it has a
+// microformats2 prefix in any case.
+func lowercasePrefix(class string) string {
+	prefix, name, ok := strings.Cut(class, "-")
+	if !ok {
+		return class
+	}
+	switch lower := strings.ToLower(prefix); lower {
+	case "h", "p", "u", "dt", "e":
+		return lower + "-" + name
+	}
+	return class
+}
+
+// WithHTMLParseOptions parses documents using the net/html parse options
+// opts, such as html.ParseOptionEnableScripting(false) to parse the content
+// of <noscript> elements as markup, as a browser with scripting disabled
+// would.  By default, documents are parsed the same as html.Parse, with
+// scripting enabled, so <noscript> content is treated as text and any
+// microformats in it are ignored.  The options are also used for the
+// fragments parsed by WithScriptTemplates and Parser, and for XHTML documents
+// that are parsed as HTML.
+func WithHTMLParseOptions(opts ...html.ParseOption) Option {
+	return func(p *parser) {
+		p.htmlOptions = opts
+	}
+}
+
+// WithDeclarativeShadowDOM parses microformats in declarative shadow roots,
+// which are <template> elements with a shadowrootmode attribute (or the
+// older shadowroot attribute).  Browsers attach the content of these
+// templates to their parent element as a shadow tree, so it is rendered,
+// unlike the content of other templates, which is always ignored.
+//
+// Shadow roots are walked in place, as if their content were children of the
+// template's parent.  Slots are not assigned, so the text values of elements
+// are taken from their light DOM children, not the shadow tree.
+func WithDeclarativeShadowDOM() Option {
+	return func(p *parser) {
+		p.shadowDOM = true
+	}
+}
+
+// isShadowRoot returns whether node is a declarative shadow root.
+func isShadowRoot(node *html.Node) bool {
+	return isAtom(node, atom.Template) && (hasAttr(node, "shadowrootmode") || hasAttr(node, "shadowroot"))
+}
+
+// WithoutHead skips the <head> of documents, starting the walk for
+// microformats and rels at the <body> element instead.  This saves time on
+// pages with large heads, at the cost of everything in them: rels from
+// <link> elements are not collected, and a <base> element is not used to
+// resolve relative URLs, which are resolved against the base URL passed to
+// Parse instead.  If the document has no <body> element, the whole document
+// is walked.  Malformed documents with more than one <body> element, such as
+// XHTML documents made by concatenating pages, have each of them walked.
+func WithoutHead() Option {
+	return func(p *parser) {
+		p.withoutHead = true
+	}
+}
+
+// starts returns the nodes to start walking doc from, which are its <body>
+// elements if set by WithoutHead.  The HTML parser merges repeated <html>
+// and <body> tags into a single element, but documents parsed as XML by
+// WithXHTML, or built by the caller, may have several.
+func (p *parser) starts(doc *html.Node) []*html.Node {
+	if !p.withoutHead {
+		return []*html.Node{doc}
+	}
+	var bodies []*html.Node
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		if !isAtom(c, atom.Html) {
+			continue
+		}
+		for b := c.FirstChild; b != nil; b = b.NextSibling {
+			if isAtom(b, atom.Body) {
+				bodies = append(bodies, b)
+			}
+		}
+	}
+	if len(bodies) == 0 {
+		return []*html.Node{doc}
+	}
+	return bodies
+}
+
+// WithIgnoreAttribute skips elements that have the attribute name, such as
+// "data-mf-ignore", along with everything in them, so that documentation
+// pages can show examples of microformats markup without the examples being
+// parsed as real data.  No microformats, properties, or rels are parsed from
+// ignored elements, but they are still included in the text and html values
+// of any properties they are within, since they are a part of the content.
+// The value of the attribute is not used.  By default, no elements are
+// ignored.
+func WithIgnoreAttribute(name string) Option {
+	return func(p *parser) {
+		p.ignoreAttr = strings.ToLower(name)
+	}
+}
+
+// isIgnored returns whether node is ignored, as set by WithIgnoreAttribute.
+func (p *parser) isIgnored(node *html.Node) bool {
+	return p.ignoreAttr != "" && node.Type == html.ElementNode && hasAttr(node, p.ignoreAttr)
+}
+
+// WithScriptTemplates parses microformats in client-side templates, which
+// are <script> elements whose type is one of types, such as
+// "text/template" or "text/x-handlebars-template".  Types are compared case
+// insensitively, ignoring any parameters.
+//
+// The content of a <script> element is raw text rather than part of the
+// document, so it is normally ignored.  With this option, the content of
+// each matching script is parsed as an HTML fragment and walked as if it
+// appeared in place of the script element: top-level microformats in it are
+// added to the parsed items (or as children or properties of an enclosing
+// microformat), and its rel links are collected.  Template syntax such as
+// Mustache "{{name}}" placeholders is not evaluated, and appears as-is in
+// property values.  Templates are often never rendered, or rendered with
+// different content, so the microformats found in them may not describe the
+// page at all.  This option should only be used for sites known to ship
+// their markup as templates.
+func WithScriptTemplates(types ...string) Option {
+	return func(p *parser) {
+		p.scriptTypes = make(map[string]bool, len(types))
+		for _, t := range types {
+			p.scriptTypes[mediaType(t)] = true
+		}
+	}
+}
+
+// mediaType returns the lowercased media type of t, without parameters.
+func mediaType(t string) string {
+	t, _, _ = strings.Cut(t, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// scriptTemplate returns the nodes parsed from the content of node, if it is
+// a <script> element that is a template as configured by WithScriptTemplates.
+func (p *parser) scriptTemplate(node *html.Node) []*html.Node {
+	if p.scriptTypes == nil || !isAtom(node, atom.Script) || !p.scriptTypes[mediaType(getAttr(node, "type"))] {
+		return nil
+	}
+	var text strings.Builder
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			text.WriteString(c.Data)
+		}
+	}
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragmentWithOptions(strings.NewReader(text.String()), context, p.htmlOptions...)
+	if err != nil {
+		return nil
+	}
+	return nodes
+}
@@ -0,0 +1,821 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+	"golang.org/x/text/unicode/norm"
+)
+
+// parseItemsWith parses the HTML document s with a base URL of
+// http://example.com/ and the provided options, and returns the top-level
+// microformats found.
+func parseItemsWith(s string, opts ...Option) []*Microformat {
+	base, _ := url.Parse("http://example.com/")
+	return Parse(strings.NewReader(s), base, opts...).Items
+}
+
+func Test_WithPropertyPrefix(t *testing.T) {
+	jsonValue := func(node *html.Node, _ *url.URL) any {
+		var v any
+		if err := json.Unmarshal([]byte(getAttr(node, "value")), &v); err != nil {
+			return nil
+		}
+		return v
+	}
+	upper := func(node *html.Node, _ *url.URL) any {
+		return strings.ToUpper(getTextContent(node, nil))
+	}
+	doc := `<div class="h-entry">
+		<data class="j-meta" value='{"a":1}'></data>
+		<data class="j-invalid" value='{'></data>
+		<span class="x-name">shout</span>
+		<span class="p-summary">summary</span>
+		<span class="x-author h-card">Jane</span>
+	</div>`
+
+	tests := []struct {
+		opts []Option
+		want map[string][]any
+	}{
+		{
+			nil,
+			map[string][]any{
+				"summary": {"summary"},
+			},
+		},
+		{
+			[]Option{WithPropertyPrefix("j", jsonValue), WithPropertyPrefix("x", upper)},
+			map[string][]any{
+				"meta":    {map[string]any{"a": float64(1)}},
+				"name":    {"SHOUT"},
+				"summary": {"summary"},
+				"author": {&Microformat{
+					Type:       []string{"h-card"},
+					Properties: map[string][]any{"name": {"Jane"}},
+					Value:      "JANE",
+				}},
+			},
+		},
+		{
+			// built-in prefixes cannot be overridden
+			[]Option{WithPropertyPrefix("p", upper), WithPropertyPrefix("h", upper)},
+			map[string][]any{
+				"summary": {"summary"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		if diff := cmp.Diff(tt.want, items[0].Properties, ignoreParseState); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+	}
+}
+
+func Test_LowercaseEmail(t *testing.T) {
+	tests := []struct {
+		email, want string
+	}{
+		{"", ""},
+		{"Foo@Bar.com", "Foo@bar.com"},
+		{"mailto:Foo@Bar.com", "mailto:Foo@bar.com"},
+		{"MAILTO:Foo@BAR.COM", "mailto:Foo@bar.com"},
+		{"mailto:Foo@Bar.com?Subject=Hi", "mailto:Foo@bar.com?Subject=Hi"},
+		{"\"A@B\"@Example.com", "\"A@B\"@example.com"},
+		{"Not An Email", "Not An Email"},
+	}
+
+	for _, tt := range tests {
+		if got := lowercaseEmail(tt.email); got != tt.want {
+			t.Errorf("lowercaseEmail(%q) returned %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func Test_WithLowercaseEmails(t *testing.T) {
+	doc := `<div class="h-card">
+		<a class="p-name u-url" href="/Jane">Jane DOE</a>
+		<a class="u-email" href="mailto:Jane.Doe@Example.COM">email</a>
+		<span class="p-email">JD@Example.net</span>
+	</div>`
+
+	tests := []struct {
+		opts []Option
+		want map[string][]any
+	}{
+		{nil, map[string][]any{
+			"name":  {"Jane DOE"},
+			"url":   {"http://example.com/Jane"},
+			"email": {"mailto:Jane.Doe@Example.COM", "JD@Example.net"},
+		}},
+		{[]Option{WithLowercaseEmails()}, map[string][]any{
+			"name":  {"Jane DOE"},
+			"url":   {"http://example.com/Jane"},
+			"email": {"mailto:Jane.Doe@example.com", "JD@example.net"},
+		}},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		if diff := cmp.Diff(tt.want, items[0].Properties); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+	}
+}
+
+func Test_Truncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"", 3, ""},
+		{"abc", 3, "abc"},
+		{"abcd", 3, "abc"},
+		{"abc", 0, ""},
+		{"héllo", 2, "h"},  // é is 2 bytes
+		{"héllo", 3, "hé"}, // é is 2 bytes
+		{"日本語", 4, "日"},    // each character is 3 bytes
+		{"日本語", 6, "日本"},
+		{"🙂🙂", 7, "🙂"}, // emoji is 4 bytes
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncate(%q, %d) returned %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func Test_WithMaxValueLength(t *testing.T) {
+	doc := `<div class="h-entry">
+		<span class="p-name">日本語のタイトル</span>
+		<span class="p-summary">short</span>
+		<div class="e-content"><p>long content</p></div>
+		<a class="u-url" href="/a/very/long/url">link</a>
+		<a class="u-syndication" href="/s">short</a>
+	</div>`
+	base, _ := url.Parse("http://example.com/")
+
+	tests := []struct {
+		n            int
+		want         map[string][]any
+		wantWarnings int
+	}{
+		{0, map[string][]any{
+			"name":        {"日本語のタイトル"},
+			"summary":     {"short"},
+			"content":     {map[string]string{"value": "long content", "html": "<p>long content</p>"}},
+			"url":         {"http://example.com/a/very/long/url"},
+			"syndication": {"http://example.com/s"},
+		}, 0},
+		{20, map[string][]any{
+			"name":        {"日本語のタイ"},
+			"summary":     {"short"},
+			"content":     {map[string]string{"value": "long content", "html": "<p>long content</p>"}},
+			"syndication": {"http://example.com/s"},
+		}, 2},
+		{10, map[string][]any{
+			"name":    {"日本語"},
+			"summary": {"short"},
+			"content": {map[string]string{"value": "long conte", "html": "<p>long co"}},
+		}, 5},
+	}
+
+	for _, tt := range tests {
+		data, warnings := ParseWithWarnings(strings.NewReader(doc), base, WithMaxValueLength(tt.n))
+		if diff := cmp.Diff(tt.want, data.Items[0].Properties); diff != "" {
+			t.Errorf("WithMaxValueLength(%d) mismatch (-want +got):\n%s", tt.n, diff)
+		}
+		if got := len(warnings); got != tt.wantWarnings {
+			t.Errorf("WithMaxValueLength(%d) returned %d warnings %v, want %d", tt.n, got, warnings, tt.wantWarnings)
+		}
+	}
+}
+
+func Test_Parse_Picture(t *testing.T) {
+	doc := `<div class="h-entry">
+		<picture class="u-photo">
+			<source srcset="/a.avif" type="image/avif">
+			<source srcset="/a.webp 1x, /a-2x.webp 2x" type="image/webp">
+			<img src="/a.jpg" srcset="/a-2x.jpg 2x" alt="A photo">
+		</picture>
+		<picture><source srcset="/b.webp"><img class="u-featured" src="/b.jpg" alt=""></picture>
+	</div>`
+
+	tests := []struct {
+		opts []Option
+		want map[string][]any
+	}{
+		{nil, map[string][]any{
+			"photo":    {map[string]string{"value": "http://example.com/a.jpg", "alt": "A photo"}},
+			"featured": {"http://example.com/b.jpg"},
+			"name":     {"A photo"},
+		}},
+		{[]Option{WithSrcset()}, map[string][]any{
+			"photo": {map[string]string{
+				"value":  "http://example.com/a.jpg",
+				"alt":    "A photo",
+				"srcset": "http://example.com/a.avif, http://example.com/a.webp 1x, http://example.com/a-2x.webp 2x, http://example.com/a-2x.jpg 2x",
+			}},
+			"featured": {"http://example.com/b.jpg"},
+			"name":     {"A photo"},
+		}},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		if diff := cmp.Diff(tt.want, items[0].Properties); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+	}
+}
+
+func Test_Parse_ImpliedPicture(t *testing.T) {
+	doc := `<div class="h-card"><picture><source srcset="/a.webp"><img src="/a.jpg" alt="Jane"></picture></div>`
+	want := map[string][]any{
+		"name":  {"Jane"},
+		"photo": {map[string]string{"value": "http://example.com/a.jpg", "alt": "Jane"}},
+	}
+	items := parseItems(doc)
+	if diff := cmp.Diff(want, items[0].Properties); diff != "" {
+		t.Errorf("Parse(%q) mismatch (-want +got):\n%s", doc, diff)
+	}
+}
+
+func Test_WithUnicodeNormalization(t *testing.T) {
+	decomposed := "José García" // combining acute accents
+	composed := "José García"
+	doc := `<div class="h-card"><span class="p-name">` + decomposed + `</span>` +
+		`<div class="e-note"><b>` + decomposed + `</b></div>` +
+		"<a class=\"u-url\" href=\"/josé\">home</a>" +
+		`<div class="h-card">` + decomposed + `</div></div>`
+
+	tests := []struct {
+		opts       []Option
+		want, html string
+	}{
+		{nil, decomposed, decomposed},
+		{[]Option{WithUnicodeNormalization(norm.NFC)}, composed, decomposed},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		props := items[0].Properties
+		if got := props["name"]; !cmp.Equal(got, []any{tt.want}) {
+			t.Errorf("name property returned %q, want %q", got, tt.want)
+		}
+		wantNote := map[string]string{"value": tt.want, "html": "<b>" + tt.html + "</b>"}
+		if got := props["note"]; !cmp.Equal(got, []any{wantNote}) {
+			t.Errorf("note property returned %q, want %q", got, wantNote)
+		}
+		if got, want := props["url"], []any{"http://example.com/jose%CC%81"}; !cmp.Equal(got, want) {
+			t.Errorf("url property returned %q, want %q", got, want)
+		}
+		child := items[0].Children[0]
+		if got := child.Properties["name"]; !cmp.Equal(got, []any{tt.want}) {
+			t.Errorf("implied name returned %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func Test_WithLegacyBareProperties(t *testing.T) {
+	doc := `<div class="h-card">
+		<span class="name">Jane Doe</span>
+		<span class="org p-org">Example</span>
+		<span class="locality big">Portland</span>
+		<a class="u-url" href="/jane">home</a>
+	</div>
+	<div class="vcard"><span class="fn">John</span><span class="summary">v1</span></div>
+	<p class="summary">not in a root</p>`
+
+	tests := []struct {
+		opts []Option
+		want []map[string][]any
+	}{
+		{nil, []map[string][]any{
+			{"url": {"http://example.com/jane"}, "org": {"Example"}},
+			{"name": {"John"}},
+		}},
+		{[]Option{WithLegacyBareProperties()}, []map[string][]any{
+			{
+				"name":     {"Jane Doe"},
+				"org":      {"Example"},
+				"locality": {"Portland"},
+				"url":      {"http://example.com/jane"},
+			},
+			{"name": {"John"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		var got []map[string][]any
+		for _, item := range items {
+			got = append(got, item.Properties)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+	}
+}
+
+func Test_BarePropertyClasses(t *testing.T) {
+	// props has spare capacity, which must not be written to
+	props := make([]string, 1, 4)
+	props[0] = "p-name"
+	got := barePropertyClasses([]string{"name", "summary", "summary", "other"}, props)
+	if diff := cmp.Diff([]string{"p-summary"}, got); diff != "" {
+		t.Errorf("barePropertyClasses mismatch (-want +got):\n%s", diff)
+	}
+	if extended := props[:cap(props)]; extended[1] != "" {
+		t.Errorf("barePropertyClasses modified the backing array of props: %q", extended)
+	}
+}
+
+func Test_WithScriptTemplates(t *testing.T) {
+	doc := `<div class="h-feed"><span class="p-name">Feed</span>
+		<script type="text/x-handlebars-template">
+			<div class="h-entry"><a class="p-name u-url" href="/{{slug}}">{{title}}</a></div>
+		</script>
+		<script type="text/javascript">var s = '<div class="h-card">not a template</div>';</script>
+	</div>
+	<script type="Text/Template; charset=utf-8"><a class="h-card" href="/jane">Jane</a></script>`
+
+	tests := []struct {
+		opts []Option
+		want []*Microformat
+	}{
+		{nil, []*Microformat{{
+			Type:       []string{"h-feed"},
+			Properties: map[string][]any{"name": {"Feed"}},
+		}}},
+		{[]Option{WithScriptTemplates("text/x-handlebars-template", "text/template")}, []*Microformat{
+			{
+				Type:       []string{"h-feed"},
+				Properties: map[string][]any{"name": {"Feed"}},
+				Children: []*Microformat{{
+					Type: []string{"h-entry"},
+					Properties: map[string][]any{
+						"name": {"{{title}}"},
+						"url":  {"http://example.com/%7B%7Bslug%7D%7D"},
+					},
+				}},
+			},
+			{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"url":  {"http://example.com/jane"},
+				},
+			},
+		}},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		if diff := cmp.Diff(tt.want, items, ignoreParseState); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+	}
+}
+
+func Test_WithHTMLParseOptions(t *testing.T) {
+	doc := `<div class="h-entry"><span class="p-name">Post</span>
+		<noscript><img class="u-photo" src="/photo.jpg" alt=""></noscript>
+	</div>`
+
+	tests := []struct {
+		opts []Option
+		want []*Microformat
+	}{
+		{nil, []*Microformat{{
+			Type:       []string{"h-entry"},
+			Properties: map[string][]any{"name": {"Post"}},
+		}}},
+		{[]Option{WithHTMLParseOptions(html.ParseOptionEnableScripting(true))}, []*Microformat{{
+			Type:       []string{"h-entry"},
+			Properties: map[string][]any{"name": {"Post"}},
+		}}},
+		{[]Option{WithHTMLParseOptions(html.ParseOptionEnableScripting(false))}, []*Microformat{{
+			Type: []string{"h-entry"},
+			Properties: map[string][]any{
+				"name":  {"Post"},
+				"photo": {"http://example.com/photo.jpg"},
+			},
+		}}},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		if diff := cmp.Diff(tt.want, items, ignoreParseState); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+
+		p := NewParser(&url.URL{Scheme: "http", Host: "example.com", Path: "/"}, tt.opts...)
+		_, _ = p.Write([]byte(doc))
+		_ = p.Close()
+		if diff := cmp.Diff(tt.want, p.Items(), ignoreParseState); diff != "" {
+			t.Errorf("Parser with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+	}
+}
+
+func Test_WithDeclarativeShadowDOM(t *testing.T) {
+	doc := `<profile-card>
+		<template shadowrootmode="open">
+			<div class="h-card"><slot name="name" class="p-name">Jane</slot><a class="u-url" href="/jane">home</a></div>
+		</template>
+	</profile-card>
+	<template><div class="h-card">Not rendered</div></template>
+	<div class="h-entry"><span class="p-name">Post</span>
+		<share-button><template shadowroot="closed"><a class="u-syndication" href="https://social.example/1">share</a></template></share-button>
+	</div>`
+
+	tests := []struct {
+		opts []Option
+		want []*Microformat
+	}{
+		{nil, []*Microformat{{
+			Type:       []string{"h-entry"},
+			Properties: map[string][]any{"name": {"Post"}},
+		}}},
+		{[]Option{WithDeclarativeShadowDOM()}, []*Microformat{
+			{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"url":  {"http://example.com/jane"},
+				},
+			},
+			{
+				Type: []string{"h-entry"},
+				Properties: map[string][]any{
+					"name":        {"Post"},
+					"syndication": {"https://social.example/1"},
+				},
+			},
+		}},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		if diff := cmp.Diff(tt.want, items, ignoreParseState); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+	}
+}
+
+func Test_WithRawURLs(t *testing.T) {
+	doc := `<base href="/blog/">
+	<div class="h-entry">
+		<a class="u-url" href=" 2024/hello ">permalink</a>
+		<img class="u-photo" src="../a.jpg" alt="A">
+		<span class="p-category">go</span><a class="u-category" href="/tags/mf">mf</a>
+		<a class="u-author h-card" href="//jane.example">Jane</a>
+		<span class="u-uid">tag:example.com,2024:1</span>
+	</div>`
+
+	items := parseItemsWith(doc)
+	if items[0].RawURLs != nil {
+		t.Errorf("Parse without WithRawURLs returned RawURLs %v, want nil", items[0].RawURLs)
+	}
+
+	items = parseItemsWith(doc, WithRawURLs())
+	wantProps := parseItemsWith(doc)[0].Properties
+	want := map[string][]string{
+		"url":      {"2024/hello"},
+		"photo":    {"../a.jpg"},
+		"category": {"/tags/mf"},
+		"author":   {"//jane.example"},
+		"uid":      {"tag:example.com,2024:1"},
+	}
+	if diff := cmp.Diff(want, items[0].RawURLs); diff != "" {
+		t.Errorf("Parse with WithRawURLs RawURLs mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantProps, items[0].Properties, ignoreParseState); diff != "" {
+		t.Errorf("Parse with WithRawURLs properties mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_WithStripParams(t *testing.T) {
+	doc := `<div class="h-entry">
+		<a class="u-url" href="/post?id=1&utm_source=feed&UTM_X=y&fbclid=abc#top">post</a>
+		<a class="u-syndication" href="https://social.example/1?utm_medium=x&utm_campaign=y">social</a>
+		<a class="u-uid" href="/post?utm_source=feed">uid</a>
+		<a class="u-like-of" href="/a?q=a%20b&fbclid">like</a>
+		<div class="e-content"><a class="u-in-reply-to" href="/b?fbclid=1">b</a></div>
+		<a class="h-card" href="/jane?utm_term=z">Jane</a>
+	</div>
+	<a rel="me" href="https://social.example/@jane?utm_source=a&ref=b">me</a>`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base, WithStripParams("utm_*", "fbclid"))
+
+	want := map[string][]any{
+		"url":         {"http://example.com/post?id=1&UTM_X=y#top"},
+		"syndication": {"https://social.example/1"},
+		"uid":         {"http://example.com/post?utm_source=feed"},
+		"like-of":     {"http://example.com/a?q=a%20b"},
+		"in-reply-to": {"http://example.com/b"},
+		"content": {map[string]string{
+			"value": "b",
+			"html":  `<a class="u-in-reply-to" href="http://example.com/b?fbclid=1">b</a>`,
+		}},
+	}
+	got := data.Items[0].Properties
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Parse with WithStripParams mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := firstString(data.Items[0].Children[0], "url"), "http://example.com/jane"; got != want {
+		t.Errorf("Parse with WithStripParams returned implied url %q, want %q", got, want)
+	}
+	if diff := cmp.Diff([]string{"https://social.example/@jane?ref=b"}, data.Rels["me"]); diff != "" {
+		t.Errorf("Parse with WithStripParams rels mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_WithAllowedSchemes(t *testing.T) {
+	doc := `<div class="h-card">
+		<a class="p-name u-url" href="javascript:alert(1)">Jane</a>
+		<a class="u-url" href="https://jane.example/">home</a>
+		<a class="u-email" href="mailto:jane@example.com">email</a>
+		<img class="u-photo" src="data:image/png;base64,AAAA" alt="Jane">
+		<a class="u-uid" href="tag:jane.example,2024:jane">uid</a>
+		<a class="u-x-app" href="JavaScript:void(0)">app</a>
+	</div>
+	<a class="h-card" href=" javascript:alert(2)"><img alt="Logo" src="data:image/gif;base64,AAAA"></a>`
+
+	tests := []struct {
+		opts     []Option
+		want     []map[string][]any
+		warnings int
+	}{
+		{
+			// all schemes are allowed by default
+			nil,
+			[]map[string][]any{
+				{
+					"name":  {"Jane"},
+					"url":   {"javascript:alert(1)", "https://jane.example/"},
+					"email": {"mailto:jane@example.com"},
+					"photo": {map[string]string{"value": "data:image/png;base64,AAAA", "alt": "Jane"}},
+					"uid":   {"tag:jane.example,2024:jane"},
+					"x-app": {"javascript:void(0)"},
+				},
+				{
+					"name":  {"Logo"},
+					"url":   {" javascript:alert(2)"},
+					"photo": {map[string]string{"value": "data:image/gif;base64,AAAA", "alt": "Logo"}},
+				},
+			},
+			0,
+		},
+		{
+			[]Option{WithAllowedSchemes()},
+			[]map[string][]any{
+				{
+					"name":  {"Jane"},
+					"url":   {"https://jane.example/"},
+					"email": {"mailto:jane@example.com"},
+					"uid":   {"tag:jane.example,2024:jane"},
+				},
+				{"name": {"Logo"}},
+			},
+			5,
+		},
+		{
+			[]Option{WithAllowedSchemes("HTTPS", "data")},
+			[]map[string][]any{
+				{
+					"name":  {"Jane"},
+					"url":   {"https://jane.example/"},
+					"photo": {map[string]string{"value": "data:image/png;base64,AAAA", "alt": "Jane"}},
+					"uid":   {"tag:jane.example,2024:jane"},
+				},
+				{
+					"name":  {"Logo"},
+					"photo": {map[string]string{"value": "data:image/gif;base64,AAAA", "alt": "Logo"}},
+				},
+			},
+			4,
+		},
+	}
+
+	base, _ := url.Parse("http://example.com/")
+	for _, tt := range tests {
+		data, warnings := ParseWithWarnings(strings.NewReader(doc), base, tt.opts...)
+		var got []map[string][]any
+		for _, item := range data.Items {
+			got = append(got, item.Properties)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+		if len(warnings) != tt.warnings {
+			t.Errorf("Parse with %d options returned warnings %v, want %d", len(tt.opts), warnings, tt.warnings)
+		}
+	}
+}
+
+func Test_WithAllowedSchemes_Obfuscated(t *testing.T) {
+	tests := []struct {
+		html string
+		want map[string][]any
+	}{
+		{
+			`<div class="h-card"><a class="u-url" href="java&#9;script:alert(1)">x</a></div>`,
+			map[string][]any{"name": {"x"}},
+		},
+		{
+			`<div class="h-card"><a class="u-url" href="java&#10;script:alert(1)">x</a></div>`,
+			map[string][]any{"name": {"x"}},
+		},
+		{
+			`<div class="h-card"><a class="u-url" href="&#1;javascript:alert(1)">x</a></div>`,
+			map[string][]any{"name": {"x"}},
+		},
+		{
+			// implied url
+			`<a class="h-card" href="java&#9;script:alert(1)">Jane</a>`,
+			map[string][]any{"name": {"Jane"}},
+		},
+		{
+			// unparseable, with something that could be read as a scheme
+			`<a class="h-card" href="java&#1;script:alert(1)">Jane</a>`,
+			map[string][]any{"name": {"Jane"}},
+		},
+		{
+			// unparseable, without a scheme
+			`<a class="h-card" href="jane%zz">Jane</a>`,
+			map[string][]any{"name": {"Jane"}, "url": {"jane%zz"}},
+		},
+	}
+
+	base, _ := url.Parse("http://example.com/")
+	for _, tt := range tests {
+		data, warnings := ParseWithWarnings(strings.NewReader(tt.html), base, WithAllowedSchemes())
+		if len(data.Items) != 1 {
+			t.Fatalf("Parse(%q) returned %d items, want 1", tt.html, len(data.Items))
+		}
+		if diff := cmp.Diff(tt.want, data.Items[0].Properties); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+		wantWarnings := 0
+		if _, ok := tt.want["url"]; !ok {
+			wantWarnings = 1
+		}
+		if len(warnings) != wantWarnings {
+			t.Errorf("Parse(%q) returned warnings %v, want %d", tt.html, warnings, wantWarnings)
+		}
+	}
+}
+
+func Test_WithoutHead(t *testing.T) {
+	doc := `<html><head><base href="/blog/"><link rel="me" href="https://social.example/@jane">
+	<title class="h-card">Head</title></head>
+	<body><div class="h-card"><a class="p-name u-url" rel="author" href="jane">Jane</a></div></body></html>`
+	base, _ := url.Parse("http://example.com/")
+
+	data := Parse(strings.NewReader(doc), base)
+	if got, want := len(data.Rels), 2; got != want {
+		t.Errorf("Parse returned %d rels, want %d", got, want)
+	}
+
+	data = Parse(strings.NewReader(doc), base, WithoutHead())
+	want := map[string][]string{"author": {"http://example.com/jane"}}
+	if diff := cmp.Diff(want, data.Rels); diff != "" {
+		t.Errorf("Parse with WithoutHead rels mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := len(data.Items), 1; got != want {
+		t.Fatalf("Parse with WithoutHead returned %d items, want %d", got, want)
+	}
+	if got, want := firstString(data.Items[0], "url"), "http://example.com/jane"; got != want {
+		t.Errorf("Parse with WithoutHead returned url %q, want %q", got, want)
+	}
+	if data.BaseURL.String() != base.String() {
+		t.Errorf("Parse with WithoutHead returned BaseURL %v, want %v", data.BaseURL, base)
+	}
+}
+
+// duplicateBodyDocument is two pages concatenated into one, as produced by
+// some broken templates and archiving tools.
+const duplicateBodyDocument = `<!doctype html>
+<html><head><title>First</title></head>
+<body><div class="h-entry"><p class="p-name">First</p></div></body></html>
+<!doctype html>
+<html><head><title>Second</title><link rel="me" href="/me"></head>
+<body><div class="h-entry"><p class="p-name">Second</p></div></body></html>`
+
+func Test_Parse_DuplicateBody(t *testing.T) {
+	// a well-formed XHTML document keeps both bodies in the tree
+	xhtml := `<html xmlns="http://www.w3.org/1999/xhtml">
+<body><div class="h-entry"><p class="p-name">First</p></div></body>
+<body><div class="h-entry"><p class="p-name">Second</p></div></body></html>`
+
+	tests := []struct {
+		name string
+		doc  string
+		opts []Option
+	}{
+		{"html", duplicateBodyDocument, nil},
+		{"html without head", duplicateBodyDocument, []Option{WithoutHead()}},
+		{"xhtml", xhtml, []Option{WithXHTML()}},
+		{"xhtml without head", xhtml, []Option{WithXHTML(), WithoutHead()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, item := range parseItemsWith(tt.doc, tt.opts...) {
+				names = append(names, firstString(item, "name"))
+			}
+			if diff := cmp.Diff([]string{"First", "Second"}, names); diff != "" {
+				t.Errorf("Parse returned item names mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	base, _ := url.Parse("http://example.com/")
+	it, _ := FirstOfType(strings.NewReader(xhtml), base, "h-entry", WithXHTML(), WithoutHead())
+	if got := firstString(it, "name"); got != "First" {
+		t.Errorf("FirstOfType returned name %q, want %q", got, "First")
+	}
+}
+
+func Test_WithIgnoreAttribute(t *testing.T) {
+	doc := `<div class="h-entry"><p class="p-name">Marking up posts</p>
+		<div class="e-content"><p>Use this:</p>
+			<pre data-mf-ignore><div class="h-card"><a class="p-name u-url" rel="me" href="/jane">Jane</a></div></pre>
+		</div>
+		<span class="p-category" DATA-MF-IGNORE>draft</span>
+	</div>
+	<div class="h-card" data-mf-ignore="true">Example</div>
+	<div class="h-card">Jane</div>`
+
+	// without the option, the examples are parsed
+	if got, want := len(parseItems(doc)), 3; got != want {
+		t.Errorf("Parse returned %d items, want %d", got, want)
+	}
+
+	data := Parse(strings.NewReader(doc), nil, WithIgnoreAttribute("data-mf-ignore"))
+	want := []*Microformat{
+		{
+			Type: []string{"h-entry"},
+			Properties: map[string][]any{
+				"name": {"Marking up posts"},
+				"content": {map[string]string{
+					"value": "Use this:\n\t\t\tJane",
+					"html": `<p>Use this:</p>` + "\n\t\t\t" +
+						`<pre data-mf-ignore=""><div class="h-card"><a class="p-name u-url" rel="me" href="/jane">Jane</a></div></pre>`,
+				}},
+			},
+		},
+		{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Jane"}}},
+	}
+	if diff := cmp.Diff(want, data.Items, ignoreParseState); diff != "" {
+		t.Errorf("Parse with WithIgnoreAttribute mismatch (-want +got):\n%s", diff)
+	}
+	if len(data.Rels) != 0 {
+		t.Errorf("Parse with WithIgnoreAttribute returned rels %v, want none", data.Rels)
+	}
+}
+
+func Test_WithCaseInsensitivePrefixes(t *testing.T) {
+	doc := `<div class="H-card"><span class="P-name">Jane</span><a class="U-url u-uid" href="/jane">home</a>
+		<a class="U-URL" href="/other">other</a><span class="Dt-bday">2000-01-02</span>
+		<div class="E-note"><b>hi</b></div><span class="X-custom">x</span></div>`
+
+	if items := parseItemsWith(doc); len(items) != 0 {
+		t.Errorf("Parse without WithCaseInsensitivePrefixes returned %d items, want 0", len(items))
+	}
+
+	want := []*Microformat{{
+		Type: []string{"h-card"},
+		Properties: map[string][]any{
+			"name": {"Jane"},
+			"url":  {"http://example.com/jane"},
+			"uid":  {"http://example.com/jane"},
+			"bday": {"2000-01-02"},
+			"note": {map[string]string{"value": "hi", "html": "<b>hi</b>"}},
+		},
+	}}
+	items := parseItemsWith(doc, WithCaseInsensitivePrefixes())
+	if diff := cmp.Diff(want, items, ignoreParseState); diff != "" {
+		t.Errorf("Parse with WithCaseInsensitivePrefixes mismatch (-want +got):\n%s", diff)
+	}
+
+	p := NewParser(nil, WithCaseInsensitivePrefixes())
+	_, _ = p.Write([]byte(`<div class="H-card"><span class="P-name">Jane</span></div>`))
+	_ = p.Close()
+	if got := len(p.Items()); got != 1 {
+		t.Errorf("Parser with WithCaseInsensitivePrefixes returned %d items, want 1", got)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for locating elements in the document source.
+
+package microformats
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// WithPositions records the position in the document source of the element
+// each warning returned by ParseWithWarnings is about, in the Line and Column
+// fields of the Warning, so that warnings can be reported like the output of
+// a linter.
+//
+// The parsed document tree has no record of positions, so they are found by
+// a separate pass over the source with an HTML tokenizer, matching the start
+// tags it finds to the elements of the tree in document order.  This is
+// accurate for well-formed documents, but elements created by the HTML parser
+// rather than written in the source (such as an implied <tbody>) have no
+// position, and elements moved by the parser's error recovery (such as
+// content misnested in a table) may not be matched, or be matched to a later
+// tag with the same name.  Positions are counted in the document after any
+// character encoding is decoded by WithCharsetDetection, with columns counted
+// in characters.  Positions are not recorded for documents parsed as XML by
+// WithXHTML.  Recording positions requires buffering the whole document, and
+// is off by default.
+func WithPositions() Option {
+	return func(p *parser) {
+		p.trackPositions = true
+	}
+}
+
+// position is a line and column in the document source, both starting at 1.
+type position struct {
+	line, column int
+}
+
+// sourceTag is a start tag found in the document source.
+type sourceTag struct {
+	name   string
+	offset int
+}
+
+// implicitElements are the elements that the HTML parser may create without
+// a start tag in the source.  They are only matched to the next start tag,
+// so that an implied element does not consume a later tag for an element
+// with the same name.
+var implicitElements = map[atom.Atom]bool{
+	atom.Html: true, atom.Head: true, atom.Body: true, atom.Tbody: true,
+	atom.Colgroup: true, atom.Tr: true,
+}
+
+// maxLookahead is the number of source tags searched for a match when an
+// element does not match the next tag, which limits how far a single element
+// created by the HTML parser (such as a formatting element reopened after a
+// misnested tag) can move matching ahead of the rest of the document.
+const maxLookahead = 16
+
+// parseWithPositions parses the document read from r using parse, and returns
+// the positions in the source of the elements of the parsed document, as
+// described in WithPositions.
+func parseWithPositions(r io.Reader, parse func(io.Reader) *html.Node) (*html.Node, map[*html.Node]position) {
+	src, _ := io.ReadAll(r) // read errors are recorded by errReader
+	doc := parse(bytes.NewReader(src))
+	if doc == nil {
+		return nil, nil
+	}
+
+	var tags []sourceTag
+	z := html.NewTokenizer(bytes.NewReader(src))
+	for offset := 0; ; {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := len(z.Raw())
+		if tt == html.StartTagToken || tt == html.SelfClosingTagToken {
+			name, _ := z.TagName()
+			tags = append(tags, sourceTag{name: string(name), offset: offset})
+		}
+		offset += raw
+	}
+
+	offsets := make(map[*html.Node]int)
+	next := 0
+	var match func(n *html.Node)
+	match = func(n *html.Node) {
+		if n.Type == html.ElementNode && next < len(tags) {
+			if tags[next].name == n.Data {
+				offsets[n] = tags[next].offset
+				next++
+			} else if !implicitElements[n.DataAtom] {
+				for i := next + 1; i < len(tags) && i <= next+maxLookahead; i++ {
+					if tags[i].name == n.Data {
+						offsets[n] = tags[i].offset
+						next = i + 1
+						break
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			match(c)
+		}
+	}
+	match(doc)
+
+	var lineStarts []int
+	lineStarts = append(lineStarts, 0)
+	for i, b := range src {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	positions := make(map[*html.Node]position, len(offsets))
+	for n, offset := range offsets {
+		line := sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > offset }) - 1
+		positions[n] = position{
+			line:   line + 1,
+			column: utf8.RuneCount(src[lineStarts[line]:offset]) + 1,
+		}
+	}
+	return doc, positions
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_WithPositions(t *testing.T) {
+	doc := "<!doctype html>\n<html><body>\n" +
+		"<div class=\"h-entry\">\n" +
+		"  <p>café</p> <div class=\"p-content\"><b>x</b></div>\n" +
+		"</div>\n" +
+		"<div class=\"h-event\"><time class=\"dt-start\">2024-01-02</time><time class=\"dt-end\">2024-01-01</time></div>\n"
+
+	_, warnings := ParseWithWarnings(strings.NewReader(doc), nil)
+	for _, w := range warnings {
+		if w.Line != 0 || w.Column != 0 {
+			t.Errorf("ParseWithWarnings without WithPositions returned position %d:%d", w.Line, w.Column)
+		}
+	}
+
+	_, warnings = ParseWithWarnings(strings.NewReader(doc), nil, WithPositions())
+	want := []Warning{
+		{
+			Message: "p-content on <div> contains markup, which is not included in its value; e-content may have been intended",
+			Line:    4, Column: 15,
+		},
+		{Message: `h-event end "2024-01-01" is before start "2024-01-02"`, Line: 6, Column: 1},
+	}
+	if diff := cmp.Diff(want, warnings); diff != "" {
+		t.Errorf("ParseWithWarnings with WithPositions mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := warnings[1].String(), `6:1: h-event end "2024-01-01" is before start "2024-01-02"`; got != want {
+		t.Errorf("Warning.String returned %q, want %q", got, want)
+	}
+}
+
+func Test_ParseWithPositions(t *testing.T) {
+	// implied elements have no position, and do not consume later tags
+	doc := "<table><tr><td id=a>a</td></tr></table>\n<p id=b><b id=c>b</p><p id=d>d</p>\n<tbody id=e>"
+	_, positions := parseDocument(strings.NewReader(doc), []Option{WithPositions()})
+
+	got := make(map[string]position)
+	for n, pos := range positions {
+		if id := getAttr(n, "id"); id != "" {
+			got[id] = pos
+		} else {
+			got[n.Data] = pos
+		}
+	}
+	want := map[string]position{
+		"table": {1, 1},
+		"tr":    {1, 8},
+		"a":     {1, 12},
+		"b":     {2, 1},
+		"c":     {2, 9},
+		"d":     {2, 22},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(position{})); diff != "" {
+		t.Errorf("parseDocument with WithPositions mismatch (-want +got):\n%s", diff)
+	}
+}
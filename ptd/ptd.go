@@ -27,11 +27,21 @@ func PostType(item *microformats.Microformat) string {
 		}
 	}
 
-	// duplicate rsvp, repost, like, reply detection from response algorithm
-	if t := ResponseType(item); t != "" && t != "mention" {
-		return t
+	// the Post Type Algorithm checks response properties in a different
+	// order than the Response Type Algorithm, so that a reply which is
+	// also a repost or like is treated as a reply.
+	if hasRSVP(item) {
+		return "rsvp"
+	}
+	if validURL(item.Properties["in-reply-to"]) {
+		return "reply"
+	}
+	if validURL(item.Properties["repost-of"]) {
+		return "repost"
+	}
+	if validURL(item.Properties["like-of"]) {
+		return "like"
 	}
-
 	if validURL(item.Properties["video"]) {
 		return "video"
 	}
@@ -40,31 +50,15 @@ func PostType(item *microformats.Microformat) string {
 	}
 
 	// compare content and name to determine if post is a note or an article
-	var content, name string
-	for _, value := range item.Properties["content"] {
-		if v, ok := value.(string); ok && v != "" {
-			content = v
-			break
-		}
-	}
+	content := firstText(item.Properties["content"])
 	if content == "" {
-		for _, value := range item.Properties["summary"] {
-			if v, ok := value.(string); ok && v != "" {
-				content = v
-				break
-			}
-		}
+		content = firstText(item.Properties["summary"])
 	}
 	if content == "" {
 		return "note"
 	}
 
-	for _, value := range item.Properties["name"] {
-		if v, ok := value.(string); ok && v != "" {
-			name = v
-			break
-		}
-	}
+	name := firstText(item.Properties["name"])
 	if name == "" {
 		return "note"
 	}
@@ -88,12 +82,8 @@ func ResponseType(item *microformats.Microformat) string {
 		return ""
 	}
 
-	for _, value := range item.Properties["rsvp"] {
-		if v, ok := value.(string); ok {
-			if v == "yes" || v == "no" || v == "maybe" || v == "interested" {
-				return "rsvp"
-			}
-		}
+	if hasRSVP(item) {
+		return "rsvp"
 	}
 
 	if validURL(item.Properties["repost-of"]) {
@@ -109,12 +99,59 @@ func ResponseType(item *microformats.Microformat) string {
 	return "mention"
 }
 
-// Returns true if one of values is a string that is a valid URL.
+// hasRSVP returns whether item has a valid rsvp value.
+func hasRSVP(item *microformats.Microformat) bool {
+	for _, value := range item.Properties["rsvp"] {
+		if v, ok := value.(string); ok {
+			if v == "yes" || v == "no" || v == "maybe" || v == "interested" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// firstText returns the first non-empty plain text value in values.  For
+// embedded markup (e-* properties) and nested microformats, this is their
+// text value.
+func firstText(values []any) string {
+	for _, value := range values {
+		var v string
+		switch value := value.(type) {
+		case string:
+			v = value
+		case map[string]string:
+			v = value["value"]
+		case *microformats.Microformat:
+			if value != nil {
+				v = value.Value
+			}
+		}
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Returns true if one of values is a valid URL.  Values may be strings, images
+// with alt text, or nested microformats with a url property.
 func validURL(values []any) bool {
 	for _, value := range values {
+		var s string
+		switch value := value.(type) {
+		case string:
+			s = value
+		case map[string]string:
+			s = value["value"]
+		case *microformats.Microformat:
+			if value != nil && validURL(value.Properties["url"]) {
+				return true
+			}
+		}
 		// url.Parse will happily parse an empty string, but
 		// that's probably not really what we want here
-		if s, ok := value.(string); ok && s != "" {
+		if s != "" {
 			if _, err := url.Parse(s); err == nil {
 				return true
 			}
@@ -60,6 +60,18 @@ func Test_PostType_Properties(t *testing.T) {
 		{pm{"in-reply-to": {"foo"}}, "reply"},
 		{pm{"video": {"foo"}}, "video"},
 		{pm{"photo": {"foo"}}, "photo"},
+		{pm{"photo": {map[string]string{"value": "foo", "alt": "bar"}}}, "photo"},
+		{pm{"in-reply-to": {&microformats.Microformat{Properties: pm{"url": {"foo"}}}}}, "reply"},
+		{pm{"in-reply-to": {&microformats.Microformat{Properties: pm{}}}}, "note"},
+
+		// precedence
+		{pm{"rsvp": {"yes"}, "in-reply-to": {"foo"}}, "rsvp"},
+		{pm{"in-reply-to": {"foo"}, "repost-of": {"foo"}}, "reply"},
+		{pm{"in-reply-to": {"foo"}, "like-of": {"foo"}}, "reply"},
+		{pm{"repost-of": {"foo"}, "like-of": {"foo"}}, "repost"},
+		{pm{"like-of": {"foo"}, "video": {"foo"}}, "like"},
+		{pm{"video": {"foo"}, "photo": {"foo"}}, "video"},
+		{pm{"photo": {"foo"}, "content": {"foo"}, "name": {"bar"}}, "photo"},
 
 		// content and name variations
 		{pm{"content": {"foo"}}, "note"},
@@ -71,6 +83,8 @@ func Test_PostType_Properties(t *testing.T) {
 		{pm{"content": {"foo"}, "name": {"bar"}}, "article"},
 		{pm{"content": {"foo"}, "summary": {"bar"}, "name": {"bar"}}, "article"},
 		{pm{"content": {"foo \t\n bar"}, "name": {" foo bar "}}, "note"},
+		{pm{"content": {map[string]string{"value": "foo", "html": "<p>foo</p>"}}, "name": {"bar"}}, "article"},
+		{pm{"content": {map[string]string{"value": "foo bar", "html": "<p>foo bar</p>"}}, "name": {"foo"}}, "note"},
 	}
 
 	for _, tt := range tests {
@@ -110,6 +124,10 @@ func Test_ResponseType(t *testing.T) {
 		{pm{"in-reply-to": {}}, "mention"},
 		{pm{"in-reply-to": {""}}, "mention"},
 		{pm{"in-reply-to": {"foo"}}, "reply"},
+
+		// precedence
+		{pm{"in-reply-to": {"foo"}, "repost-of": {"foo"}}, "repost"},
+		{pm{"in-reply-to": {"foo"}, "like-of": {"foo"}}, "like"},
 	}
 
 	for _, tt := range tests {
@@ -134,6 +152,10 @@ func Test_ValidURL(t *testing.T) {
 		{[]any{"a", "b"}, true},
 		{[]any{"", "a"}, true},
 		{[]any{"%", "a"}, true},
+		{[]any{map[string]string{"value": "a", "alt": "b"}}, true},
+		{[]any{map[string]string{"alt": "b"}}, false},
+		{[]any{&microformats.Microformat{Properties: map[string][]any{"url": {"a"}}}}, true},
+		{[]any{(*microformats.Microformat)(nil)}, false},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,413 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes helpers for working with the rel values of a page.
+
+package microformats
+
+import (
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// FeedLink is a feed advertised by a page using a rel=alternate link.
+type FeedLink struct {
+	URL   string
+	Title string
+	Type  string // media type of the feed, such as "application/atom+xml"
+}
+
+// Icon is an icon for a site, advertised by a page using a rel=icon or
+// rel=apple-touch-icon link.
+type Icon struct {
+	URL   string
+	Sizes string // sizes of the icon, such as "16x16 32x32" or "any"
+	Type  string // media type of the icon, such as "image/png"
+}
+
+// iconRels are the rel values of links recognized by Icons, in the order
+// their icons are returned.
+var iconRels = []string{"icon", "apple-touch-icon", "apple-touch-icon-precomposed"}
+
+// feedTypes are the media types of feed formats recognized by Feeds.
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true, // used by JSON Feed 1.0
+}
+
+// LinksTo returns whether the page links to u with any rel value.  URLs are
+// compared using the loose rules described in RelMeLinksTo.
+func (d *Data) LinksTo(u string) bool {
+	if d == nil {
+		return false
+	}
+	for link := range d.RelURLs {
+		if sameURL(link, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// RelMeLinksTo returns whether the page has a rel=me link to u.  This can be
+// used to perform the bidirectional rel=me verification used by IndieAuth and
+// others, by checking that each page links to the other.
+//
+// URLs are compared after normalization: scheme and host are compared case
+// insensitively, default ports and trailing slashes are ignored, and "http"
+// and "https" URLs, as well as hosts with and without a leading "www.", are
+// considered equivalent.
+func (d *Data) RelMeLinksTo(u string) bool {
+	if d == nil {
+		return false
+	}
+	for _, link := range d.Rels["me"] {
+		if sameURL(link, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// RelURLsByType returns the RelURLs of the URLs linked to with the rel value
+// rel, such as "me", in document order.  RelURLs do not record their URL, but
+// for data returned by Parse, they are in the same order as the URLs of
+// d.Rels[rel].  URLs whose RelURL is missing or does not include rel, which
+// can only happen in data built by hand, are omitted.
+func (d *Data) RelURLsByType(rel string) []*RelURL {
+	if d == nil {
+		return nil
+	}
+	var relURLs []*RelURL
+	for _, u := range d.Rels[rel] {
+		if r := d.RelURLs[u]; r != nil && containsString(r.Rels, rel) {
+			relURLs = append(relURLs, r)
+		}
+	}
+	return relURLs
+}
+
+// Alternates returns the translations of the page advertised by rel=alternate
+// links with an hreflang attribute, mapping each language tag (such as "fr",
+// "pt-BR", or "x-default") to the URL of the translation.  Language tags are
+// used as written in the page.  If more than one URL is advertised for the
+// same language, the last one in document order is used.
+//
+// As with Feeds, languages are taken from RelURLs, so if a URL is linked more
+// than once, only the hreflang of its first link is used.
+func (d *Data) Alternates() map[string]string {
+	alternates := make(map[string]string)
+	if d == nil {
+		return alternates
+	}
+	for _, u := range d.Rels["alternate"] {
+		if rel := d.RelURLs[u]; rel != nil {
+			if lang := strings.TrimSpace(rel.HrefLang); lang != "" {
+				alternates[lang] = u
+			}
+		}
+	}
+	return alternates
+}
+
+// RepresentativeHCard returns the h-card that represents the page at pageURL,
+// such as the author of a personal site, following the representative h-card
+// parsing algorithm.  This is the first h-card on the page (in the order
+// described in FindByProperty) for which:
+//
+//  1. a url and a uid property both match pageURL, or else
+//  2. a url property matches one of the page's rel=me URLs, or else
+//  3. a url property matches pageURL, if it is the only h-card on the page
+//
+// URLs are compared after normalizing their scheme, host, and port, and
+// relative property values are resolved against pageURL.  If pageURL is
+// nil, the page's BaseURL is used.  If no h-card matches, nil is returned.
+//
+// See https://microformats.org/wiki/representative-h-card-parsing
+func (d *Data) RepresentativeHCard(pageURL *url.URL) *Microformat {
+	if d == nil {
+		return nil
+	}
+	if pageURL == nil {
+		pageURL = d.BaseURL
+	}
+	var page string
+	if pageURL != nil {
+		page = normalizeURL(pageURL.String(), false)
+	}
+	relMe := make(map[string]bool)
+	for _, u := range d.Rels["me"] {
+		relMe[normalizeURL(expandURL(u, pageURL), false)] = true
+	}
+
+	var cards []*Microformat
+	d.walkItems(func(m *Microformat) {
+		if m.hasType("h-card") {
+			cards = append(cards, m)
+		}
+	})
+	// matches returns whether any value of prop in m satisfies match
+	matches := func(m *Microformat, prop string, match func(string) bool) bool {
+		for _, u := range allStrings(m, prop) {
+			if match(normalizeURL(expandURL(u, pageURL), false)) {
+				return true
+			}
+		}
+		return false
+	}
+	isPage := func(u string) bool { return page != "" && u == page }
+	isRelMe := func(u string) bool { return relMe[u] }
+
+	for _, card := range cards {
+		if matches(card, "url", isPage) && matches(card, "uid", isPage) {
+			return card
+		}
+	}
+	for _, card := range cards {
+		if matches(card, "url", isRelMe) {
+			return card
+		}
+	}
+	if len(cards) == 1 && matches(cards[0], "url", isPage) {
+		return cards[0]
+	}
+	return nil
+}
+
+// MentionEndpoints returns the Webmention and Pingback endpoints of the page,
+// to which notifications of links to it can be sent.  Either is empty if the
+// page does not advertise one.
+//
+// As specified by Webmention, the webmention endpoint is the first URL of a
+// Link header with rel=webmention, or else the first <link> or <a> element
+// with rel=webmention.  Likewise for Pingback, the X-Pingback header takes
+// precedence over the first rel=pingback link.  Headers are only available
+// when the page was fetched by ParseURL or ParseRequest, which record them in
+// HeaderRels; for pages parsed by other means, only the links in the document
+// are used.  An empty href is a valid endpoint, referring to the page
+// itself, and so resolves to the base URL.
+func (d *Data) MentionEndpoints() (webmention, pingback string) {
+	if d == nil {
+		return "", ""
+	}
+	first := func(rel string) string {
+		if urls := d.HeaderRels[rel]; len(urls) > 0 {
+			return urls[0]
+		}
+		if urls := d.Rels[rel]; len(urls) > 0 {
+			return urls[0]
+		}
+		return ""
+	}
+	return first("webmention"), first("pingback")
+}
+
+// Feeds returns the feeds advertised by the page, in document order, for
+// feed autodiscovery.  A feed is a rel=alternate link whose type is the media
+// type of an RSS, Atom, or JSON Feed document.  Media type parameters (such
+// as "charset") are ignored, and the Type of the returned FeedLink omits
+// them.
+//
+// Titles and types are taken from RelURLs, so if a URL is linked more than
+// once, only the attributes of its first link are used.
+func (d *Data) Feeds() []FeedLink {
+	if d == nil {
+		return nil
+	}
+	var feeds []FeedLink
+	for _, u := range d.Rels["alternate"] {
+		rel := d.RelURLs[u]
+		if rel == nil {
+			continue
+		}
+		mediatype, _, err := mime.ParseMediaType(rel.Type)
+		if err != nil || !feedTypes[mediatype] {
+			continue
+		}
+		feeds = append(feeds, FeedLink{URL: u, Title: rel.Title, Type: mediatype})
+	}
+	return feeds
+}
+
+// Icons returns the icons advertised by the page, for displaying alongside
+// links to it.  These are the rel=icon links (including the legacy "shortcut
+// icon"), in document order, followed by the rel=apple-touch-icon links.
+// Each URL is only returned once, even if it is linked more than once.
+//
+// As with Feeds, sizes and types are taken from RelURLs, so only the
+// attributes of the first link to each URL are used.
+func (d *Data) Icons() []Icon {
+	if d == nil {
+		return nil
+	}
+	var icons []Icon
+	seen := make(map[string]bool)
+	for _, relval := range iconRels {
+		for _, u := range d.Rels[relval] {
+			if seen[u] {
+				continue
+			}
+			seen[u] = true
+			icon := Icon{URL: u}
+			if rel := d.RelURLs[u]; rel != nil {
+				icon.Sizes = rel.Sizes
+				icon.Type = rel.Type
+			}
+			icons = append(icons, icon)
+		}
+	}
+	return icons
+}
+
+// Canonical returns the page's rel=canonical URL, resolved against its base
+// URL, or an empty string if it has none.  If the page has more than one, the
+// first is used.  This is the same as CanonicalURL(nil), and is available
+// whether or not the page has any microformats, unless rel=canonical is
+// excluded by WithRels.
+func (d *Data) Canonical() string {
+	return d.CanonicalURL(nil)
+}
+
+// CanonicalURL returns the canonical URL of entry, a microformat found on the
+// page, for identifying it across syndicated copies.  This is the first url
+// property of entry, or else its first uid property, or else the page's
+// rel=canonical URL.  If entry is nil, the page's rel=canonical URL is
+// returned.  Property values are absolute if the page was parsed with a base
+// URL; otherwise, a relative value is resolved against the rel=canonical URL
+// when there is one.  If no canonical URL is found, an empty string is
+// returned.
+func (d *Data) CanonicalURL(entry *Microformat) string {
+	var canonical string
+	if d != nil && len(d.Rels["canonical"]) > 0 {
+		canonical = d.Rels["canonical"][0]
+	}
+	if entry != nil {
+		for _, prop := range []string{"url", "uid"} {
+			if u := firstString(entry, prop); u != "" {
+				if base, err := url.Parse(canonical); err == nil && base.IsAbs() {
+					return expandURL(u, base)
+				}
+				return u
+			}
+		}
+	}
+	return canonical
+}
+
+// SyndicationLinks returns the URLs of syndicated copies of entry, such as
+// posts on social media sites, for backfeed.  These are the syndication
+// property values of entry, followed by the page's rel=syndication URLs.
+// Relative URLs are resolved against the page's BaseURL, and duplicates are
+// removed, comparing URLs after normalizing their scheme, host, and port.
+// entry may be nil to only return rel=syndication URLs.
+func (d *Data) SyndicationLinks(entry *Microformat) []string {
+	var candidates []string
+	if entry != nil {
+		candidates = append(candidates, allStrings(entry, "syndication")...)
+	}
+	var base *url.URL
+	if d != nil {
+		candidates = append(candidates, d.Rels["syndication"]...)
+		base = d.BaseURL
+	}
+
+	var links []string
+	seen := make(map[string]bool)
+	for _, u := range candidates {
+		u = expandURL(u, base)
+		if key := normalizeURL(u, false); !seen[key] {
+			seen[key] = true
+			links = append(links, u)
+		}
+	}
+	return links
+}
+
+// normalizeURL normalizes s by lowercasing the scheme and host, removing
+// default ports, and using "/" for an empty path.  If collapseSlash is true,
+// any trailing slash is removed from non-root paths.  If s cannot be parsed
+// as a URL, it is returned unchanged.
+func normalizeURL(s string, collapseSlash bool) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Opaque != "" {
+		return s
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); port == "80" && u.Scheme == "http" || port == "443" && u.Scheme == "https" {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	if u.Host != "" && u.Path == "" {
+		u.Path = "/"
+	}
+	if collapseSlash && len(u.Path) > 1 {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	}
+	return u.String()
+}
+
+// sameURL returns whether a and b identify the same resource, using the
+// loose comparison described in RelMeLinksTo.
+func sameURL(a, b string) bool {
+	return looseURL(a) == looseURL(b)
+}
+
+// looseURL normalizes s for loose comparison, ignoring the difference between
+// http and https, and a leading "www." in the host.
+func looseURL(s string) string {
+	s = normalizeURL(s, true)
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		if u.Scheme == "https" {
+			u.Scheme = "http"
+		}
+		u.Host = strings.TrimPrefix(u.Host, "www.")
+		s = u.String()
+	}
+	return s
+}
+
+// WithRels only collects the specified rel values into Data.Rels and
+// Data.RelURLs, such as "me", "author", and "webmention", skipping all
+// others.  The Rels field of each RelURL only includes the specified values,
+// and URLs whose links have none of them are omitted.  Rel values used by
+// microformats v1 properties (such as rel=tag) are still parsed as
+// properties.  By default, all rel values are collected.
+func WithRels(rels ...string) Option {
+	return func(p *parser) {
+		p.relFilter = make(map[string]bool, len(rels))
+		for _, rel := range rels {
+			p.relFilter[rel] = true
+		}
+	}
+}
+
+// WithNormalizedRelURLs normalizes rel URLs before storing them in Data.Rels
+// and Data.RelURLs, so that URLs that differ only in spelling are stored
+// once, and can be compared reliably.  The scheme and host are lowercased,
+// default ports are removed, and an empty path is replaced with "/".  If
+// collapseSlash is true, any trailing slash is also removed from non-root
+// paths, which most sites treat as the same URL, though not all.  By default,
+// rel URLs are stored as authored, after resolving them against the base
+// URL.
+func WithNormalizedRelURLs(collapseSlash bool) Option {
+	return func(p *parser) {
+		p.normalizeRels = true
+		p.collapseRelSlash = collapseSlash
+	}
+}
+
+// collectedRels returns the values in rels that are collected, as configured
+// by WithRels.  The returned slice does not share memory with rels.
+func (p *parser) collectedRels(rels []string) []string {
+	var collected []string
+	for _, rel := range rels {
+		if p.relFilter == nil || p.relFilter[rel] {
+			collected = append(collected, rel)
+		}
+	}
+	return collected
+}
@@ -0,0 +1,551 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_NormalizeURL(t *testing.T) {
+	tests := []struct {
+		url           string
+		collapseSlash bool
+		want          string
+	}{
+		{"", false, ""},
+		{"HTTP://Example.COM", false, "http://example.com/"},
+		{"http://example.com:80/", false, "http://example.com/"},
+		{"https://example.com:443/", false, "https://example.com/"},
+		{"https://example.com:80/", false, "https://example.com:80/"},
+		{"http://example.com:8080/", false, "http://example.com:8080/"},
+		{"http://example.com/Path/", false, "http://example.com/Path/"},
+		{"http://example.com/Path/", true, "http://example.com/Path"},
+		{"http://example.com/", true, "http://example.com/"},
+		{"mailto:Jane@Example.com", true, "mailto:Jane@Example.com"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeURL(tt.url, tt.collapseSlash); got != tt.want {
+			t.Errorf("normalizeURL(%q, %t) returned %q, want %q", tt.url, tt.collapseSlash, got, tt.want)
+		}
+	}
+}
+
+func Test_SameURL(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"http://example.com/", "http://example.com/", true},
+		{"http://example.com", "http://example.com/", true},
+		{"http://example.com/", "https://example.com/", true},
+		{"http://www.example.com/", "https://example.com", true},
+		{"HTTPS://WWW.Example.com:443/", "http://example.com", true},
+		{"http://example.com/me/", "http://example.com/me", true},
+
+		{"http://example.com/", "http://example.org/", false},
+		{"http://example.com/me", "http://example.com/Me", false},
+		{"http://example.com/", "ftp://example.com/", false},
+		{"http://blog.example.com/", "http://example.com/", false},
+	}
+
+	for _, tt := range tests {
+		if got := sameURL(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameURL(%q, %q) returned %t, want %t", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func Test_RelMeLinksTo(t *testing.T) {
+	doc := `<a rel="me" href="https://github.com/jane/">GitHub</a>
+		<link rel="me authorization_endpoint" href="http://www.example.com">
+		<a rel="author" href="https://example.org/">Author</a>`
+	data := Parse(strings.NewReader(doc), nil)
+
+	tests := []struct {
+		url         string
+		me, linksTo bool
+	}{
+		{"https://github.com/jane", true, true},
+		{"http://github.com/jane/", true, true},
+		{"https://example.com/", true, true},
+		{"https://example.org/", false, true},
+		{"https://github.com/john", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := data.RelMeLinksTo(tt.url); got != tt.me {
+			t.Errorf("RelMeLinksTo(%q) returned %t, want %t", tt.url, got, tt.me)
+		}
+		if got := data.LinksTo(tt.url); got != tt.linksTo {
+			t.Errorf("LinksTo(%q) returned %t, want %t", tt.url, got, tt.linksTo)
+		}
+	}
+
+	var nilData *Data
+	if nilData.RelMeLinksTo("https://example.com/") || nilData.LinksTo("https://example.com/") {
+		t.Errorf("nil Data reported a link")
+	}
+}
+
+func Test_RelURLsByType(t *testing.T) {
+	doc := `<a rel="me" href="https://social.example/@jane">social</a>
+		<a rel="me author" href="/about">about</a>
+		<a rel="author" href="/jane">Jane</a>
+		<link rel="me" href="https://code.example/jane" title="code">
+		<a rel="author me" href="/about">again</a>`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	tests := []struct {
+		rel  string
+		want []*RelURL
+	}{
+		{"me", []*RelURL{
+			{Rels: []string{"me"}, Text: "social"},
+			{Rels: []string{"author", "me"}, Text: "about"},
+			{Rels: []string{"me"}, Title: "code"},
+		}},
+		{"author", []*RelURL{
+			{Rels: []string{"author", "me"}, Text: "about"},
+			{Rels: []string{"author"}, Text: "Jane"},
+		}},
+		{"webmention", nil},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, data.RelURLsByType(tt.rel)); diff != "" {
+			t.Errorf("RelURLsByType(%q) mismatch (-want +got):\n%s", tt.rel, diff)
+		}
+	}
+
+	// shared URLs are the same RelURL
+	if me, author := data.RelURLsByType("me")[1], data.RelURLsByType("author")[0]; me != author {
+		t.Errorf("RelURLsByType returned different RelURLs for the same URL")
+	}
+}
+
+func Test_Feeds(t *testing.T) {
+	doc := `<link rel="alternate" type="application/rss+xml" title="RSS" href="/feed.rss">
+		<link rel="alternate" type="application/atom+xml; charset=utf-8" title="Atom" href="/feed.atom">
+		<link rel="alternate" type="application/feed+json" href="/feed.json">
+		<link rel="alternate" type="Application/JSON" title="Old JSON Feed" href="/feed-v1.json">
+		<link rel="alternate" hreflang="fr" href="/fr/">
+		<link rel="alternate" type="text/html" href="/other">
+		<link rel="feed" type="application/rss+xml" href="/not-alternate.rss">`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	want := []FeedLink{
+		{URL: "http://example.com/feed.rss", Title: "RSS", Type: "application/rss+xml"},
+		{URL: "http://example.com/feed.atom", Title: "Atom", Type: "application/atom+xml"},
+		{URL: "http://example.com/feed.json", Type: "application/feed+json"},
+		{URL: "http://example.com/feed-v1.json", Title: "Old JSON Feed", Type: "application/json"},
+	}
+	if diff := cmp.Diff(want, data.Feeds()); diff != "" {
+		t.Errorf("Feeds mismatch (-want +got):\n%s", diff)
+	}
+
+	var nilData *Data
+	if got := nilData.Feeds(); got != nil {
+		t.Errorf("Feeds on nil Data returned %v, want nil", got)
+	}
+}
+
+func Test_Icons(t *testing.T) {
+	doc := `<link rel="apple-touch-icon" sizes="180x180" href="/apple-touch-icon.png">
+		<link rel="icon" type="image/png" sizes="32x32" href="/favicon-32x32.png">
+		<link rel="icon" type="image/png" sizes="16x16" href="/favicon-16x16.png">
+		<link rel="icon" type="image/svg+xml" sizes="any" href="/icon.svg">
+		<link rel="shortcut icon" href="/favicon.ico">
+		<link rel="apple-touch-icon icon" href="/both.png">
+		<link rel="stylesheet" href="/style.css">`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	want := []Icon{
+		{URL: "http://example.com/favicon-32x32.png", Sizes: "32x32", Type: "image/png"},
+		{URL: "http://example.com/favicon-16x16.png", Sizes: "16x16", Type: "image/png"},
+		{URL: "http://example.com/icon.svg", Sizes: "any", Type: "image/svg+xml"},
+		{URL: "http://example.com/favicon.ico"},
+		{URL: "http://example.com/both.png"},
+		{URL: "http://example.com/apple-touch-icon.png", Sizes: "180x180"},
+	}
+	if diff := cmp.Diff(want, data.Icons()); diff != "" {
+		t.Errorf("Icons mismatch (-want +got):\n%s", diff)
+	}
+
+	var nilData *Data
+	if got := nilData.Icons(); got != nil {
+		t.Errorf("Icons on nil Data returned %v, want nil", got)
+	}
+}
+
+func Test_WithRels(t *testing.T) {
+	doc := `<link rel="stylesheet" href="/style.css">
+		<link rel="preconnect" href="https://cdn.example/">
+		<link rel="webmention" href="/webmention">
+		<a rel="me nofollow" href="https://social.example/@jane">Jane</a>
+		<div class="hentry"><a rel="tag" href="/tags/go">go</a></div>`
+	base, _ := url.Parse("http://example.com/")
+
+	data := Parse(strings.NewReader(doc), base, WithRels("me", "webmention", "author"))
+	wantRels := map[string][]string{
+		"me":         {"https://social.example/@jane"},
+		"webmention": {"http://example.com/webmention"},
+	}
+	if diff := cmp.Diff(wantRels, data.Rels); diff != "" {
+		t.Errorf("Rels mismatch (-want +got):\n%s", diff)
+	}
+	wantRelURLs := map[string]*RelURL{
+		"https://social.example/@jane":  {Rels: []string{"me"}, Text: "Jane"},
+		"http://example.com/webmention": {Rels: []string{"webmention"}},
+	}
+	if diff := cmp.Diff(wantRelURLs, data.RelURLs); diff != "" {
+		t.Errorf("RelURLs mismatch (-want +got):\n%s", diff)
+	}
+
+	// v1 rel properties are still parsed
+	if got, want := data.Items[0].Properties["category"], []any{"go"}; !cmp.Equal(got, want) {
+		t.Errorf("category property returned %v, want %v", got, want)
+	}
+
+	// all rels are collected by default
+	if got := len(Parse(strings.NewReader(doc), base).Rels); got != 6 {
+		t.Errorf("Parse without WithRels returned %d rels, want 6", got)
+	}
+}
+
+func Test_WithNormalizedRelURLs(t *testing.T) {
+	doc := `<a rel="me" href="HTTPS://Social.Example:443/@jane/">Jane</a>
+		<a rel="me" href="https://social.example/@jane">Jane</a>
+		<a rel="me" href="http://Jane.Example:80">home</a>
+		<a rel="me" href="https://jane.example:8443/">alt</a>
+		<link rel="webmention" href="/webmention/">`
+	base, _ := url.Parse("http://example.com/")
+
+	tests := []struct {
+		opts []Option
+		want map[string][]string
+	}{
+		{
+			nil,
+			map[string][]string{
+				"me": {
+					"https://Social.Example:443/@jane/",
+					"https://social.example/@jane",
+					"http://Jane.Example:80",
+					"https://jane.example:8443/",
+				},
+				"webmention": {"http://example.com/webmention/"},
+			},
+		},
+		{
+			[]Option{WithNormalizedRelURLs(false)},
+			map[string][]string{
+				"me": {
+					"https://social.example/@jane/",
+					"https://social.example/@jane",
+					"http://jane.example/",
+					"https://jane.example:8443/",
+				},
+				"webmention": {"http://example.com/webmention/"},
+			},
+		},
+		{
+			[]Option{WithNormalizedRelURLs(true)},
+			map[string][]string{
+				"me": {
+					"https://social.example/@jane",
+					"http://jane.example/",
+					"https://jane.example:8443/",
+				},
+				"webmention": {"http://example.com/webmention"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(doc), base, tt.opts...)
+		if diff := cmp.Diff(tt.want, data.Rels); diff != "" {
+			t.Errorf("Parse with %d options rels mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+		for _, urls := range tt.want {
+			for _, u := range urls {
+				if data.RelURLs[u] == nil {
+					t.Errorf("Parse with %d options returned no RelURL for %q", len(tt.opts), u)
+				}
+			}
+		}
+	}
+}
+
+func Test_MentionEndpoints(t *testing.T) {
+	tests := []struct {
+		html                 string
+		webmention, pingback string
+	}{
+		{`<p>no endpoints</p>`, "", ""},
+		{
+			`<a rel="webmention" href="/first">first</a><link rel="webmention" href="/second">
+			<link rel="pingback" href="/xmlrpc"><link rel="pingback" href="/other">`,
+			"http://example.com/first", "http://example.com/xmlrpc",
+		},
+		{`<link rel="webmention" href="">`, "http://example.com/page", ""},
+		{`<link rel="nofollow webmention" href="wm">`, "http://example.com/wm", ""},
+	}
+	base, _ := url.Parse("http://example.com/page")
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(tt.html), base)
+		webmention, pingback := data.MentionEndpoints()
+		if webmention != tt.webmention || pingback != tt.pingback {
+			t.Errorf("MentionEndpoints of %q returned %q, %q, want %q, %q", tt.html, webmention, pingback, tt.webmention, tt.pingback)
+		}
+	}
+
+	var d *Data
+	if webmention, pingback := d.MentionEndpoints(); webmention != "" || pingback != "" {
+		t.Errorf("MentionEndpoints of nil Data returned %q, %q, want empty", webmention, pingback)
+	}
+}
+
+func Test_CanonicalURL(t *testing.T) {
+	base, _ := url.Parse("http://example.com/")
+	tests := []struct {
+		html string
+		base *url.URL
+		want string
+	}{
+		{`<div class="h-entry"><a class="u-url" href="/post">p</a><a class="u-uid" href="/uid">u</a></div>
+			<link rel="canonical" href="/canonical">`, base, "http://example.com/post"},
+		{`<div class="h-entry"><span class="p-name">n</span><a class="u-uid" href="/uid">u</a></div>
+			<link rel="canonical" href="/canonical">`, base, "http://example.com/uid"},
+		{`<div class="h-entry"><span class="p-name">n</span></div>
+			<link rel="canonical" href="/canonical">`, base, "http://example.com/canonical"},
+		{`<div class="h-entry"><span class="p-name">n</span></div>`, base, ""},
+
+		// relative values are resolved against rel=canonical
+		{`<div class="h-entry"><a class="u-url" href="post">p</a></div>
+			<link rel="canonical" href="https://example.org/a/">`, nil, "https://example.org/post"},
+		{`<div class="h-entry"><a class="u-url" href="post">p</a></div>`, nil, "/post"},
+	}
+
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(tt.html), tt.base)
+		if got := data.CanonicalURL(data.Items[0]); got != tt.want {
+			t.Errorf("CanonicalURL(%q) returned %q, want %q", tt.html, got, tt.want)
+		}
+	}
+
+	data := Parse(strings.NewReader(`<link rel="canonical" href="/canonical">`), base)
+	if got, want := data.CanonicalURL(nil), "http://example.com/canonical"; got != want {
+		t.Errorf("CanonicalURL(nil) returned %q, want %q", got, want)
+	}
+}
+
+func Test_Canonical(t *testing.T) {
+	base, _ := url.Parse("http://example.com/post?utm_source=feed")
+	tests := []struct {
+		html string
+		opts []Option
+		want string
+	}{
+		{`<link rel="canonical" href="/post">`, nil, "http://example.com/post"},
+		{`<link rel="canonical" href="/first"><link rel="alternate canonical" href="/second">`, nil, "http://example.com/first"},
+		{`<base href="https://example.org/"><link rel="canonical" href="post">`, nil, "https://example.org/post"},
+		{`<a rel="author" href="/jane">Jane</a>`, nil, ""},
+		{`<link rel="canonical" href="/post">`, []Option{WithRels("me")}, ""},
+	}
+
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(tt.html), base, tt.opts...)
+		if got := data.Canonical(); got != tt.want {
+			t.Errorf("Canonical(%q) returned %q, want %q", tt.html, got, tt.want)
+		}
+	}
+	if got := (*Data)(nil).Canonical(); got != "" {
+		t.Errorf("Canonical on nil Data returned %q, want empty string", got)
+	}
+}
+
+func Test_SyndicationLinks(t *testing.T) {
+	doc := `<div class="h-entry">
+		<a class="u-syndication" href="https://social.example/@jane/1">social</a>
+		<a class="u-syndication" href="/copy">copy</a>
+	</div>
+	<a rel="syndication" href="HTTPS://Social.Example:443/@jane/1">dup</a>
+	<a rel="syndication" href="https://news.example/item/2">news</a>`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	want := []string{
+		"https://social.example/@jane/1",
+		"http://example.com/copy",
+		"https://news.example/item/2",
+	}
+	if got := data.SyndicationLinks(data.Items[0]); !cmp.Equal(got, want) {
+		t.Errorf("SyndicationLinks returned %q, want %q", got, want)
+	}
+
+	want = []string{"https://Social.Example:443/@jane/1", "https://news.example/item/2"}
+	if got := data.SyndicationLinks(nil); !cmp.Equal(got, want) {
+		t.Errorf("SyndicationLinks(nil) returned %q, want %q", got, want)
+	}
+
+	// relative values in data built by hand are resolved against BaseURL
+	entry := &Microformat{Properties: map[string][]any{"syndication": {"/a", "http://example.com/a"}}}
+	data = &Data{BaseURL: base}
+	if got, want := data.SyndicationLinks(entry), []string{"http://example.com/a"}; !cmp.Equal(got, want) {
+		t.Errorf("SyndicationLinks returned %q, want %q", got, want)
+	}
+}
+
+func Test_Parse_DuplicateRels(t *testing.T) {
+	doc := `<a rel="me" href="/a">a</a><a rel="me author" href="/a">again</a><a rel="me" href="/b">b</a><a rel=" " href="/c">c</a>`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	want := map[string][]string{
+		"me":     {"http://example.com/a", "http://example.com/b"},
+		"author": {"http://example.com/a"},
+	}
+	if diff := cmp.Diff(want, data.Rels); diff != "" {
+		t.Errorf("Rels mismatch (-want +got):\n%s", diff)
+	}
+	if _, ok := data.RelURLs["http://example.com/c"]; ok {
+		t.Errorf("RelURLs includes link with empty rel")
+	}
+}
+
+func Test_Parse_MergedRelURLs_Order(t *testing.T) {
+	tests := []struct {
+		html string
+		want []string
+	}{
+		{`<a rel="me" href="/a">a</a><a rel="author" href="/a">a</a>`, []string{"author", "me"}},
+		{`<a rel="me" href="/a">a</a><a rel="me" href="/a">a</a>`, []string{"me"}},
+		{`<a rel="me" href="/a">a</a><a rel="me author" href="/b">b</a><a rel="author me" href="/a">a</a>`, []string{"author", "me"}},
+	}
+
+	base, _ := url.Parse("http://example.com/")
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(tt.html), base)
+		if diff := cmp.Diff(tt.want, data.RelURLs["http://example.com/a"].Rels); diff != "" {
+			t.Errorf("Parse(%q) rels mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_Parse_MergedRelURLs(t *testing.T) {
+	// the rels of each link to a URL are merged into its rel-urls entry,
+	// sorted, while other metadata comes from the first link.  See
+	// http://microformats.org/wiki/microformats2-parsing#parse_a_hyperlink_element_for_rel_microformats
+	doc := `<a rel="me" href="/jane" title="first">Jane</a><link rel="author me" href="/jane" title="second">`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	want := &RelURL{Rels: []string{"author", "me"}, Text: "Jane", Title: "first"}
+	if diff := cmp.Diff(want, data.RelURLs["http://example.com/jane"]); diff != "" {
+		t.Errorf("RelURLs mismatch (-want +got):\n%s", diff)
+	}
+	if err := ValidateSchema(data); err != nil {
+		t.Errorf("ValidateSchema returned error: %v", err)
+	}
+}
+
+func Test_RepresentativeHCard(t *testing.T) {
+	page, _ := url.Parse("https://example.com/")
+	tests := []struct {
+		name string
+		html string
+		want string // name of the representative h-card, if any
+	}{
+		{
+			"url and uid match page",
+			`<div class="h-card"><a class="u-url p-name" href="/">Other</a></div>
+			<div class="h-card"><a class="u-url u-uid p-name" href="https://EXAMPLE.com">Jane</a></div>`,
+			"Jane",
+		},
+		{
+			"url matches rel=me",
+			`<div class="h-entry"><div class="p-author h-card"><a class="u-url p-name" href="https://social.example/@jane">Jane</a></div></div>
+			<div class="h-card"><a class="u-url p-name" href="/">John</a></div>
+			<a rel="me" href="https://social.example/@jane">me</a>`,
+			"Jane",
+		},
+		{
+			"uid match takes priority over rel=me",
+			`<div class="h-card"><a class="u-url p-name" href="https://social.example/@jane">Jane</a></div>
+			<div class="h-card"><a class="u-url u-uid p-name" href="/">John</a></div>
+			<a rel="me" href="https://social.example/@jane">me</a>`,
+			"John",
+		},
+		{
+			"single h-card with url matching page",
+			`<div class="h-card"><a class="u-url p-name" href="/">Jane</a></div>`,
+			"Jane",
+		},
+		{
+			"single h-card with other url",
+			`<div class="h-card"><a class="u-url p-name" href="/about">Jane</a></div>`,
+			"",
+		},
+		{
+			"several h-cards with url matching page",
+			`<div class="h-card"><a class="u-url p-name" href="/">Jane</a></div>
+			<div class="h-card"><a class="u-url p-name" href="/">John</a></div>`,
+			"",
+		},
+		{"no h-cards", `<div class="h-entry"><a class="u-url" href="/">Post</a></div>`, ""},
+	}
+
+	for _, tt := range tests {
+		data := Parse(strings.NewReader(tt.html), page)
+		var got string
+		if card := data.RepresentativeHCard(nil); card != nil {
+			got = firstString(card, "name")
+		}
+		if got != tt.want {
+			t.Errorf("RepresentativeHCard for %s returned %q, want %q", tt.name, got, tt.want)
+		}
+		if card := data.RepresentativeHCard(page); (card != nil) != (tt.want != "") {
+			t.Errorf("RepresentativeHCard(%q) for %s returned %v", page, tt.name, card)
+		}
+	}
+
+	var nilData *Data
+	if got := nilData.RepresentativeHCard(page); got != nil {
+		t.Errorf("RepresentativeHCard on nil Data returned %v, want nil", got)
+	}
+}
+
+func Test_Alternates(t *testing.T) {
+	doc := `<link rel="alternate" hreflang="fr" href="/fr/">
+		<link rel="alternate" hreflang="pt-BR" href="/pt-br/">
+		<link rel="alternate" hreflang="x-default" href="/">
+		<link rel="alternate" type="application/rss+xml" href="/feed.rss">
+		<link rel="alternate" hreflang="fr" href="/fr-v2/">
+		<link rel="canonical" hreflang="de" href="/de/">
+		<a rel="alternate" hreflang=" es " href="/es/">Español</a>`
+	base, _ := url.Parse("https://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	want := map[string]string{
+		"fr":        "https://example.com/fr-v2/",
+		"pt-BR":     "https://example.com/pt-br/",
+		"x-default": "https://example.com/",
+		"es":        "https://example.com/es/",
+	}
+	if diff := cmp.Diff(want, data.Alternates()); diff != "" {
+		t.Errorf("Alternates mismatch (-want +got):\n%s", diff)
+	}
+
+	var nilData *Data
+	if got := nilData.Alternates(); len(got) != 0 {
+		t.Errorf("Alternates on nil Data returned %v, want empty map", got)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for summarizing parsed microformats.
+
+package microformats
+
+import (
+	"strings"
+	"time"
+)
+
+// ItemSummary is a flattened summary of a top-level microformat, such as for
+// a search index.  Fields that don't apply to the microformat, or that it
+// has no value for, are empty.
+type ItemSummary struct {
+	// Type is the vocabulary the summary was made for: "h-entry", "h-card",
+	// or "h-event" if the microformat has one of those types, or else its
+	// PrimaryType.
+	Type string
+
+	Name string
+	URL  string
+
+	// Published is the publish time of entries and other microformats, or
+	// the start time of events.  Times without a timezone offset are in UTC.
+	Published time.Time
+
+	// Summary is the summary or content of entries, the summary or
+	// description of events, and the note of cards.  Runs of whitespace are
+	// collapsed to a single space.
+	Summary string
+
+	// Author is the display name of the author, as returned by
+	// HCard.DisplayName.
+	Author string
+
+	// Photo is the URL of the first photo.
+	Photo string
+}
+
+// summaryTypes are the types that Summaries selects fields for, in order of
+// preference for microformats with more than one of them.
+var summaryTypes = []string{"h-entry", "h-event", "h-card"}
+
+// Summaries returns an ItemSummary for each top-level item of d, in order.
+// Fields are selected by vocabulary: entries are summarized by their name
+// and content, events by their name and start, and cards by their display
+// name and url, while other microformats use the same fields as entries.
+// Summaries are lossy by design, and not suitable for processing the items
+// themselves.
+func (d *Data) Summaries() []ItemSummary {
+	if d == nil {
+		return nil
+	}
+	var summaries []ItemSummary
+	for _, m := range d.Items {
+		if m != nil {
+			summaries = append(summaries, summarize(m))
+		}
+	}
+	return summaries
+}
+
+// summarize returns the ItemSummary of m, as described in Summaries.
+func summarize(m *Microformat) ItemSummary {
+	s := ItemSummary{
+		Type:   m.PrimaryType(),
+		Name:   firstString(m, "name"),
+		URL:    firstString(m, "url"),
+		Author: author(m).DisplayName(),
+	}
+	for _, t := range summaryTypes {
+		if m.hasType(t) {
+			s.Type = t
+			break
+		}
+	}
+	if photos := m.Photos(); len(photos) > 0 {
+		s.Photo = photos[0].URL
+	}
+
+	switch s.Type {
+	case "h-card":
+		s.Name = hcard(m).DisplayName()
+		s.Summary = firstString(m, "note")
+	case "h-event":
+		s.Published, _ = timeIn(m, "start", nil)
+		s.Summary = firstString(m, "summary")
+		if s.Summary == "" {
+			s.Summary = firstString(m, "description")
+		}
+	default:
+		s.Published, _ = timeIn(m, "published", nil)
+		s.Summary = m.Preview(0)
+	}
+	s.Summary = strings.Join(strings.Fields(s.Summary), " ")
+	return s
+}
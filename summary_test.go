@@ -0,0 +1,73 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Summaries(t *testing.T) {
+	doc := `<article class="h-entry h-as-note">
+		<h1 class="p-name">Hello</h1>
+		<a class="u-url" href="/hello"><time class="dt-published" datetime="2024-01-02T15:04:05-05:00">Jan 2</time></a>
+		<div class="p-author h-card"><a class="p-name u-url" href="/">Jane</a><img class="u-photo" src="/jane.jpg"></div>
+		<img class="u-photo" src="/photo.jpg" alt="A photo">
+		<div class="e-content"><p>Hello,
+			world</p></div>
+	</article>
+	<div class="h-card"><a class="p-nickname u-url" href="https://jane.example/">jj</a>
+		<p class="p-note">Writes   code.</p>
+	</div>
+	<div class="h-event"><span class="p-name">Meetup</span>
+		<time class="dt-start" datetime="2024-03-04 18:00">March 4</time>
+		<p class="p-description">Monthly meetup</p>
+	</div>
+	<div class="h-review"><span class="p-name">Great</span><time class="dt-published" datetime="2024-05-06">May 6</time>
+		<span class="p-author">Bob</span></div>`
+	base, _ := url.Parse("http://example.com/")
+	data := Parse(strings.NewReader(doc), base)
+
+	want := []ItemSummary{
+		{
+			Type:      "h-entry",
+			Name:      "Hello",
+			URL:       "http://example.com/hello",
+			Published: time.Date(2024, 1, 2, 15, 4, 5, 0, time.FixedZone("", -5*60*60)),
+			Summary:   "Hello, world",
+			Author:    "Jane",
+			Photo:     "http://example.com/photo.jpg",
+		},
+		{
+			Type:    "h-card",
+			Name:    "jj",
+			URL:     "https://jane.example/",
+			Summary: "Writes code.",
+		},
+		{
+			Type:      "h-event",
+			Name:      "Meetup",
+			Published: time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC),
+			Summary:   "Monthly meetup",
+		},
+		{
+			Type:      "h-review",
+			Name:      "Great",
+			Published: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC),
+			Author:    "Bob",
+		},
+	}
+	got := data.Summaries()
+	if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) })); diff != "" {
+		t.Errorf("Summaries mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := (*Data)(nil).Summaries(); got != nil {
+		t.Errorf("Summaries on nil Data returned %v, want nil", got)
+	}
+}
@@ -6,17 +6,12 @@
 package microformats_test
 
 import (
-	"bytes"
-	"encoding/json"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
-	"github.com/google/go-cmp/cmp"
-	"github.com/kylelemons/godebug/pretty"
-	"willnorris.com/go/microformats"
+	"willnorris.com/go/microformats/testutil"
 )
 
 // skip the tests which we don't pass yet
@@ -80,33 +75,7 @@ func listTests(root string) ([]string, error) {
 }
 
 func runTest(t *testing.T, test string) {
-	input, err := os.ReadFile(test + ".html")
-	if err != nil {
-		t.Fatalf("error reading file %q: %v", test+".html", err)
-	}
-
-	URL, _ := url.Parse("http://example.com/")
-	data := microformats.Parse(bytes.NewReader(input), URL)
-
-	expectedJSON, err := os.ReadFile(test + ".json")
-	if err != nil {
-		t.Fatalf("error reading file %q: %v", test+".json", err)
-	}
-
-	want := make(map[string]any)
-	err = json.Unmarshal(expectedJSON, &want)
-	if err != nil {
-		t.Fatalf("error unmarshaling json in file %q: %v", test+".json", err)
-	}
-
-	outputJSON, _ := json.Marshal(data)
-	got := make(map[string]any)
-	err = json.Unmarshal(outputJSON, &got)
-	if err != nil {
-		t.Fatalf("error unmarshaling json: %v", err)
-	}
-
-	if !cmp.Equal(got, want) {
-		t.Fatalf("Parse value differs:\n%v", pretty.Compare(want, got))
+	if ok, diff := testutil.CompareToFixture(test+".html", test+".json"); !ok {
+		t.Fatalf("Parse value differs:\n%v", diff)
 	}
 }
@@ -0,0 +1,64 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// Package testutil provides helpers for testing microformats parsing against
+// fixtures, in the format of the shared test suite at
+// https://github.com/microformats/tests.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kylelemons/godebug/pretty"
+	"willnorris.com/go/microformats"
+)
+
+// FixtureBaseURL is the base URL that fixture documents are parsed with, as
+// in the shared test suite.
+const FixtureBaseURL = "http://example.com/"
+
+// CompareToFixture parses the HTML document at htmlPath with a base URL of
+// FixtureBaseURL, and compares the result to the expected canonical JSON in the
+// file at jsonPath.  The results are compared as JSON values, so formatting
+// and the order of object members do not matter.
+//
+// CompareToFixture returns whether the result matches.  If it does not, the
+// returned string describes the differences between the expected and actual
+// results, or the error if either file could not be read or the expected JSON
+// is invalid.
+func CompareToFixture(htmlPath, jsonPath string) (bool, string) {
+	input, err := os.ReadFile(htmlPath)
+	if err != nil {
+		return false, fmt.Sprintf("error reading file %q: %v", htmlPath, err)
+	}
+	expected, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return false, fmt.Sprintf("error reading file %q: %v", jsonPath, err)
+	}
+
+	base, _ := url.Parse(FixtureBaseURL)
+	data := microformats.Parse(bytes.NewReader(input), base)
+
+	want := make(map[string]any)
+	if err := json.Unmarshal(expected, &want); err != nil {
+		return false, fmt.Sprintf("error unmarshaling json in file %q: %v", jsonPath, err)
+	}
+	output, err := json.Marshal(data)
+	if err != nil {
+		return false, fmt.Sprintf("error marshaling json: %v", err)
+	}
+	got := make(map[string]any)
+	if err := json.Unmarshal(output, &got); err != nil {
+		return false, fmt.Sprintf("error unmarshaling json: %v", err)
+	}
+
+	if !cmp.Equal(got, want) {
+		return false, pretty.Compare(want, got)
+	}
+	return true, ""
+}
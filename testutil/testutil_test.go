@@ -0,0 +1,48 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_CompareToFixture(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("error writing %q: %v", path, err)
+		}
+		return path
+	}
+
+	html := write("card.html", `<a class="h-card" href="/jane">Jane &amp; Co</a>`)
+	match := write("match.json", `{
+		"rels": {}, "rel-urls": {},
+		"items": [{"type": ["h-card"], "properties": {"url": ["http://example.com/jane"], "name": ["Jane & Co"]}}]
+	}`)
+	differ := write("differ.json", `{
+		"items": [{"type": ["h-card"], "properties": {"name": ["John"]}}],
+		"rels": {}, "rel-urls": {}
+	}`)
+	invalid := write("invalid.json", `{"items": [`)
+
+	if ok, diff := CompareToFixture(html, match); !ok {
+		t.Errorf("CompareToFixture returned false for matching fixture: %s", diff)
+	}
+	if ok, diff := CompareToFixture(html, differ); ok || !strings.Contains(diff, "John") {
+		t.Errorf("CompareToFixture returned %t, %q for differing fixture, want false with differences", ok, diff)
+	}
+	for _, json := range []string{invalid, filepath.Join(dir, "missing.json")} {
+		if ok, diff := CompareToFixture(html, json); ok || diff == "" {
+			t.Errorf("CompareToFixture(%q) returned %t, %q, want false with error", json, ok, diff)
+		}
+	}
+	if ok, _ := CompareToFixture(filepath.Join(dir, "missing.html"), match); ok {
+		t.Errorf("CompareToFixture with missing html returned true")
+	}
+}
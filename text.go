@@ -0,0 +1,252 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes alternative text extraction algorithms.
+
+package microformats
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockElements are the HTML elements rendered as blocks by default, which
+// are separated from surrounding text by getBlockTextContent.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Dd: true, atom.Details: true, atom.Dialog: true, atom.Div: true,
+	atom.Dl: true, atom.Dt: true, atom.Fieldset: true, atom.Figcaption: true,
+	atom.Figure: true, atom.Footer: true, atom.Form: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hgroup: true, atom.Hr: true, atom.Li: true,
+	atom.Main: true, atom.Nav: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.Section: true, atom.Summary: true, atom.Table: true, atom.Tr: true,
+	atom.Ul: true,
+}
+
+// WithBlockText inserts line breaks between block-level elements and at <br>
+// elements in the plain text value of e-* properties, so that
+// "<p>a</p><p>b</p>" has the value "a\nb" rather than "ab".  Within each
+// line, runs of whitespace are collapsed to a single space, and empty lines
+// are removed.
+func WithBlockText() Option {
+	return func(p *parser) {
+		p.blockText = true
+	}
+}
+
+// getBlockTextContent returns the text content of node like textContent,
+// but with line breaks inserted at block boundaries as described in
+// WithBlockText.
+func getBlockTextContent(node *html.Node, imgFn func(*html.Node) string, skip func(*html.Node) bool) string {
+	var buf strings.Builder
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		writeBlockText(&buf, c, imgFn, skip)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeBlockText writes the text content of node to buf, writing a newline
+// before and after each block element and at each <br> element.  Newlines
+// within text are written as spaces.
+func writeBlockText(buf *strings.Builder, node *html.Node, imgFn func(*html.Node) string, skip func(*html.Node) bool) {
+	switch {
+	case node.Type == html.TextNode:
+		// line breaks in the source are whitespace, like any other
+		buf.WriteString(strings.Map(func(r rune) rune {
+			if r == '\n' || r == '\r' {
+				return ' '
+			}
+			return r
+		}, node.Data))
+		return
+	case node.Type != html.ElementNode || isAtom(node, atom.Script, atom.Style, atom.Template):
+		return
+	case skip != nil && skip(node):
+		return
+	case isAtom(node, atom.Br):
+		buf.WriteByte('\n')
+		return
+	case isAtom(node, atom.Img):
+		if imgFn != nil {
+			buf.WriteString(imgFn(node))
+		}
+		return
+	}
+
+	block := node.Namespace == "" && blockElements[node.DataAtom]
+	if block {
+		buf.WriteByte('\n')
+	}
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		writeBlockText(buf, c, imgFn, skip)
+	}
+	if block {
+		buf.WriteByte('\n')
+	}
+}
+
+// WithSkipHidden ignores hidden elements when extracting the plain text value
+// of e-* properties and implied names.  An element is hidden if it has the
+// hidden attribute, an aria-hidden attribute of "true", or an inline style
+// that sets "display: none".  Stylesheets are not considered.  The html value
+// of e-* properties still includes hidden elements.
+func WithSkipHidden() Option {
+	return func(p *parser) {
+		p.skipHidden = true
+	}
+}
+
+// skipFunc returns the function used to determine whether nested elements
+// are ignored in text content, or nil if no elements are ignored.
+func (p *parser) skipFunc() func(*html.Node) bool {
+	if p.skipHidden {
+		return isHidden
+	}
+	return nil
+}
+
+// WithExcludeDeletedText ignores <del> elements, which mark text removed in
+// an edit, when extracting the plain text value of e-* properties, so that
+// the value reads as the edited text.  The html value still includes them,
+// as does the markdown value added by WithContentMarkdown.  By default, the
+// text of <del> elements is included, as the microformats2 parsing
+// specification requires.
+func WithExcludeDeletedText() Option {
+	return func(p *parser) {
+		p.excludeDeleted = true
+	}
+}
+
+// contentSkipFunc returns the function used to determine whether nested
+// elements are ignored in the text value of e-* properties, or nil if no
+// elements are ignored.
+func (p *parser) contentSkipFunc() func(*html.Node) bool {
+	skip := p.skipFunc()
+	if !p.excludeDeleted {
+		return skip
+	}
+	return func(node *html.Node) bool {
+		return isAtom(node, atom.Del) || skip != nil && skip(node)
+	}
+}
+
+// isHidden returns whether node is hidden, as described in WithSkipHidden.
+func isHidden(node *html.Node) bool {
+	if hasAttr(node, "hidden") {
+		return true
+	}
+	if strings.EqualFold(strings.TrimSpace(getAttr(node, "aria-hidden")), "true") {
+		return true
+	}
+	for _, decl := range strings.Split(getAttr(node, "style"), ";") {
+		prop, val, ok := strings.Cut(decl, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(prop), "display") {
+			continue
+		}
+		val = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(val), "!important"))
+		if strings.EqualFold(val, "none") {
+			return true
+		}
+	}
+	return false
+}
+
+// A WhitespacePolicy determines how whitespace is removed from the text of
+// p-* properties and implied names.
+type WhitespacePolicy int
+
+const (
+	// SpecNormalize removes all leading and trailing whitespace, including
+	// non-breaking spaces, matching the microformats2 test suite.
+	// Whitespace within the text is kept as-is.  This is the default.
+	SpecNormalize WhitespacePolicy = iota
+
+	// PreserveNBSP removes leading and trailing whitespace like
+	// SpecNormalize, except for non-breaking spaces (U+00A0, such as
+	// from "&nbsp;"), which are kept.
+	PreserveNBSP
+
+	// None keeps all whitespace, so text values are exactly the text
+	// content of the element, with a line break for each <br> element.
+	// Under the other policies, each <br> element becomes a space.
+	None
+
+	// Collapse removes leading and trailing whitespace like SpecNormalize,
+	// and also replaces each run of whitespace within the text, such as the
+	// line breaks and indentation of a multiline element, with a single
+	// space, as the text would be rendered.  This does not match the
+	// microformats2 test suite.
+	Collapse
+)
+
+// WithWhitespacePolicy sets how whitespace is removed from the text of p-*
+// properties and implied names.  It does not affect values taken from
+// attributes, such as the alt text of an image, or the plain text value of
+// e-* properties.
+func WithWhitespacePolicy(policy WhitespacePolicy) Option {
+	return func(p *parser) {
+		p.whitespace = policy
+	}
+}
+
+// lineBreak returns the text written for a <br> element in p-* properties
+// and implied names according to p's WhitespacePolicy: a line break for
+// None, which keeps the text as it is, or else a space, so that the lines
+// are separated once whitespace is normalized.
+func (p *parser) lineBreak() string {
+	if p.whitespace == None {
+		return "\n"
+	}
+	return " "
+}
+
+// trimText removes whitespace from s according to p's WhitespacePolicy.
+func (p *parser) trimText(s string) string {
+	switch p.whitespace {
+	case PreserveNBSP:
+		return strings.TrimFunc(s, func(r rune) bool {
+			return r != '\u00a0' && unicode.IsSpace(r)
+		})
+	case None:
+		return s
+	case Collapse:
+		return strings.Join(strings.Fields(s), " ")
+	default:
+		return strings.TrimSpace(s)
+	}
+}
+
+// WithSVGTitles uses the accessible name of inline SVG elements for p-*
+// properties.  When a p-* property is on an SVG element with a <title>
+// child, the text of the <title> is used as the property value rather than
+// the text content of the entire element, which may include unrelated text
+// such as a <desc> or labels.  This is an accessibility-aware extension to
+// the parsing specification.
+func WithSVGTitles() Option {
+	return func(p *parser) {
+		p.svgTitles = true
+	}
+}
+
+// getSVGTitle returns the text of the first <title> child of the SVG element
+// node, or nil if it has none.
+func getSVGTitle(node *html.Node) *string {
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Namespace == "svg" && c.Data == "title" {
+			title := strings.TrimSpace(getTextContent(c, nil))
+			return &title
+		}
+	}
+	return nil
+}
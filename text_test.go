@@ -0,0 +1,308 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_GetBlockTextContent(t *testing.T) {
+	tests := []struct {
+		html    string
+		content string
+	}{
+		{"<div></div>", ""},
+		{"<div>foo</div>", "foo"},
+		{"<div> foo \n bar </div>", "foo bar"},
+		{"<div><p>a</p><p>b</p></div>", "a\nb"},
+		{"<div><p>a</p>\n\n<p>b</p></div>", "a\nb"},
+		{"<div>a<p>b</p>c</div>", "a\nb\nc"},
+		{"<div><b>a</b><i>b</i></div>", "ab"},
+		{"<div>a<br>b</div>", "a\nb"},
+		{"<div>a <br> b<br><br>c</div>", "a\nb\nc"},
+		{"<div><ul><li>one</li><li>two</li></ul></div>", "one\ntwo"},
+		{"<div><h1>Title</h1>Text <em>here</em>.</div>", "Title\nText here."},
+		{"<div><p>a<script>b</script></p><style>c</style><p>d</p></div>", "a\nd"},
+		{"<div><p>a <img alt=\"b\"></p></div>", "a b"},
+	}
+
+	for _, tt := range tests {
+		n, err := parseNode(tt.html)
+		if err != nil {
+			t.Fatalf("Error parsing HTML: %v", err)
+		}
+
+		if got, want := getBlockTextContent(n, imageAltValue, nil), tt.content; got != want {
+			t.Errorf("getBlockTextContent(%q) returned %q, want %q", tt.html, got, want)
+		}
+	}
+}
+
+func Test_WithBlockText(t *testing.T) {
+	doc := `<div class="h-entry"><div class="e-content"><p>Hello</p><ul><li>a</li><li>b<br>c</li></ul></div></div>`
+
+	tests := []struct {
+		opts []Option
+		want string
+	}{
+		{nil, "Helloabc"},
+		{[]Option{WithBlockText()}, "Hello\na\nb\nc"},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		want := []any{map[string]string{
+			"value": tt.want,
+			"html":  "<p>Hello</p><ul><li>a</li><li>b<br>c</li></ul>",
+		}}
+		if got := items[0].Properties["content"]; !cmp.Equal(got, want) {
+			t.Errorf("content property returned %v, want %v", got, want)
+		}
+	}
+}
+
+func Test_IsHidden(t *testing.T) {
+	tests := []struct {
+		html string
+		want bool
+	}{
+		{`<span>a</span>`, false},
+		{`<span hidden>a</span>`, true},
+		{`<span hidden="until-found">a</span>`, true},
+		{`<span aria-hidden="true">a</span>`, true},
+		{`<span aria-hidden="TRUE ">a</span>`, true},
+		{`<span aria-hidden="false">a</span>`, false},
+		{`<span style="display:none">a</span>`, true},
+		{`<span style="color: red; DISPLAY : None !important;">a</span>`, true},
+		{`<span style="display: block">a</span>`, false},
+		{`<span style="visibility: hidden">a</span>`, false},
+	}
+
+	for _, tt := range tests {
+		n, err := parseNode(tt.html)
+		if err != nil {
+			t.Fatalf("Error parsing HTML: %v", err)
+		}
+		if got := isHidden(n); got != tt.want {
+			t.Errorf("isHidden(%q) returned %t, want %t", tt.html, got, tt.want)
+		}
+	}
+}
+
+func Test_WithSkipHidden(t *testing.T) {
+	tests := []struct {
+		html string
+		opts []Option
+		want map[string][]any
+	}{
+		{
+			`<div class="h-card">Jane <span hidden>(skip)</span><span style="display:none"> Doe</span></div>`,
+			nil,
+			map[string][]any{"name": {"Jane (skip) Doe"}},
+		},
+		{
+			`<div class="h-card">Jane <span hidden>(skip)</span><span style="display:none"> Doe</span></div>`,
+			[]Option{WithSkipHidden()},
+			map[string][]any{"name": {"Jane"}},
+		},
+		{
+			`<div class="h-entry"><div class="e-content">Hello<span aria-hidden="true"> icon</span> world</div></div>`,
+			[]Option{WithSkipHidden()},
+			map[string][]any{"content": {map[string]string{
+				"value": "Hello world",
+				"html":  `Hello<span aria-hidden="true"> icon</span> world`,
+			}}},
+		},
+		{
+			`<div class="h-entry"><div class="e-content"><p>a</p><p hidden>b</p><p>c</p></div></div>`,
+			[]Option{WithSkipHidden(), WithBlockText()},
+			map[string][]any{"content": {map[string]string{
+				"value": "a\nc",
+				"html":  `<p>a</p><p hidden="">b</p><p>c</p>`,
+			}}},
+		},
+		{
+			// explicit p-* properties are not affected
+			`<div class="h-card"><span class="p-name">Jane <span hidden>Doe</span></span></div>`,
+			[]Option{WithSkipHidden()},
+			map[string][]any{"name": {"Jane Doe"}},
+		},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(tt.html, tt.opts...)
+		if diff := cmp.Diff(tt.want, items[0].Properties); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_WithExcludeDeletedText(t *testing.T) {
+	doc := `<div class="h-entry"><p class="p-name">Post</p><div class="e-content">The meeting is <del>Monday</del> <ins>Tuesday</ins>.</div></div>`
+	tests := []struct {
+		opts []Option
+		want map[string][]any
+	}{
+		{
+			nil,
+			map[string][]any{"name": {"Post"}, "content": {map[string]string{
+				"value": "The meeting is Monday Tuesday.",
+				"html":  `The meeting is <del>Monday</del> <ins>Tuesday</ins>.`,
+			}}},
+		},
+		{
+			[]Option{WithExcludeDeletedText()},
+			map[string][]any{"name": {"Post"}, "content": {map[string]string{
+				"value": "The meeting is  Tuesday.",
+				"html":  `The meeting is <del>Monday</del> <ins>Tuesday</ins>.`,
+			}}},
+		},
+		{
+			[]Option{WithExcludeDeletedText(), WithBlockText()},
+			map[string][]any{"name": {"Post"}, "content": {map[string]string{
+				"value": "The meeting is Tuesday.",
+				"html":  `The meeting is <del>Monday</del> <ins>Tuesday</ins>.`,
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		if diff := cmp.Diff(tt.want, items[0].Properties); diff != "" {
+			t.Errorf("Parse(%q) with %d options mismatch (-want +got):\n%s", doc, len(tt.opts), diff)
+		}
+	}
+
+	// p-* properties are not affected
+	items := parseItemsWith(`<div class="h-card"><span class="p-name">Jane <del>Doe</del></span></div>`, WithExcludeDeletedText())
+	if got, want := items[0].Properties["name"], []any{"Jane Doe"}; !cmp.Equal(got, want) {
+		t.Errorf("Parse with WithExcludeDeletedText returned name %v, want %v", got, want)
+	}
+}
+
+func Test_WithSVGTitles(t *testing.T) {
+	doc := `<div class="h-entry">
+		<svg class="p-name" viewBox="0 0 10 10">
+			<title>Sales chart</title>
+			<desc>Bar chart of monthly sales</desc>
+			<text>Jan</text>
+		</svg>
+		<svg class="p-summary"><text>No title</text></svg>
+	</div>`
+
+	tests := []struct {
+		opts    []Option
+		name    string
+		summary string
+	}{
+		{nil, "Sales chart\n\t\t\tBar chart of monthly sales\n\t\t\tJan", "No title"},
+		{[]Option{WithSVGTitles()}, "Sales chart", "No title"},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		props := items[0].Properties
+		if got := props["name"]; !cmp.Equal(got, []any{tt.name}) {
+			t.Errorf("name property returned %q, want %q", got, tt.name)
+		}
+		if got := props["summary"]; !cmp.Equal(got, []any{tt.summary}) {
+			t.Errorf("summary property returned %q, want %q", got, tt.summary)
+		}
+	}
+}
+
+func Test_WithWhitespacePolicy(t *testing.T) {
+	doc := `<div class="h-card"><span class="p-name">` + "\n\u00a0Jane\u00a0 Doe\u00a0 " + `</span>
+		<abbr class="p-nickname" title=" JD ">Jane</abbr></div>
+		<div class="h-card"> &nbsp;Implied&nbsp; </div>`
+
+	tests := []struct {
+		policy        WhitespacePolicy
+		name, implied string
+	}{
+		{SpecNormalize, "Jane\u00a0 Doe", "Implied"},
+		{PreserveNBSP, "\u00a0Jane\u00a0 Doe\u00a0", "\u00a0Implied\u00a0"},
+		{None, "\n\u00a0Jane\u00a0 Doe\u00a0 ", " \u00a0Implied\u00a0 "},
+		{Collapse, "Jane Doe", "Implied"},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, WithWhitespacePolicy(tt.policy))
+		if got, want := items[0].Properties["name"], []any{tt.name}; !cmp.Equal(got, want) {
+			t.Errorf("name with policy %d returned %q, want %q", tt.policy, got, want)
+		}
+		if got, want := items[0].Properties["nickname"], []any{" JD "}; !cmp.Equal(got, want) {
+			t.Errorf("nickname with policy %d returned %q, want %q", tt.policy, got, want)
+		}
+		if got, want := items[1].Properties["name"], []any{tt.implied}; !cmp.Equal(got, want) {
+			t.Errorf("implied name with policy %d returned %q, want %q", tt.policy, got, want)
+		}
+	}
+}
+
+func Test_WithWhitespacePolicy_Multiline(t *testing.T) {
+	doc := "<div class=\"h-card\">\n\t\t<span>John</span>\n\t\t\t<span>Doe</span>\n\t</div>\n" +
+		"<a class=\"h-card\" href=\"/\">\n    John\r\n    Doe\n</a>\n" +
+		"<div class=\"h-card\"><p class=\"p-name\">\n    John\n    \tDoe\n</p></div>"
+
+	tests := []struct {
+		policy WhitespacePolicy
+		want   []string
+	}{
+		// whitespace within the text is kept, as the test suite expects
+		{SpecNormalize, []string{"John\n\t\t\tDoe", "John\n    Doe", "John\n    \tDoe"}},
+		{Collapse, []string{"John Doe", "John Doe", "John Doe"}},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, WithWhitespacePolicy(tt.policy))
+		var got []string
+		for _, item := range items {
+			got = append(got, firstString(item, "name"))
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("names with policy %d mismatch (-want +got):\n%s", tt.policy, diff)
+		}
+	}
+}
+
+func Test_Parse_LineBreaks(t *testing.T) {
+	doc := `<div class="h-card"><p class="p-adr">1 Main St<br>Springfield<br/>USA</p>
+		<span class="p-name">Jane<br>Doe</span></div>
+	<div class="h-card">John<br>Doe</div>`
+
+	tests := []struct {
+		policy             WhitespacePolicy
+		adr, name, implied string
+	}{
+		{SpecNormalize, "1 Main St Springfield USA", "Jane Doe", "John Doe"},
+		{PreserveNBSP, "1 Main St Springfield USA", "Jane Doe", "John Doe"},
+		{None, "1 Main St\nSpringfield\nUSA", "Jane\nDoe", "John\nDoe"},
+		{Collapse, "1 Main St Springfield USA", "Jane Doe", "John Doe"},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, WithWhitespacePolicy(tt.policy))
+		if got := firstString(items[0], "adr"); got != tt.adr {
+			t.Errorf("adr with policy %d is %q, want %q", tt.policy, got, tt.adr)
+		}
+		if got := firstString(items[0], "name"); got != tt.name {
+			t.Errorf("name with policy %d is %q, want %q", tt.policy, got, tt.name)
+		}
+		if got := firstString(items[1], "name"); got != tt.implied {
+			t.Errorf("implied name with policy %d is %q, want %q", tt.policy, got, tt.implied)
+		}
+	}
+
+	// <br> elements in nested microformats separate the text of their
+	// parent too
+	items := parseItems(`<div class="h-entry"><div class="p-location h-adr">
+		<span class="p-street-address">1 Main St</span><br><span class="p-locality">Springfield</span></div></div>`)
+	location := items[0].Properties["location"][0].(*Microformat)
+	if got, want := location.Value, "1 Main St Springfield"; got != want {
+		t.Errorf("location value is %q, want %q", got, want)
+	}
+}
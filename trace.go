@@ -0,0 +1,53 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for tracing decisions made while parsing.
+
+package microformats
+
+import "golang.org/x/net/html"
+
+// Events passed to the function registered with WithTracer.
+const (
+	// TraceRoot is traced for the root element of each microformat,
+	// including backcompat roots, before any of its properties are parsed.
+	TraceRoot = "root"
+
+	// TraceProperty is traced for an element each time a property value is
+	// parsed from one of its property classes.  An element with several
+	// property classes is traced once for each of them.
+	TraceProperty = "property"
+
+	// TraceImpliedName, TraceImpliedPhoto, and TraceImpliedURL are traced
+	// for the root element of a microformat when the corresponding property
+	// is implied.
+	TraceImpliedName  = "implied-name"
+	TraceImpliedPhoto = "implied-photo"
+	TraceImpliedURL   = "implied-url"
+
+	// TraceValueClass is traced for an element when a p-*, u-*, or dt-*
+	// property value is taken from its value class pattern descendants.
+	TraceValueClass = "value-class"
+)
+
+// WithTracer calls fn at key decision points while walking the document, to
+// help explain how parsed data was produced.  Each event (such as TraceRoot)
+// is passed along with the element it relates to.  Events are traced in
+// document order of the decisions, so a microformat's implied properties are
+// traced after its explicit properties, once all of its descendants have been
+// parsed.
+//
+// fn is called synchronously, and must not modify node or call back into the
+// parser.
+func WithTracer(fn func(event string, node *html.Node)) Option {
+	return func(p *parser) {
+		p.tracer = fn
+	}
+}
+
+// trace calls the tracer of p, if there is one.
+func (p *parser) trace(event string, node *html.Node) {
+	if p.tracer != nil {
+		p.tracer(event, node)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/html"
+)
+
+func Test_WithTracer(t *testing.T) {
+	doc := `<div class="h-entry" id="entry">
+		<span class="p-name" id="name"><span class="value">Hello</span></span>
+		<time class="dt-published" id="published"><span class="value">2024-01-02</span></time>
+		<div class="p-author h-card" id="author"><img src="jane.jpg" alt="Jane"></div>
+	</div>
+	<div class="h-card" id="card"><a href="/jane">Jane</a></div>`
+
+	var events []string
+	Parse(strings.NewReader(doc), nil, WithTracer(func(event string, node *html.Node) {
+		events = append(events, event+" "+getAttr(node, "id"))
+	}))
+
+	want := []string{
+		"root entry",
+		"value-class name",
+		"property name",
+		"value-class published",
+		"property published",
+		"root author",
+		"implied-name author",
+		"implied-photo author",
+		"property author",
+		"root card",
+		"implied-name card",
+		"implied-url card",
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("WithTracer events mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes validation of parsed data against the structure of the
+// canonical microformats2 JSON.
+
+package microformats
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SchemaError is returned by ValidateSchema, and describes each way in which
+// parsed data does not have the structure of the canonical microformats2
+// JSON.
+type SchemaError struct {
+	// Problems describes each problem found, prefixed by the location
+	// of the invalid value, such as `items[0].properties["content"][0]`.
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "microformats: invalid data: " + strings.Join(e.Problems, "; ")
+}
+
+// ValidateSchema checks that d has the structure of the canonical
+// microformats2 JSON, returning a *SchemaError describing every problem
+// found, or nil if there are none.  Data returned by Parse is always valid
+// when parsed with an absolute base URL, except that invalid URLs on the page
+// cannot be resolved and so are not absolute.  This is mostly useful as a
+// consistency check in tests and CI pipelines, and for data that was
+// unmarshaled from JSON or built by hand.  The following are checked:
+//
+//   - each microformat has at least one type, each of which is a valid root
+//     class name, and non-nil properties
+//   - property names are valid, and each value is a string, a nested
+//     microformat, or a map with a "value" member
+//   - maps with an "html" member (e-* properties) and images with "alt"
+//     text only have the members used for them
+//   - each key of RelURLs is an absolute URL, and the rels of each URL in
+//     Rels and RelURLs agree
+func ValidateSchema(d *Data) error {
+	if d == nil {
+		return &SchemaError{Problems: []string{"data is nil"}}
+	}
+	v := &validator{}
+	for i, item := range d.Items {
+		v.microformat(fmt.Sprintf("items[%d]", i), item)
+	}
+	v.rels(d)
+	if len(v.problems) > 0 {
+		return &SchemaError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validator collects the problems found by ValidateSchema.
+type validator struct {
+	problems []string
+}
+
+func (v *validator) add(path, format string, args ...any) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// microformat validates m, found at path.
+func (v *validator) microformat(path string, m *Microformat) {
+	if m == nil {
+		v.add(path, "microformat is nil")
+		return
+	}
+	if len(m.Type) == 0 {
+		v.add(path, "microformat has no type")
+	}
+	for _, t := range m.Type {
+		if !rootClassNames.MatchString(t) {
+			v.add(path, "invalid type %q", t)
+		}
+	}
+	if m.Properties == nil {
+		v.add(path, "microformat has nil properties")
+	}
+	for _, name := range sortedKeys(m.Properties) {
+		values := m.Properties[name]
+		ppath := fmt.Sprintf("%s.properties[%q]", path, name)
+		if !propertyClassNames.MatchString("p-" + name) {
+			v.add(ppath, "invalid property name")
+		}
+		for i, value := range values {
+			v.value(fmt.Sprintf("%s[%d]", ppath, i), value)
+		}
+	}
+	for i, child := range m.Children {
+		v.microformat(fmt.Sprintf("%s.children[%d]", path, i), child)
+	}
+}
+
+// value validates the property value found at path.
+func (v *validator) value(path string, value any) {
+	switch value := value.(type) {
+	case string:
+	case *Microformat:
+		v.microformat(path, value)
+	case map[string]string:
+		if _, ok := value["value"]; !ok {
+			v.add(path, `value has no "value" member`)
+		}
+		allowed := map[string]bool{"value": true, "alt": true, "srcset": true}
+		if _, ok := value["html"]; ok {
+			allowed = map[string]bool{"value": true, "html": true, "markdown": true}
+		}
+		for _, k := range sortedKeys(value) {
+			if !allowed[k] {
+				v.add(path, "value has unexpected member %q", k)
+			}
+		}
+	default:
+		v.add(path, "value has unexpected type %T", value)
+	}
+}
+
+// rels validates the Rels and RelURLs of d, and that they agree.
+func (v *validator) rels(d *Data) {
+	for _, u := range sortedKeys(d.RelURLs) {
+		rel := d.RelURLs[u]
+		path := fmt.Sprintf("rel-urls[%q]", u)
+		if parsed, err := url.Parse(u); err != nil || !parsed.IsAbs() {
+			v.add(path, "URL is not absolute")
+		}
+		if rel == nil {
+			v.add(path, "rel-url is nil")
+			continue
+		}
+		if len(rel.Rels) == 0 {
+			v.add(path, "rel-url has no rels")
+		}
+		for _, r := range rel.Rels {
+			if !containsString(d.Rels[r], u) {
+				v.add(path, "URL is missing from rels[%q]", r)
+			}
+		}
+	}
+	for _, r := range sortedKeys(d.Rels) {
+		for i, u := range d.Rels[r] {
+			path := fmt.Sprintf("rels[%q][%d]", r, i)
+			rel := d.RelURLs[u]
+			if rel == nil {
+				v.add(path, "URL %q is missing from rel-urls", u)
+			} else if !containsString(rel.Rels, r) {
+				v.add(path, "rel is missing from rel-urls[%q]", u)
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsString returns whether s contains v.
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
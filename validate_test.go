@@ -0,0 +1,103 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ValidateSchema_Parsed(t *testing.T) {
+	doc := `<html><head><link rel="me authorization_endpoint" href="/me"></head><body>
+		<div class="h-entry" id="post">
+			<a class="p-author h-card" href="/jane"><img src="/jane.jpg" alt="Jane"> Jane</a>
+			<div class="e-content">Hello <a href="/world" rel="tag">world</a></div>
+			<img class="u-photo" src="/a.jpg" alt="a photo">
+			<time class="dt-published" datetime="2024-01-01">today</time>
+			<div class="h-cite"></div>
+		</div>
+		<div class="vcard"><span class="fn">John</span><a class="url" rel="me" href="/me">me</a></div>
+	</body></html>`
+	base, _ := url.Parse("http://example.com/")
+	if err := ValidateSchema(Parse(strings.NewReader(doc), base)); err != nil {
+		t.Errorf("ValidateSchema returned error: %v", err)
+	}
+}
+
+func Test_ValidateSchema(t *testing.T) {
+	tests := []struct {
+		data *Data
+		want []string
+	}{
+		{nil, []string{"data is nil"}},
+		{&Data{}, nil},
+		{
+			&Data{Items: []*Microformat{
+				nil,
+				{Type: []string{"card"}},
+				{
+					Type: []string{"h-entry"},
+					Properties: map[string][]any{
+						"content":  {map[string]string{"html": "<b>hi</b>", "alt": "x"}},
+						"count":    {1},
+						"Name":     {"x"},
+						"author":   {&Microformat{Properties: map[string][]any{}}},
+						"photo":    {map[string]string{"value": "http://example.com/a.jpg", "alt": "a"}},
+						"location": {&Microformat{Type: []string{"h-adr"}, Properties: map[string][]any{}}},
+					},
+					Children: []*Microformat{{Properties: map[string][]any{}}},
+				},
+			}},
+			[]string{
+				`items[0]: microformat is nil`,
+				`items[1]: invalid type "card"`,
+				`items[1]: microformat has nil properties`,
+				`items[2].properties["Name"]: invalid property name`,
+				`items[2].properties["author"][0]: microformat has no type`,
+				`items[2].properties["content"][0]: value has no "value" member`,
+				`items[2].properties["content"][0]: value has unexpected member "alt"`,
+				`items[2].properties["count"][0]: value has unexpected type int`,
+				`items[2].children[0]: microformat has no type`,
+			},
+		},
+		{
+			&Data{
+				Rels: map[string][]string{
+					"me":     {"http://example.com/", "http://example.net/"},
+					"author": {"http://example.com/"},
+				},
+				RelURLs: map[string]*RelURL{
+					"http://example.com/": {Rels: []string{"me"}},
+					"/relative":           {Rels: []string{"alternate"}},
+					"http://example.org/": {},
+				},
+			},
+			[]string{
+				`rel-urls["/relative"]: URL is not absolute`,
+				`rel-urls["/relative"]: URL is missing from rels["alternate"]`,
+				`rel-urls["http://example.org/"]: rel-url has no rels`,
+				`rels["author"][0]: rel is missing from rel-urls["http://example.com/"]`,
+				`rels["me"][1]: URL "http://example.net/" is missing from rel-urls`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		err := ValidateSchema(tt.data)
+		var got []string
+		if err != nil {
+			schemaErr, ok := err.(*SchemaError)
+			if !ok {
+				t.Fatalf("ValidateSchema returned %T, want *SchemaError", err)
+			}
+			got = schemaErr.Problems
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("ValidateSchema(%v) problems mismatch (-want +got):\n%s", tt.data, diff)
+		}
+	}
+}
@@ -0,0 +1,930 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes typed representations of common microformats
+// vocabularies.
+
+package microformats
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HCard is a person or organization, represented by an h-card microformat.
+//
+// See https://microformats.org/wiki/h-card
+type HCard struct {
+	Name     string
+	Nickname string
+	Org      string
+	URL      string
+	Photo    string
+	Email    string
+	Note     string
+
+	// Sex, GenderIdentity, and Pronouns are the sex, gender identity, and
+	// pronouns (such as "she/her") of a person.
+	Sex            string
+	GenderIdentity string
+	Pronouns       []string
+
+	// Source is the microformat this HCard was mapped from.  It is nil if
+	// the HCard was created from a plain string value.
+	Source *Microformat
+}
+
+// DisplayName returns a name suitable for displaying c to a user.  This is
+// the first non-empty value of c's name, nickname, or org, or else the host
+// of c's URL.  If c has none of these, an empty string is returned.
+func (c *HCard) DisplayName() string {
+	if c == nil {
+		return ""
+	}
+	for _, s := range []string{c.Name, c.Nickname, c.Org} {
+		if s = strings.TrimSpace(s); s != "" {
+			return s
+		}
+	}
+	if u, err := url.Parse(c.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return c.URL
+}
+
+// Name is the structured name of a person, from the name part properties of
+// an h-card.
+type Name struct {
+	HonorificPrefix string
+	GivenName       string
+	AdditionalName  string
+	FamilyName      string
+	HonorificSuffix string
+}
+
+// nameParts are the h-card properties of the fields of Name.
+var nameParts = []string{"honorific-prefix", "given-name", "additional-name", "family-name", "honorific-suffix"}
+
+// StructuredName returns the structured name of c, from the name part
+// properties (given-name, family-name, and so on) of its Source.  These may be
+// properties of the h-card itself, as they are for microformats v1 vcards
+// with an n element, or of a microformat nested as its n or name property,
+// such as <span class="p-n h-card">.  Properties of the h-card itself take
+// precedence for each part.  If c has no Source, an empty Name is returned.
+func (c *HCard) StructuredName() Name {
+	if c == nil || c.Source == nil {
+		return Name{}
+	}
+	var nested []*Microformat
+	for _, prop := range []string{"n", "name"} {
+		for _, v := range c.Source.Properties[prop] {
+			if m, ok := v.(*Microformat); ok && m != nil {
+				nested = append(nested, m)
+			}
+		}
+	}
+	var parts [5]string
+	for i, prop := range nameParts {
+		parts[i] = firstString(c.Source, prop)
+		for _, m := range nested {
+			if parts[i] != "" {
+				break
+			}
+			parts[i] = firstString(m, prop)
+		}
+	}
+	return Name{
+		HonorificPrefix: parts[0],
+		GivenName:       parts[1],
+		AdditionalName:  parts[2],
+		FamilyName:      parts[3],
+		HonorificSuffix: parts[4],
+	}
+}
+
+// Telephone is a telephone number of an h-card, with its vCard type labels.
+type Telephone struct {
+	// Type is the comma-separated type labels of the number, such as "work"
+	// or "home,pref", or empty if it has none.
+	Type   string
+	Number string
+}
+
+// Telephones returns the tel property values of c's Source as Telephones, in
+// order.  Plain values have no Type, unless they are the value of a
+// microformats v1 tel with labels, as recorded in TypeLabels, such as
+// <div class="tel"><span class="type">work</span> <span class="value">+1 555 0100</span></div>.
+// Nested microformats use their type and value properties, or else their
+// value.  A "tel:" scheme is removed from numbers, and empty numbers are
+// omitted.  If c has no Source, nil is returned.
+func (c *HCard) Telephones() []Telephone {
+	if c == nil || c.Source == nil {
+		return nil
+	}
+	labels := c.Source.TypeLabels["tel"]
+	var tels []Telephone
+	for i, v := range c.Source.Properties["tel"] {
+		var tel Telephone
+		if m, ok := v.(*Microformat); ok && m != nil {
+			tel.Type = strings.Join(allStrings(m, "type"), ",")
+			tel.Number = firstString(m, "value")
+			if tel.Number == "" {
+				tel.Number = m.Value
+			}
+		} else {
+			tel.Number = valueString(v)
+			if i < len(labels) {
+				tel.Type = strings.Join(labels[i], ",")
+			}
+		}
+		number := strings.TrimSpace(tel.Number)
+		if len(number) > 4 && strings.EqualFold(number[:4], "tel:") {
+			number = number[4:]
+		}
+		if tel.Number = number; tel.Number != "" {
+			tels = append(tels, tel)
+		}
+	}
+	return tels
+}
+
+// Emails returns the email property values of c's Source as addresses, in
+// order and without duplicates.  Values may be mailto: links, such as from
+// <a class="u-email" href="mailto:jane@example.com">, or plain text.  Nested
+// microformats, such as a microformats v1 email with a type, use their value
+// property, or else their value.  The "mailto:" scheme and any query, such as
+// "?subject=Hi", are removed, and the domain is lowercased as described in
+// WithLowercaseEmails, so the same address from different forms is returned
+// once.  Empty values are omitted.  If c has no Source, nil is returned.
+func (c *HCard) Emails() []string {
+	if c == nil || c.Source == nil {
+		return nil
+	}
+	var emails []string
+	seen := make(map[string]bool)
+	for _, v := range c.Source.Properties["email"] {
+		var email string
+		if m, ok := v.(*Microformat); ok && m != nil {
+			email = firstString(m, "value")
+			if email == "" {
+				email = m.Value
+			}
+		} else {
+			email = valueString(v)
+		}
+		email = strings.TrimSpace(email)
+		if len(email) >= len("mailto:") && strings.EqualFold(email[:len("mailto:")], "mailto:") {
+			email = email[len("mailto:"):]
+		}
+		email, _, _ = strings.Cut(email, "?")
+		if s, err := url.PathUnescape(email); err == nil {
+			email = s
+		}
+		email = lowercaseEmail(strings.TrimSpace(email))
+		if email != "" && !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
+
+// Keys returns the key property values of c's Source, such as the URL of a
+// PGP public key or its fingerprint, in order.  Values are opaque: URLs are
+// resolved against the base URL when parsing, but fingerprints and other
+// values that are not URLs, such as "openpgp4fpr:" URIs, are left as-is.
+// Empty values are omitted.  If c has no Source, nil is returned.
+func (c *HCard) Keys() []string {
+	if c == nil || c.Source == nil {
+		return nil
+	}
+	return allStrings(c.Source, "key")
+}
+
+// Logos returns the logo property values of c's Source as Photos, such as the
+// logo of an organization, which is distinct from its photo.  Values are
+// handled the same as by Photos, including their alternative text.  If c has
+// no Source, nil is returned.
+func (c *HCard) Logos() []Photo {
+	if c == nil {
+		return nil
+	}
+	return c.Source.images("logo")
+}
+
+// HEntry is episodic or datestamped content, such as a blog post, represented
+// by an h-entry microformat.
+//
+// See https://microformats.org/wiki/h-entry
+type HEntry struct {
+	Name        string
+	Summary     string
+	Content     string
+	Published   string
+	Updated     string
+	URL         string
+	Author      *HCard
+	Category    []string
+	Syndication []string
+
+	// Response contexts, identifying the posts this entry is a response to.
+	InReplyTo  []*HCite
+	LikeOf     []*HCite
+	RepostOf   []*HCite
+	BookmarkOf []*HCite
+
+	// Source is the microformat this HEntry was mapped from.
+	Source *Microformat
+}
+
+// HCite is a citation of a published work, represented by an h-cite
+// microformat.  It is most commonly used as the context of a response.
+//
+// See https://microformats.org/wiki/h-cite
+type HCite struct {
+	Name      string
+	URL       string
+	Author    *HCard
+	Published string
+	Content   string
+
+	// Source is the microformat this HCite was mapped from.  It is nil if
+	// the HCite was created from a plain URL value.
+	Source *Microformat
+}
+
+// HApp is an application, such as an IndieAuth client, represented by an
+// h-app (or h-x-app) microformat.
+//
+// See https://indieweb.org/h-app
+type HApp struct {
+	Name string
+	URL  string
+	Logo string
+
+	// Source is the microformat this HApp was mapped from.
+	Source *Microformat
+}
+
+// HMeasure is a measurement, such as the quantity of a recipe ingredient,
+// represented by an h-measure microformat.
+//
+// See https://microformats.org/wiki/h-measure
+type HMeasure struct {
+	Num  float64
+	Unit string
+	Name string
+
+	// Source is the microformat this HMeasure was mapped from.
+	Source *Microformat
+}
+
+// HRecipe is a recipe for making a dish, represented by an h-recipe
+// microformat.
+//
+// See https://microformats.org/wiki/h-recipe
+type HRecipe struct {
+	Name         string
+	Ingredients  []string // each ingredient, including its quantity
+	Instructions string
+	Yield        string
+	Duration     time.Duration // time to prepare, or 0 if not specified
+	Nutrition    []string
+	Author       *HCard
+	Photo        []Photo
+
+	// Source is the microformat this HRecipe was mapped from.
+	Source *Microformat
+}
+
+// HProduct is a product, represented by an h-product microformat.
+//
+// See https://microformats.org/wiki/h-product
+type HProduct struct {
+	Name        string
+	Photo       []Photo
+	Brand       *HCard
+	Category    []string
+	Price       string
+	Identifier  []string // such as UPC, EAN, or ISBN codes
+	Review      *HReview
+	Description string
+
+	// Source is the microformat this HProduct was mapped from.
+	Source *Microformat
+}
+
+// HReview is a review of an item, represented by an h-review or
+// h-review-aggregate microformat.
+//
+// See https://microformats.org/wiki/h-review
+type HReview struct {
+	Name    string
+	Item    *Microformat // the reviewed item, if it is a nested microformat
+	Author  *HCard
+	Content string
+
+	// Rating is the rating given to the item, in the range from Worst to
+	// Best.  For an h-review-aggregate, it is the average rating.
+	Rating float64
+	Best   float64
+	Worst  float64
+
+	// Source is the microformat this HReview was mapped from.
+	Source *Microformat
+}
+
+// HAdr is a physical address, represented by an h-adr microformat.
+//
+// See https://microformats.org/wiki/h-adr
+type HAdr struct {
+	Name            string
+	StreetAddress   string
+	ExtendedAddress string
+	Locality        string
+	Region          string
+	PostalCode      string
+	CountryName     string
+
+	// Source is the microformat this HAdr was mapped from.
+	Source *Microformat
+}
+
+// Location is the location of an event or checkin, which may be given as a
+// venue (h-card), an address (h-adr), coordinates (h-geo), or a plain string.
+// Only the fields for the shape of the location, and any nested within it,
+// are set.
+type Location struct {
+	// Name is the name of the location: the display name of Card, or else
+	// the text of the location property.
+	Name string
+
+	// URL is the url of the location, or the plain string value if it is an
+	// absolute URL.
+	URL string
+
+	Card *HCard
+	Adr  *HAdr // the address of Card, if it has one
+	Geo  *HGeo // the coordinates of Card or Adr, if they have them
+}
+
+// HGeo is a geographic location, represented by an h-geo microformat or by a
+// geo URI.
+//
+// See https://microformats.org/wiki/h-geo
+type HGeo struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64 // in meters, or 0 if not specified
+
+	// Source is the microformat this HGeo was mapped from.
+	Source *Microformat
+}
+
+// AsHCard maps m to an HCard.  An error is returned if m is not an h-card.
+func (m *Microformat) AsHCard() (*HCard, error) {
+	if err := checkType(m, "h-card"); err != nil {
+		return nil, err
+	}
+	return hcard(m), nil
+}
+
+// AsHEntry maps m to an HEntry.  An error is returned if m is not an h-entry.
+//
+// Response contexts (in-reply-to, like-of, repost-of, and bookmark-of) may be
+// nested h-cite (or h-entry) microformats, or plain URLs.  Plain URLs are
+// mapped to an HCite with only the URL field set.
+func (m *Microformat) AsHEntry() (*HEntry, error) {
+	if err := checkType(m, "h-entry"); err != nil {
+		return nil, err
+	}
+	return &HEntry{
+		Name:        firstString(m, "name"),
+		Summary:     firstString(m, "summary"),
+		Content:     firstHTML(m, "content"),
+		Published:   firstString(m, "published"),
+		Updated:     firstString(m, "updated"),
+		URL:         firstString(m, "url"),
+		Author:      author(m),
+		Category:    allStrings(m, "category"),
+		Syndication: allStrings(m, "syndication"),
+		InReplyTo:   citations(m, "in-reply-to"),
+		LikeOf:      citations(m, "like-of"),
+		RepostOf:    citations(m, "repost-of"),
+		BookmarkOf:  citations(m, "bookmark-of"),
+		Source:      m,
+	}, nil
+}
+
+// AsHCite maps m to an HCite.  An error is returned if m is not an h-cite.
+func (m *Microformat) AsHCite() (*HCite, error) {
+	if err := checkType(m, "h-cite"); err != nil {
+		return nil, err
+	}
+	return hcite(m), nil
+}
+
+// AsHApp maps m to an HApp.  An error is returned if m is not an h-app or
+// h-x-app.
+func (m *Microformat) AsHApp() (*HApp, error) {
+	if err := checkType(m, "h-app", "h-x-app"); err != nil {
+		return nil, err
+	}
+	return &HApp{
+		Name:   firstString(m, "name"),
+		URL:    firstString(m, "url"),
+		Logo:   firstString(m, "logo"),
+		Source: m,
+	}, nil
+}
+
+// AsHMeasure maps m to an HMeasure.  An error is returned if m is not an
+// h-measure, or if its num property is missing or not a number.  Besides
+// decimal numbers, num may be a fraction such as "1/2" or a mixed number such
+// as "1 1/2", as are common in recipes.  A num composed using the value class
+// pattern is parsed in its concatenated form.
+func (m *Microformat) AsHMeasure() (*HMeasure, error) {
+	if err := checkType(m, "h-measure"); err != nil {
+		return nil, err
+	}
+	s := firstString(m, "num")
+	if s == "" {
+		return nil, errors.New("microformats: h-measure has no num")
+	}
+	num, err := parseNumber(s)
+	if err != nil {
+		return nil, fmt.Errorf("microformats: h-measure num %q is not a number", s)
+	}
+	return &HMeasure{
+		Num:    num,
+		Unit:   firstString(m, "unit"),
+		Name:   firstString(m, "name"),
+		Source: m,
+	}, nil
+}
+
+// AsHRecipe maps m to an HRecipe.  An error is returned if m is not an
+// h-recipe.  Ingredients may be plain strings or nested h-measure
+// microformats, which use their text, such as "2 cups flour".  Duration is
+// parsed as an ISO 8601 duration as described in GetDuration, and is 0 if the
+// duration property is missing or not a supported duration, such as plain
+// text like "1 hour".
+func (m *Microformat) AsHRecipe() (*HRecipe, error) {
+	if err := checkType(m, "h-recipe"); err != nil {
+		return nil, err
+	}
+	duration, _ := m.GetDuration("duration")
+	return &HRecipe{
+		Name:         firstString(m, "name"),
+		Ingredients:  allStrings(m, "ingredient"),
+		Instructions: firstHTML(m, "instructions"),
+		Yield:        firstString(m, "yield"),
+		Duration:     duration,
+		Nutrition:    allStrings(m, "nutrition"),
+		Author:       author(m),
+		Photo:        m.Photos(),
+		Source:       m,
+	}, nil
+}
+
+// AsHReview maps m to an HReview.  An error is returned if m is not an
+// h-review or h-review-aggregate, or if any of its rating, best, or worst
+// properties is not a number.  By convention, ratings are on a scale of 1 to
+// 5, so Best and Worst default to 5 and 1 if not specified.  Rating is 0 if
+// not specified.
+func (m *Microformat) AsHReview() (*HReview, error) {
+	if err := checkType(m, "h-review", "h-review-aggregate"); err != nil {
+		return nil, err
+	}
+	r := &HReview{
+		Name:    firstString(m, "name"),
+		Author:  author(m),
+		Content: firstHTML(m, "content"),
+		Best:    5,
+		Worst:   1,
+		Source:  m,
+	}
+	for _, v := range m.Properties["item"] {
+		if item, ok := v.(*Microformat); ok && item != nil {
+			r.Item = item
+			break
+		}
+	}
+	for _, f := range []struct {
+		prop string
+		num  *float64
+	}{{"rating", &r.Rating}, {"best", &r.Best}, {"worst", &r.Worst}} {
+		s := firstString(m, f.prop)
+		if s == "" {
+			continue
+		}
+		num, err := parseNumber(s)
+		if err != nil {
+			return nil, fmt.Errorf("microformats: %s %s %q is not a number", m.Type[0], f.prop, s)
+		}
+		*f.num = num
+	}
+	return r, nil
+}
+
+// AsHProduct maps m to an HProduct.  An error is returned if m is not an
+// h-product, or if its review is not valid as described in AsHReview.  The
+// brand may be a nested h-card, or a plain string used as its name, and the
+// review is the first nested h-review or h-review-aggregate.
+func (m *Microformat) AsHProduct() (*HProduct, error) {
+	if err := checkType(m, "h-product"); err != nil {
+		return nil, err
+	}
+	p := &HProduct{
+		Name:        firstString(m, "name"),
+		Photo:       m.Photos(),
+		Brand:       firstCard(m, "brand"),
+		Category:    allStrings(m, "category"),
+		Price:       firstString(m, "price"),
+		Identifier:  allStrings(m, "identifier"),
+		Description: firstHTML(m, "description"),
+		Source:      m,
+	}
+	for _, v := range m.Properties["review"] {
+		if review, ok := v.(*Microformat); ok && review != nil && (review.hasType("h-review") || review.hasType("h-review-aggregate")) {
+			r, err := review.AsHReview()
+			if err != nil {
+				return nil, err
+			}
+			p.Review = r
+			break
+		}
+	}
+	return p, nil
+}
+
+// AsHAdr maps m to an HAdr.  An error is returned if m is not an h-adr.
+func (m *Microformat) AsHAdr() (*HAdr, error) {
+	if err := checkType(m, "h-adr"); err != nil {
+		return nil, err
+	}
+	return hadr(m), nil
+}
+
+// Location returns the first value of m's location property, such as the
+// venue of an h-event or the place of a checkin, resolving whichever shape
+// of location it is.  A nested h-card sets Card, along with its adr and geo
+// properties (or its own address and coordinate properties) as Adr and Geo.
+// A nested h-adr sets Adr and its Geo, and a nested h-geo sets Geo.  Other
+// nested microformats only set Name and URL.  A plain string is used as the
+// URL of the location if it is an absolute URL, or else as its name.  If m
+// has no location, nil is returned.
+func (m *Microformat) Location() *Location {
+	if m == nil {
+		return nil
+	}
+	for _, v := range m.Properties["location"] {
+		switch v := v.(type) {
+		case *Microformat:
+			if v == nil {
+				continue
+			}
+			loc := &Location{Name: valueString(v), URL: firstString(v, "url")}
+			switch {
+			case v.hasType("h-card"):
+				loc.Card = hcard(v)
+				loc.Name = loc.Card.DisplayName()
+				if adr := nestedOfType(v, "adr", "h-adr"); adr != nil {
+					loc.Adr = hadr(adr)
+				} else if hasAnyProperty(v, adrProperties...) {
+					loc.Adr = hadr(v)
+				}
+			case v.hasType("h-adr"):
+				loc.Adr = hadr(v)
+			}
+			if geo, err := v.AsGeo(); err == nil {
+				loc.Geo = geo
+			} else if loc.Adr != nil && loc.Adr.Source != v {
+				loc.Geo, _ = loc.Adr.Source.AsGeo()
+			}
+			return loc
+		default:
+			s := valueString(v)
+			if s == "" {
+				continue
+			}
+			if isAbsoluteURL(s) {
+				return &Location{URL: s}
+			}
+			return &Location{Name: s}
+		}
+	}
+	return nil
+}
+
+// AsGeo maps the location of m to an HGeo.  If m is an h-geo, its latitude,
+// longitude, and altitude properties are used.  Otherwise, the first value of
+// its geo property is used, which may be a nested h-geo, a geo URI such as
+// "geo:37.78,-122.42" (RFC 5870), or a "latitude;longitude" pair as used by
+// the classic geo microformat.  An h-geo without coordinates is parsed from
+// its value in the same way.  If m has no geo property, its own latitude,
+// longitude, and altitude properties are used, as in an h-card or h-adr.
+//
+// An error is returned if m has no location, or if its coordinates are not
+// numbers.
+func (m *Microformat) AsGeo() (*HGeo, error) {
+	if m == nil {
+		return nil, errors.New("microformats: nil microformat has no location")
+	}
+	if !m.hasType("h-geo") {
+		for _, v := range m.Properties["geo"] {
+			if geo, ok := v.(*Microformat); ok && geo != nil {
+				return geo.AsGeo()
+			}
+			if s := valueString(v); s != "" {
+				g, err := parseGeo(s)
+				if err != nil {
+					return nil, err
+				}
+				g.Source = m
+				return g, nil
+			}
+		}
+	}
+
+	lat, long := firstString(m, "latitude"), firstString(m, "longitude")
+	if (lat == "" || long == "") && m.hasType("h-geo") && m.Value != "" {
+		// classic geo microformats have only a value, such as
+		// <abbr class="geo" title="37.78;-122.42">
+		g, err := parseGeo(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		g.Source = m
+		return g, nil
+	}
+	if lat == "" || long == "" {
+		return nil, fmt.Errorf("microformats: microformat of type %v has no location", m.Type)
+	}
+	g := &HGeo{Source: m}
+	for _, f := range []struct {
+		prop string
+		val  string
+		num  *float64
+	}{{"latitude", lat, &g.Latitude}, {"longitude", long, &g.Longitude}, {"altitude", firstString(m, "altitude"), &g.Altitude}} {
+		if f.val == "" {
+			continue
+		}
+		num, err := strconv.ParseFloat(strings.TrimSpace(f.val), 64)
+		if err != nil {
+			return nil, fmt.Errorf("microformats: %s %q is not a number", f.prop, f.val)
+		}
+		*f.num = num
+	}
+	return g, nil
+}
+
+// parseGeo parses s as a geo URI, or as a semicolon separated latitude and
+// longitude.  Only the coordinates of a geo URI are parsed; any parameters,
+// such as the uncertainty, are ignored.
+func parseGeo(s string) (*HGeo, error) {
+	coords := strings.Split(s, ";")
+	if len(s) > 4 && strings.EqualFold(s[:4], "geo:") {
+		coords = strings.Split(strings.SplitN(s[4:], ";", 2)[0], ",")
+	}
+	if len(coords) < 2 || len(coords) > 3 {
+		return nil, fmt.Errorf("microformats: invalid geo %q", s)
+	}
+	var nums [3]float64
+	for i, c := range coords {
+		num, err := strconv.ParseFloat(strings.TrimSpace(c), 64)
+		if err != nil {
+			return nil, fmt.Errorf("microformats: invalid geo %q", s)
+		}
+		nums[i] = num
+	}
+	if !(nums[0] >= -90 && nums[0] <= 90 && nums[1] >= -180 && nums[1] <= 180) {
+		return nil, fmt.Errorf("microformats: geo %q is out of range", s)
+	}
+	return &HGeo{Latitude: nums[0], Longitude: nums[1], Altitude: nums[2]}, nil
+}
+
+// parseNumber parses s as a decimal number, a fraction, or a mixed number.
+func parseNumber(s string) (float64, error) {
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		if num, den, ok := strings.Cut(fields[0], "/"); ok {
+			return parseFraction(num, den)
+		}
+		return strconv.ParseFloat(fields[0], 64)
+	case 2:
+		whole, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		num, den, ok := strings.Cut(fields[1], "/")
+		if !ok {
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+		f, err := parseFraction(num, den)
+		if err != nil || f < 0 {
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+		if whole < 0 {
+			return float64(whole) - f, nil
+		}
+		return float64(whole) + f, nil
+	}
+	return 0, fmt.Errorf("invalid number %q", s)
+}
+
+// parseFraction parses the integer fraction num/den.
+func parseFraction(num, den string) (float64, error) {
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	d, err := strconv.ParseInt(den, 10, 64)
+	if err != nil || d == 0 {
+		return 0, fmt.Errorf("invalid fraction %s/%s", num, den)
+	}
+	return float64(n) / float64(d), nil
+}
+
+// checkType returns an error if m is nil or does not have any of the types.
+func checkType(m *Microformat, types ...string) error {
+	if m == nil {
+		return fmt.Errorf("microformats: nil microformat is not an %s", strings.Join(types, " or "))
+	}
+	for _, t := range types {
+		if m.hasType(t) {
+			return nil
+		}
+	}
+	return fmt.Errorf("microformats: microformat of type %v is not an %s", m.Type, strings.Join(types, " or "))
+}
+
+// hasType returns whether m has the type t.
+func (m *Microformat) hasType(t string) bool {
+	for _, typ := range m.Type {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
+
+// hcard maps m to an HCard, without checking its type.
+func hcard(m *Microformat) *HCard {
+	return &HCard{
+		Name:           firstString(m, "name"),
+		Nickname:       firstString(m, "nickname"),
+		Org:            firstString(m, "org"),
+		URL:            firstString(m, "url"),
+		Photo:          firstString(m, "photo"),
+		Email:          firstString(m, "email"),
+		Note:           firstString(m, "note"),
+		Sex:            firstString(m, "sex"),
+		GenderIdentity: firstString(m, "gender-identity"),
+		Pronouns:       allStrings(m, "pronouns"),
+		Source:         m,
+	}
+}
+
+// adrProperties are the address properties of h-adr, which h-card also has.
+var adrProperties = []string{"street-address", "extended-address", "locality", "region", "postal-code", "country-name"}
+
+// hadr maps m to an HAdr, without checking its type.
+func hadr(m *Microformat) *HAdr {
+	return &HAdr{
+		Name:            firstString(m, "name"),
+		StreetAddress:   firstString(m, "street-address"),
+		ExtendedAddress: firstString(m, "extended-address"),
+		Locality:        firstString(m, "locality"),
+		Region:          firstString(m, "region"),
+		PostalCode:      firstString(m, "postal-code"),
+		CountryName:     firstString(m, "country-name"),
+		Source:          m,
+	}
+}
+
+// nestedOfType returns the first value of prop in m that is a nested
+// microformat of type t, or nil if there is none.
+func nestedOfType(m *Microformat, prop, t string) *Microformat {
+	for _, v := range m.Properties[prop] {
+		if v, ok := v.(*Microformat); ok && v != nil && v.hasType(t) {
+			return v
+		}
+	}
+	return nil
+}
+
+// hasAnyProperty returns whether m has a value for any of props.
+func hasAnyProperty(m *Microformat, props ...string) bool {
+	for _, prop := range props {
+		if len(m.Properties[prop]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hcite maps m to an HCite, without checking its type.
+func hcite(m *Microformat) *HCite {
+	return &HCite{
+		Name:      firstString(m, "name"),
+		URL:       firstString(m, "url"),
+		Author:    author(m),
+		Published: firstString(m, "published"),
+		Content:   firstHTML(m, "content"),
+		Source:    m,
+	}
+}
+
+// author returns the first author of m, as described in firstCard.
+func author(m *Microformat) *HCard {
+	return firstCard(m, "author")
+}
+
+// firstCard returns the first value of prop in m as an HCard.  Nested h-card
+// microformats are mapped in full, while string values are treated as the
+// URL of the card if they are absolute URLs, or else its name.
+func firstCard(m *Microformat, prop string) *HCard {
+	for _, v := range m.Properties[prop] {
+		switch v := v.(type) {
+		case *Microformat:
+			if v != nil && v.hasType("h-card") {
+				return hcard(v)
+			}
+		case string:
+			if v == "" {
+				continue
+			}
+			if isAbsoluteURL(v) {
+				return &HCard{URL: v}
+			}
+			return &HCard{Name: v}
+		}
+	}
+	return nil
+}
+
+// citations returns the values of prop in m as citations.  Nested h-cite or
+// h-entry microformats are mapped in full, while string values are treated as
+// the URL of the cited work.
+func citations(m *Microformat, prop string) []*HCite {
+	var cites []*HCite
+	for _, v := range m.Properties[prop] {
+		switch v := v.(type) {
+		case *Microformat:
+			if v != nil && (v.hasType("h-cite") || v.hasType("h-entry")) {
+				cites = append(cites, hcite(v))
+			}
+		case string:
+			if v != "" {
+				cites = append(cites, &HCite{URL: v})
+			}
+		}
+	}
+	return cites
+}
+
+// allStrings returns the string representation of all values of prop in m,
+// as described in FlatProperties.  Empty values are omitted.
+func allStrings(m *Microformat, prop string) []string {
+	var s []string
+	for _, v := range m.Properties[prop] {
+		if str := valueString(v); str != "" {
+			s = append(s, str)
+		}
+	}
+	return s
+}
+
+// firstHTML returns the HTML of the first value of prop in m.  If the value
+// is not embedded markup (an e-* property), its string representation is
+// returned instead.
+func firstHTML(m *Microformat, prop string) string {
+	for _, v := range m.Properties[prop] {
+		switch v := v.(type) {
+		case map[string]string:
+			if html, ok := v["html"]; ok {
+				return html
+			}
+		case *Microformat:
+			if v != nil && v.HTML != "" {
+				return v.HTML
+			}
+		}
+		if s := valueString(v); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// isAbsoluteURL returns whether s is an absolute URL with a host.
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.IsAbs() && u.Host != ""
+}
@@ -0,0 +1,798 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// ignore the Source fields of typed microformats when comparing.
+var ignoreSource = cmpopts.IgnoreFields(HCard{}, "Source")
+
+func Test_AsHCard(t *testing.T) {
+	items := parseItems(`<div class="h-card">
+		<img class="u-photo" src="/photo.png" alt="">
+		<a class="p-name u-url" href="/jane">Jane Doe</a>
+		(<span class="p-nickname">jd</span>) at <span class="p-org">Example</span>
+		<a class="u-email" href="mailto:jane@example.com">email</a>
+		<p class="p-note">Hello</p>
+	</div>`)
+
+	got, err := items[0].AsHCard()
+	if err != nil {
+		t.Fatalf("AsHCard returned error: %v", err)
+	}
+	want := &HCard{
+		Name:     "Jane Doe",
+		Nickname: "jd",
+		Org:      "Example",
+		URL:      "http://example.com/jane",
+		Photo:    "http://example.com/photo.png",
+		Email:    "mailto:jane@example.com",
+		Note:     "Hello",
+	}
+	if diff := cmp.Diff(want, got, ignoreSource); diff != "" {
+		t.Errorf("AsHCard mismatch (-want +got):\n%s", diff)
+	}
+	if got.Source != items[0] {
+		t.Errorf("AsHCard did not set Source")
+	}
+}
+
+func Test_AsHCard_Identity(t *testing.T) {
+	items := parseItems(`<div class="h-card">
+		<span class="p-name">Alex</span>
+		<span class="p-sex">F</span>
+		<span class="p-gender-identity">non-binary</span>
+		<span class="p-pronouns">they/them</span>
+		<span class="p-pronouns">xe/xem</span>
+	</div>`)
+
+	want := map[string][]any{
+		"name":            {"Alex"},
+		"sex":             {"F"},
+		"gender-identity": {"non-binary"},
+		"pronouns":        {"they/them", "xe/xem"},
+	}
+	if diff := cmp.Diff(want, items[0].Properties); diff != "" {
+		t.Errorf("Parse properties mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := items[0].AsHCard()
+	if err != nil {
+		t.Fatalf("AsHCard returned error: %v", err)
+	}
+	wantCard := &HCard{
+		Name:           "Alex",
+		Sex:            "F",
+		GenderIdentity: "non-binary",
+		Pronouns:       []string{"they/them", "xe/xem"},
+	}
+	if diff := cmp.Diff(wantCard, got, ignoreSource); diff != "" {
+		t.Errorf("AsHCard mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_AsHEntry(t *testing.T) {
+	items := parseItems(`<article class="h-entry">
+		<h1 class="p-name">Title</h1>
+		<p class="p-summary">Summary</p>
+		<div class="e-content"><p>Content</p></div>
+		<time class="dt-published" datetime="2024-01-02T03:04:05Z">Jan 2</time>
+		<a class="u-url" href="/post">permalink</a>
+		<a class="u-syndication" href="https://social.example/1">s1</a>
+		<a class="u-syndication" href="https://social.example/2">s2</a>
+		<span class="p-category">go</span><span class="p-category">mf2</span>
+		<a class="p-author h-card" href="/jane">Jane</a>
+		<div class="u-in-reply-to h-cite">
+			<a class="u-url p-name" href="https://other.example/post">Other post</a>
+			by <span class="p-author h-card">John</span>
+			<time class="dt-published">2023-12-31</time>
+			<div class="e-content">Original</div>
+		</div>
+		<a class="u-in-reply-to" href="https://third.example/">third</a>
+		<a class="u-like-of" href="https://liked.example/">liked</a>
+		<div class="u-repost-of h-entry"><a class="u-url" href="https://reposted.example/">repost</a></div>
+		<a class="u-bookmark-of" href="https://bookmark.example/">bookmark</a>
+	</article>`)
+
+	got, err := items[0].AsHEntry()
+	if err != nil {
+		t.Fatalf("AsHEntry returned error: %v", err)
+	}
+	want := &HEntry{
+		Name:        "Title",
+		Summary:     "Summary",
+		Content:     "<p>Content</p>",
+		Published:   "2024-01-02T03:04:05Z",
+		URL:         "http://example.com/post",
+		Author:      &HCard{Name: "Jane", URL: "http://example.com/jane"},
+		Category:    []string{"go", "mf2"},
+		Syndication: []string{"https://social.example/1", "https://social.example/2"},
+		InReplyTo: []*HCite{
+			{
+				Name:      "Other post",
+				URL:       "https://other.example/post",
+				Author:    &HCard{Name: "John"},
+				Published: "2023-12-31",
+				Content:   "Original",
+			},
+			{URL: "https://third.example/"},
+		},
+		LikeOf:     []*HCite{{URL: "https://liked.example/"}},
+		RepostOf:   []*HCite{{Name: "repost", URL: "https://reposted.example/"}},
+		BookmarkOf: []*HCite{{URL: "https://bookmark.example/"}},
+	}
+	opts := cmp.Options{ignoreSource, cmpopts.IgnoreFields(HEntry{}, "Source"), cmpopts.IgnoreFields(HCite{}, "Source")}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("AsHEntry mismatch (-want +got):\n%s", diff)
+	}
+	if got.InReplyTo[0].Source == nil || got.InReplyTo[1].Source != nil {
+		t.Errorf("AsHEntry did not set Source of response contexts correctly")
+	}
+}
+
+func Test_Author(t *testing.T) {
+	tests := []struct {
+		values []any
+		want   *HCard
+	}{
+		{nil, nil},
+		{[]any{""}, nil},
+		{[]any{"Jane"}, &HCard{Name: "Jane"}},
+		{[]any{"https://example.com/"}, &HCard{URL: "https://example.com/"}},
+		{[]any{&Microformat{Type: []string{"h-entry"}}, "Jane"}, &HCard{Name: "Jane"}},
+		{[]any{&Microformat{Type: []string{"h-card"}, Properties: map[string][]any{"name": {"Jane"}}}}, &HCard{Name: "Jane"}},
+	}
+
+	for _, tt := range tests {
+		m := &Microformat{Properties: map[string][]any{"author": tt.values}}
+		if diff := cmp.Diff(tt.want, author(m), ignoreSource); diff != "" {
+			t.Errorf("author(%v) mismatch (-want +got):\n%s", tt.values, diff)
+		}
+	}
+}
+
+func Test_AsType_Errors(t *testing.T) {
+	var nilItem *Microformat
+	entry := &Microformat{Type: []string{"h-entry"}}
+	card := &Microformat{Type: []string{"h-card"}}
+
+	if _, err := nilItem.AsHCard(); err == nil {
+		t.Errorf("AsHCard on nil microformat did not return error")
+	}
+	if _, err := entry.AsHCard(); err == nil {
+		t.Errorf("AsHCard on h-entry did not return error")
+	}
+	if _, err := card.AsHEntry(); err == nil {
+		t.Errorf("AsHEntry on h-card did not return error")
+	}
+	if _, err := entry.AsHCite(); err == nil {
+		t.Errorf("AsHCite on h-entry did not return error")
+	}
+	if _, err := (&Microformat{Type: []string{"h-cite"}}).AsHCite(); err != nil {
+		t.Errorf("AsHCite on h-cite returned error: %v", err)
+	}
+}
+
+func Test_AsHApp(t *testing.T) {
+	tests := []string{
+		`<div class="h-app"><img class="u-logo" src="/logo.png" alt=""><a class="u-url p-name" href="/">Example App</a></div>`,
+		`<div class="h-x-app"><img class="u-logo" src="logo.png" alt=""><a class="u-url p-name" href="http://example.com/">Example App</a></div>`,
+		`<div class="h-app"><img class="u-logo" src="/logo.png" alt="Logo"><a class="u-url p-name" href="/">Example App</a></div>`,
+	}
+
+	want := &HApp{
+		Name: "Example App",
+		URL:  "http://example.com/",
+		Logo: "http://example.com/logo.png",
+	}
+	for _, tt := range tests {
+		items := parseItems(tt)
+		got, err := items[0].AsHApp()
+		if err != nil {
+			t.Fatalf("AsHApp(%q) returned error: %v", tt, err)
+		}
+		if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(HApp{}, "Source")); diff != "" {
+			t.Errorf("AsHApp(%q) mismatch (-want +got):\n%s", tt, diff)
+		}
+	}
+
+	if _, err := (&Microformat{Type: []string{"h-card"}}).AsHApp(); err == nil {
+		t.Errorf("AsHApp on h-card did not return error")
+	}
+}
+
+func Test_HCard_DisplayName(t *testing.T) {
+	tests := []struct {
+		card *HCard
+		want string
+	}{
+		{nil, ""},
+		{&HCard{}, ""},
+		{&HCard{Name: "Jane", Nickname: "jd", Org: "Example", URL: "https://jane.example/"}, "Jane"},
+		{&HCard{Name: " ", Nickname: "jd", Org: "Example", URL: "https://jane.example/"}, "jd"},
+		{&HCard{Org: "Example", URL: "https://jane.example/"}, "Example"},
+		{&HCard{URL: "https://jane.example/about"}, "jane.example"},
+		{&HCard{URL: "not a url"}, "not a url"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.card.DisplayName(); got != tt.want {
+			t.Errorf("DisplayName(%+v) returned %q, want %q", tt.card, got, tt.want)
+		}
+	}
+}
+
+func Test_HCard_Telephones(t *testing.T) {
+	tests := []struct {
+		html string
+		want []Telephone
+	}{
+		{
+			`<div class="h-card"><span class="p-tel">+1 555 0100</span>
+				<a class="u-tel" href="tel:+15550101">call</a>
+				<div class="p-tel h-tel"><span class="p-type">cell</span> <span class="p-value">+1 555 0102</span></div>
+				<span class="p-tel"> </span></div>`,
+			[]Telephone{
+				{Number: "+1 555 0100"},
+				{Number: "+15550101"},
+				{Type: "cell", Number: "+1 555 0102"},
+			},
+		},
+		{
+			`<div class="vcard"><span class="fn">Jane</span>
+				<span class="tel">+1 555 0100</span>
+				<div class="tel"><span class="type">Work</span> <span class="value">+1 555 0101</span></div>
+				<div class="tel"><span class="type">home</span><span class="type">pref</span>: <span class="value">+1 555 0102</span></div>
+				<abbr class="tel" title="+15550103"><abbr class="type" title="cell">mobile</abbr> phone</abbr>
+				<span class="tel">+1 555 0104</span></div>`,
+			[]Telephone{
+				{Number: "+1 555 0100"},
+				{Type: "work", Number: "+1 555 0101"},
+				{Type: "home,pref", Number: "+1 555 0102"},
+				{Type: "cell", Number: "+15550103"},
+				{Number: "+1 555 0104"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		card, err := parseItems(tt.html)[0].AsHCard()
+		if err != nil {
+			t.Fatalf("AsHCard(%q) returned error: %v", tt.html, err)
+		}
+		if diff := cmp.Diff(tt.want, card.Telephones()); diff != "" {
+			t.Errorf("Telephones(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+
+	if got := (&HCard{Name: "Jane"}).Telephones(); got != nil {
+		t.Errorf("Telephones without Source returned %v, want nil", got)
+	}
+}
+
+func Test_HCard_Emails(t *testing.T) {
+	tests := []struct {
+		html string
+		want []string
+	}{
+		{
+			// the same address from each form of email value
+			`<div class="h-card"><span class="p-name">Jane</span>
+				<a class="u-email" href="mailto:jane@example.com">email</a>
+				<a class="u-email" href="MAILTO:jane@Example.COM?subject=Hi">email</a>
+				<span class="p-email">jane@EXAMPLE.com</span>
+				<a class="u-email" href="mailto:jane%40example.com">email</a>
+				<span class="p-email"> </span>
+				<a class="u-email" href="mailto:Work@example.com">work</a></div>`,
+			[]string{"jane@example.com", "Work@example.com"},
+		},
+		{
+			`<div class="h-card"><div class="u-email h-email"><span class="p-type">work</span>
+				<a class="u-value" href="mailto:jane@example.com">email</a></div>
+				<a class="u-email" href="mailto:jane@example.com">email</a></div>`,
+			[]string{"jane@example.com"},
+		},
+		{
+			`<div class="vcard"><span class="fn">Jane</span>
+				<a class="email" href="mailto:jane@Example.com">email</a>
+				<a class="email" href="mailto:jane@example.com">email</a></div>`,
+			[]string{"jane@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		card, err := parseItems(tt.html)[0].AsHCard()
+		if err != nil {
+			t.Fatalf("AsHCard(%q) returned error: %v", tt.html, err)
+		}
+		if diff := cmp.Diff(tt.want, card.Emails()); diff != "" {
+			t.Errorf("Emails(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+
+	if got := (&HCard{Email: "jane@example.com"}).Emails(); got != nil {
+		t.Errorf("Emails without Source returned %v, want nil", got)
+	}
+}
+
+func Test_HCard_Keys(t *testing.T) {
+	doc := `<div class="h-card"><span class="p-name">Jane</span>
+		<a class="u-key" href="/key.asc">PGP key</a>
+		<span class="u-key">ABCD 1234 EF56 7890 ABCD  1234 EF56 7890 ABCD 1234</span>
+		<span class="u-key">0xABCD1234</span>
+		<code class="u-key">AB:CD:12:34:EF:56:78:90</code>
+		<a class="u-key" href="openpgp4fpr:ABCD1234EF567890ABCD1234EF567890ABCD1234">fingerprint</a>
+		<span class="u-key">ssh-ed25519 AAAAC3NzaC1lZDI1NTE5 jane@example.com</span>
+	</div>`
+	card, err := parseItemsWith(doc, WithAllowedSchemes(), WithStripParams("utm_*"))[0].AsHCard()
+	if err != nil {
+		t.Fatalf("AsHCard returned error: %v", err)
+	}
+
+	want := []string{
+		"http://example.com/key.asc",
+		"ABCD 1234 EF56 7890 ABCD  1234 EF56 7890 ABCD 1234",
+		"0xABCD1234",
+		"AB:CD:12:34:EF:56:78:90",
+		"openpgp4fpr:ABCD1234EF567890ABCD1234EF567890ABCD1234",
+		"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5 jane@example.com",
+	}
+	if diff := cmp.Diff(want, card.Keys()); diff != "" {
+		t.Errorf("Keys mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := (&HCard{Name: "Jane"}).Keys(); got != nil {
+		t.Errorf("Keys without Source returned %v, want nil", got)
+	}
+}
+
+func Test_HCard_Logos(t *testing.T) {
+	doc := `<div class="h-card">
+		<a class="p-name u-url" href="/">Acme Inc</a>
+		<img class="u-logo" src="/logo.svg" alt="Acme logo">
+		<img class="u-photo" src="/office.jpg" alt="Our office">
+		<a class="u-logo" href="/logo.png">PNG logo</a>
+	</div>`
+	card, err := parseItems(doc)[0].AsHCard()
+	if err != nil {
+		t.Fatalf("AsHCard returned error: %v", err)
+	}
+
+	want := []Photo{
+		{URL: "http://example.com/logo.svg", Alt: "Acme logo"},
+		{URL: "http://example.com/logo.png"},
+	}
+	if diff := cmp.Diff(want, card.Logos()); diff != "" {
+		t.Errorf("Logos mismatch (-want +got):\n%s", diff)
+	}
+	want = []Photo{{URL: "http://example.com/office.jpg", Alt: "Our office"}}
+	if diff := cmp.Diff(want, card.Source.Photos()); diff != "" {
+		t.Errorf("Photos mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := card.Photo, "http://example.com/office.jpg"; got != want {
+		t.Errorf("AsHCard returned Photo %q, want %q", got, want)
+	}
+
+	// a logo alone is not an implied photo
+	card, _ = parseItems(`<div class="h-card"><img class="u-logo" src="/logo.svg" alt="Acme"></div>`)[0].AsHCard()
+	if card.Photo != "" || len(card.Logos()) != 1 {
+		t.Errorf("AsHCard returned Photo %q and logos %v, want only a logo", card.Photo, card.Logos())
+	}
+	if got := (&HCard{}).Logos(); got != nil {
+		t.Errorf("Logos without Source returned %v, want nil", got)
+	}
+}
+func Test_AsHMeasure(t *testing.T) {
+	doc := `<div class="h-recipe"><span class="p-name">Pancakes</span><ul>
+		<li class="p-ingredient h-measure"><data class="p-num" value="2">two</data> <span class="p-unit">cups</span> <span class="p-name">flour</span></li>
+		<li class="p-ingredient h-measure"><span class="p-num">1 1/2</span> <span class="p-unit">tbsp</span> <span class="p-name">sugar</span></li>
+		<li class="p-ingredient h-measure"><span class="p-num"><span class="value">0.</span>,<span class="value">5</span></span> <span class="p-unit">tsp</span> <span class="p-name">salt</span></li>
+		<li class="p-ingredient h-measure"><span class="p-num">3/4</span> <span class="p-name">milk</span></li>
+	</ul></div>`
+
+	want := []*HMeasure{
+		{Num: 2, Unit: "cups", Name: "flour"},
+		{Num: 1.5, Unit: "tbsp", Name: "sugar"},
+		{Num: 0.5, Unit: "tsp", Name: "salt"},
+		{Num: 0.75, Name: "milk"},
+	}
+	items := parseItems(doc)
+	var got []*HMeasure
+	for _, v := range items[0].Properties["ingredient"] {
+		m, err := v.(*Microformat).AsHMeasure()
+		if err != nil {
+			t.Fatalf("AsHMeasure returned error: %v", err)
+		}
+		got = append(got, m)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(HMeasure{}, "Source")); diff != "" {
+		t.Errorf("AsHMeasure mismatch (-want +got):\n%s", diff)
+	}
+
+	errs := []*Microformat{
+		{Type: []string{"h-card"}},
+		{Type: []string{"h-measure"}, Properties: map[string][]any{"unit": {"cups"}}},
+		{Type: []string{"h-measure"}, Properties: map[string][]any{"num": {"a few"}}},
+		{Type: []string{"h-measure"}, Properties: map[string][]any{"num": {"1/0"}}},
+		{Type: []string{"h-measure"}, Properties: map[string][]any{"num": {"1 -1/2"}}},
+	}
+	for _, m := range errs {
+		if _, err := m.AsHMeasure(); err == nil {
+			t.Errorf("AsHMeasure(%v) did not return error", m.Properties)
+		}
+	}
+}
+
+func Test_AsGeo(t *testing.T) {
+	tests := []struct {
+		html string
+		want *HGeo
+	}{
+		{`<div class="h-card"><a class="u-geo" href="geo:37.78,-122.42">SF</a></div>`, &HGeo{Latitude: 37.78, Longitude: -122.42}},
+		{`<div class="h-card"><a class="u-geo" href="GEO:37.78,-122.42,15;u=35">SF</a></div>`, &HGeo{Latitude: 37.78, Longitude: -122.42, Altitude: 15}},
+		{`<div class="h-card"><span class="p-geo h-geo"><span class="p-latitude">37.78</span> <span class="p-longitude">-122.42</span></span></div>`, &HGeo{Latitude: 37.78, Longitude: -122.42}},
+		{`<div class="h-card"><span class="p-latitude">37.78</span> <span class="p-longitude">-122.42</span> <span class="p-altitude">15</span></div>`, &HGeo{Latitude: 37.78, Longitude: -122.42, Altitude: 15}},
+		{`<div class="h-geo"><span class="p-latitude">37.78</span> <span class="p-longitude">-122.42</span></div>`, &HGeo{Latitude: 37.78, Longitude: -122.42}},
+		{`<div class="vcard"><abbr class="geo" title="37.78;-122.42">SF</abbr></div>`, &HGeo{Latitude: 37.78, Longitude: -122.42}},
+	}
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		got, err := items[0].AsGeo()
+		if err != nil {
+			t.Errorf("AsGeo(%q) returned error: %v", tt.html, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreFields(HGeo{}, "Source")); diff != "" {
+			t.Errorf("AsGeo(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+
+	// the raw geo URI is left in the property
+	items := parseItems(`<div class="h-card"><a class="u-geo" href="geo:37.78,-122.42">SF</a></div>`)
+	if got, want := items[0].Properties["geo"], []any{"geo:37.78,-122.42"}; !cmp.Equal(got, want) {
+		t.Errorf("Parse returned geo %v, want %v", got, want)
+	}
+
+	errs := []string{
+		`<div class="h-card"><span class="p-name">Jane</span></div>`,
+		`<div class="h-card"><a class="u-geo" href="geo:37.78">SF</a></div>`,
+		`<div class="h-card"><a class="u-geo" href="geo:91,0">SF</a></div>`,
+		`<div class="h-card"><a class="u-geo" href="geo:NaN,0">SF</a></div>`,
+		`<div class="h-card"><a class="u-geo" href="https://example.com/map">SF</a></div>`,
+		`<div class="h-geo"><span class="p-latitude">north</span> <span class="p-longitude">0</span></div>`,
+	}
+	for _, doc := range errs {
+		if _, err := parseItems(doc)[0].AsGeo(); err == nil {
+			t.Errorf("AsGeo(%q) did not return error", doc)
+		}
+	}
+}
+
+func Test_ParseNumber(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"2", 2},
+		{" 0.25 ", 0.25},
+		{"-3", -3},
+		{"1/4", 0.25},
+		{"2 1/2", 2.5},
+		{"-1 1/2", -1.5},
+	}
+	for _, tt := range tests {
+		got, err := parseNumber(tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("parseNumber(%q) returned %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func Test_AsHRecipe(t *testing.T) {
+	doc := `<article class="h-recipe">
+		<h1 class="p-name">Pancakes</h1>
+		<img class="u-photo" src="/pancakes.jpg" alt="A stack of pancakes">
+		<ul>
+			<li class="p-ingredient">1 egg</li>
+			<li class="p-ingredient h-measure"><span class="p-num">2</span> <span class="p-unit">cups</span> flour</li>
+			<li class="p-ingredient">1 1/2 cups milk</li>
+		</ul>
+		<p>Serves <span class="p-yield">4</span>, ready in
+			<time class="dt-duration" datetime="PT20M">20 minutes</time>.</p>
+		<div class="e-instructions"><ol><li>Mix.</li><li>Fry.</li></ol></div>
+		<span class="p-nutrition">300 calories</span>
+		<a class="p-author h-card" href="/jane">Jane</a>
+	</article>`
+
+	want := &HRecipe{
+		Name:         "Pancakes",
+		Ingredients:  []string{"1 egg", "2 cups flour", "1 1/2 cups milk"},
+		Instructions: "<ol><li>Mix.</li><li>Fry.</li></ol>",
+		Yield:        "4",
+		Duration:     20 * time.Minute,
+		Nutrition:    []string{"300 calories"},
+		Author:       &HCard{Name: "Jane", URL: "http://example.com/jane"},
+		Photo:        []Photo{{URL: "http://example.com/pancakes.jpg", Alt: "A stack of pancakes"}},
+	}
+	items := parseItems(doc)
+	got, err := items[0].AsHRecipe()
+	if err != nil {
+		t.Fatalf("AsHRecipe returned error: %v", err)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(HRecipe{}, "Source"), ignoreSource); diff != "" {
+		t.Errorf("AsHRecipe mismatch (-want +got):\n%s", diff)
+	}
+
+	// durations that are not ISO 8601 are ignored
+	items = parseItems(`<div class="h-recipe"><span class="p-name">Tea</span><span class="dt-duration">5 minutes</span></div>`)
+	if got, err := items[0].AsHRecipe(); err != nil || got.Duration != 0 {
+		t.Errorf("AsHRecipe with text duration returned %v, %v, want 0 duration", got, err)
+	}
+
+	if _, err := (&Microformat{Type: []string{"h-entry"}}).AsHRecipe(); err == nil {
+		t.Errorf("AsHRecipe of h-entry did not return error")
+	}
+}
+
+func Test_AsHProduct(t *testing.T) {
+	doc := `<div class="h-product">
+		<h1 class="p-name">Widget</h1>
+		<img class="u-photo" src="/widget.jpg" alt="A widget">
+		<div class="p-brand h-card"><a class="p-name u-url" href="https://acme.example/">Acme</a></div>
+		<span class="p-category">tools</span> <span class="p-category">gadgets</span>
+		<data class="p-price" value="9.99">$9.99</data>
+		<span class="u-identifier">upc:012345678905</span>
+		<div class="p-review h-review"><span class="p-name">Works well</span>
+			<span class="p-author">Bob</span> gives it <span class="p-rating">4</span> stars</div>
+		<div class="e-description"><p>A <b>useful</b> widget.</p></div>
+	</div>`
+
+	items := parseItems(doc)
+	got, err := items[0].AsHProduct()
+	if err != nil {
+		t.Fatalf("AsHProduct returned error: %v", err)
+	}
+	want := &HProduct{
+		Name:       "Widget",
+		Photo:      []Photo{{URL: "http://example.com/widget.jpg", Alt: "A widget"}},
+		Brand:      &HCard{Name: "Acme", URL: "https://acme.example/"},
+		Category:   []string{"tools", "gadgets"},
+		Price:      "9.99",
+		Identifier: []string{"upc:012345678905"},
+		Review: &HReview{
+			Name:   "Works well",
+			Author: &HCard{Name: "Bob"},
+			Rating: 4,
+			Best:   5,
+			Worst:  1,
+		},
+		Description: "<p>A <b>useful</b> widget.</p>",
+	}
+	opts := cmp.Options{ignoreSource, cmpopts.IgnoreFields(HProduct{}, "Source"), cmpopts.IgnoreFields(HReview{}, "Source")}
+	if diff := cmp.Diff(want, got, opts); diff != "" {
+		t.Errorf("AsHProduct mismatch (-want +got):\n%s", diff)
+	}
+	if got.Source != items[0] || got.Brand.Source == nil || got.Review.Source == nil {
+		t.Errorf("AsHProduct did not set Source")
+	}
+
+	// a plain brand is used as its name
+	items = parseItems(`<div class="h-product"><span class="p-name">Widget</span> by <span class="p-brand">Acme</span></div>`)
+	if got, err := items[0].AsHProduct(); err != nil || got.Brand == nil || got.Brand.Name != "Acme" || got.Review != nil {
+		t.Errorf("AsHProduct with plain brand returned %+v, %v, want brand Acme and no review", got, err)
+	}
+
+	items = parseItems(`<div class="h-product"><div class="p-review h-review"><span class="p-rating">great</span></div></div>`)
+	if _, err := items[0].AsHProduct(); err == nil {
+		t.Errorf("AsHProduct with invalid review rating did not return error")
+	}
+	if _, err := (&Microformat{Type: []string{"h-entry"}}).AsHProduct(); err == nil {
+		t.Errorf("AsHProduct of h-entry did not return error")
+	}
+}
+
+func Test_AsHReview(t *testing.T) {
+	tests := []struct {
+		html string
+		want *HReview
+	}{
+		{
+			`<div class="h-review">
+				<span class="p-name">Great place</span>
+				<div class="p-item h-card"><span class="p-name">Cafe</span></div>
+				<span class="p-author h-card">Jane</span>
+				<data class="p-rating" value="4">four</data> out of <span class="p-best">10</span>
+				<div class="e-content">Good <b>coffee</b></div>
+			</div>`,
+			&HReview{
+				Name:    "Great place",
+				Author:  &HCard{Name: "Jane"},
+				Content: "Good <b>coffee</b>",
+				Rating:  4,
+				Best:    10,
+				Worst:   1,
+			},
+		},
+		{
+			`<div class="h-review-aggregate"><span class="p-name">Cafe</span>
+				<span class="p-rating"><span class="value">4</span>.<span class="value">.5</span></span>
+				<span class="p-worst">0</span></div>`,
+			&HReview{Name: "Cafe", Rating: 4.5, Best: 5, Worst: 0},
+		},
+		{
+			// v1 hreview
+			`<div class="hreview"><span class="summary">Nice</span><span class="rating">3</span></div>`,
+			&HReview{Name: "Nice", Rating: 3, Best: 5, Worst: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		items := parseItems(tt.html)
+		got, err := items[0].AsHReview()
+		if err != nil {
+			t.Fatalf("AsHReview(%q) returned error: %v", tt.html, err)
+		}
+		opts := cmp.Options{
+			cmpopts.IgnoreFields(HReview{}, "Source", "Item"),
+			ignoreSource,
+		}
+		if diff := cmp.Diff(tt.want, got, opts); diff != "" {
+			t.Errorf("AsHReview(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+
+	items := parseItems(`<div class="h-review"><div class="p-item h-product"><span class="p-name">Thing</span></div></div>`)
+	review, err := items[0].AsHReview()
+	if err != nil {
+		t.Fatalf("AsHReview returned error: %v", err)
+	}
+	if review.Item == nil || !review.Item.hasType("h-product") {
+		t.Errorf("AsHReview returned item %v, want h-product", review.Item)
+	}
+
+	bad := &Microformat{Type: []string{"h-review"}, Properties: map[string][]any{"rating": {"great"}}}
+	if _, err := bad.AsHReview(); err == nil {
+		t.Errorf("AsHReview with non-numeric rating did not return error")
+	}
+	if _, err := (&Microformat{Type: []string{"h-entry"}}).AsHReview(); err == nil {
+		t.Errorf("AsHReview on h-entry did not return error")
+	}
+}
+
+func Test_AsHAdr(t *testing.T) {
+	items := parseItems(`<p class="h-adr"><span class="p-street-address">17 Austerstræti</span>
+		<span class="p-locality">Reykjavík</span> <span class="p-country-name">Iceland</span>
+		<span class="p-postal-code">107</span></p>`)
+	want := &HAdr{
+		StreetAddress: "17 Austerstræti",
+		Locality:      "Reykjavík",
+		PostalCode:    "107",
+		CountryName:   "Iceland",
+	}
+	got, err := items[0].AsHAdr()
+	if err != nil {
+		t.Fatalf("AsHAdr returned error: %v", err)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(HAdr{}, "Source")); diff != "" {
+		t.Errorf("AsHAdr mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := (&Microformat{Type: []string{"h-card"}}).AsHAdr(); err == nil {
+		t.Errorf("AsHAdr of h-card did not return error")
+	}
+}
+
+func Test_Location(t *testing.T) {
+	tests := []struct {
+		html string
+		want *Location
+	}{
+		{`<div class="h-event"><span class="p-name">Party</span></div>`, nil},
+		{
+			`<div class="h-event"><span class="p-location">The Park</span></div>`,
+			&Location{Name: "The Park"},
+		},
+		{
+			`<div class="h-event"><a class="u-location" href="https://venue.example/">venue</a></div>`,
+			&Location{URL: "https://venue.example/"},
+		},
+		{
+			`<div class="h-event"><div class="p-location h-card"><a class="p-name u-url" href="https://venue.example/">Venue</a>
+				<span class="p-locality">Portland</span></div></div>`,
+			&Location{
+				Name: "Venue",
+				URL:  "https://venue.example/",
+				Card: &HCard{Name: "Venue", URL: "https://venue.example/"},
+				Adr:  &HAdr{Name: "Venue", Locality: "Portland"},
+			},
+		},
+		{
+			`<div class="h-entry"><div class="p-location h-card"><span class="p-name">Cafe</span>
+				<div class="p-adr h-adr"><span class="p-locality">Portland</span>
+				<span class="p-geo h-geo"><data class="p-latitude" value="45.5">here</data><data class="p-longitude" value="-122.6"></data></span></div></div></div>`,
+			&Location{
+				Name: "Cafe",
+				Card: &HCard{Name: "Cafe"},
+				Adr:  &HAdr{Locality: "Portland"},
+				Geo:  &HGeo{Latitude: 45.5, Longitude: -122.6},
+			},
+		},
+		{
+			`<div class="h-event"><p class="p-location h-adr"><span class="p-street-address">1 Main St</span> <span class="p-latitude">45.5</span> <span class="p-longitude">-122.6</span></p></div>`,
+			&Location{
+				Name: "1 Main St 45.5 -122.6",
+				Adr:  &HAdr{StreetAddress: "1 Main St"},
+				Geo:  &HGeo{Latitude: 45.5, Longitude: -122.6},
+			},
+		},
+		{
+			`<div class="h-entry"><a class="p-location h-geo" href="geo:45.5,-122.6"><span class="p-latitude">45.5</span>,<span class="p-longitude">-122.6</span></a></div>`,
+			&Location{
+				Name: "45.5,-122.6",
+				URL:  "geo:45.5,-122.6",
+				Geo:  &HGeo{Latitude: 45.5, Longitude: -122.6},
+			},
+		},
+	}
+
+	ignore := cmpopts.IgnoreFields(HCard{}, "Source")
+	for _, tt := range tests {
+		got := parseItems(tt.html)[0].Location()
+		if diff := cmp.Diff(tt.want, got, ignore, cmpopts.IgnoreFields(HAdr{}, "Source"), cmpopts.IgnoreFields(HGeo{}, "Source")); diff != "" {
+			t.Errorf("Location(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}
+
+func Test_HCard_StructuredName(t *testing.T) {
+	tests := []struct {
+		html string
+		want Name
+	}{
+		{`<div class="h-card">Jane</div>`, Name{}},
+		{
+			`<div class="h-card"><span class="p-name"><span class="p-honorific-prefix">Dr</span> <span class="p-given-name">Jane</span>
+				<span class="p-additional-name">Q</span> <span class="p-family-name">Doe</span>, <span class="p-honorific-suffix">PhD</span></span></div>`,
+			Name{HonorificPrefix: "Dr", GivenName: "Jane", AdditionalName: "Q", FamilyName: "Doe", HonorificSuffix: "PhD"},
+		},
+		{
+			`<div class="h-card"><span class="p-name">Jane Doe</span>
+				<span class="p-n h-card"><span class="p-given-name">Jane</span> <span class="p-family-name">Doe</span></span></div>`,
+			Name{GivenName: "Jane", FamilyName: "Doe"},
+		},
+		{
+			`<div class="h-card"><span class="p-name h-card"><span class="p-given-name">Jane</span> <span class="p-family-name">Doe</span></span>
+				<span class="p-family-name">Smith</span></div>`,
+			Name{GivenName: "Jane", FamilyName: "Smith"},
+		},
+		{
+			`<div class="vcard"><span class="fn n"><span class="given-name">Jane</span> <span class="family-name">Doe</span></span></div>`,
+			Name{GivenName: "Jane", FamilyName: "Doe"},
+		},
+		{
+			`<div class="vcard"><span class="fn">Dr Jane Doe</span>
+				<span class="n"><span class="honorific-prefix">Dr</span> <span class="given-name">Jane</span> <span class="family-name">Doe</span></span></div>`,
+			Name{HonorificPrefix: "Dr", GivenName: "Jane", FamilyName: "Doe"},
+		},
+	}
+
+	for _, tt := range tests {
+		card, err := parseItems(tt.html)[0].AsHCard()
+		if err != nil {
+			t.Fatalf("AsHCard returned error: %v", err)
+		}
+		if diff := cmp.Diff(tt.want, card.StructuredName()); diff != "" {
+			t.Errorf("StructuredName(%q) mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+
+	if got := (&HCard{Name: "Jane"}).StructuredName(); got != (Name{}) {
+		t.Errorf("StructuredName of HCard without Source returned %v, want empty Name", got)
+	}
+}
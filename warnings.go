@@ -0,0 +1,64 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for reporting problems found while parsing.
+
+package microformats
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"golang.org/x/net/html"
+)
+
+// Warning describes a problem found while parsing a document, such as
+// suspicious markup, or a property value that was modified or dropped due to
+// a parsing option.  Warnings never prevent a document from being parsed.
+type Warning struct {
+	Message string
+
+	// Line and Column are the position in the document source of the
+	// element the warning is about, starting at 1, if parsed with
+	// WithPositions.  They are 0 if the position is not known.
+	Line, Column int
+}
+
+// String returns the message of w, preceded by its position if known, such as
+// "12:5: truncated p-name value of 300 bytes to 100 bytes".
+func (w Warning) String() string {
+	if w.Line == 0 {
+		return w.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", w.Line, w.Column, w.Message)
+}
+
+// ParseWithWarnings parses the microformats found in the HTML document read
+// from r, the same as Parse, and additionally returns any warnings about the
+// document found while parsing it.
+func ParseWithWarnings(r io.Reader, baseURL *url.URL, opts ...Option) (*Data, []Warning) {
+	// read errors are handled by ParseWithError, and otherwise ignored so
+	// that a partial document is still parsed.
+	if _, ok := r.(*errReader); !ok {
+		r = &errReader{r: r}
+	}
+	doc, positions := parseDocument(r, opts)
+	if doc == nil {
+		return nil, nil
+	}
+	p := newParser(doc, baseURL, opts)
+	p.positions = positions
+	p.parse(doc)
+	return p.curData, p.warnings
+}
+
+// warn records a warning about node with a message formatted according to
+// format.  node may be nil if the warning is not about any element.
+func (p *parser) warn(node *html.Node, format string, args ...any) {
+	w := Warning{Message: fmt.Sprintf(format, args...)}
+	if pos, ok := p.positions[node]; ok {
+		w.Line, w.Column = pos.line, pos.column
+	}
+	p.warnings = append(p.warnings, w)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+// This file includes support for parsing XHTML documents.
+
+package microformats
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// XML namespaces of elements and attributes that have an equivalent in the
+// HTML parsing model.
+const (
+	nsXHTML  = "http://www.w3.org/1999/xhtml"
+	nsSVG    = "http://www.w3.org/2000/svg"
+	nsMathML = "http://www.w3.org/1998/Math/MathML"
+	nsXLink  = "http://www.w3.org/1999/xlink"
+	nsXML    = "http://www.w3.org/XML/1998/namespace"
+)
+
+// WithXHTML parses documents as XHTML, such as those served with the
+// application/xhtml+xml media type, rather than as HTML.  This only affects
+// Parse; ParseNode is given an already parsed document.
+//
+// Documents are read as XML, so element and attribute names are case
+// sensitive, and self-closing tags such as <span class="p-name"/> are
+// honored for all elements.  Elements in the XHTML namespace (or no
+// namespace) are treated as HTML elements, and elements in the SVG and
+// MathML namespaces as foreign content.  HTML named character references such
+// as &nbsp; are recognized, and any DTD is ignored.  If the document is not
+// well-formed XML, it is parsed as HTML instead.
+//
+// The xml:base attribute is honored for relative URLs within the element
+// it is on, including in inline SVG in HTML documents: it is resolved against
+// the base URL of its parent element, which for the outermost xml:base is the
+// document's base URL (the <base href> of the document, if any, or else the
+// URL provided to Parse).  This applies whether or not WithXHTML is used.
+func WithXHTML() Option {
+	return func(p *parser) {
+		p.xhtml = true
+	}
+}
+
+// xmlBase returns the base URL for node and its descendants, if node has an
+// xml:base attribute, resolved against the current base URL.
+func (p *parser) xmlBase(node *html.Node) (*url.URL, bool) {
+	if node.Type != html.ElementNode {
+		return nil, false
+	}
+	for _, attr := range node.Attr {
+		if attr.Namespace == "xml" && attr.Key == "base" {
+			u, err := url.Parse(strings.TrimSpace(attr.Val))
+			if err != nil {
+				return nil, false
+			}
+			return p.base.ResolveReference(u), true
+		}
+	}
+	return nil, false
+}
+
+// parseXHTML parses the XHTML document read from r.  If the document cannot
+// be parsed as XML, it is parsed as HTML with opts.
+func parseXHTML(r io.Reader, opts []html.ParseOption) (*html.Node, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if doc, err := parseXML(bytes.NewReader(b)); err == nil {
+		return doc, nil
+	}
+	return html.ParseWithOptions(bytes.NewReader(b), opts...)
+}
+
+// parseXML parses the XML document read from r into a tree of html.Nodes.
+func parseXML(r io.Reader) (*html.Node, error) {
+	d := xml.NewDecoder(r)
+	d.Entity = xml.HTMLEntity
+
+	doc := &html.Node{Type: html.DocumentNode}
+	cur := doc
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &html.Node{
+				Type:      html.ElementNode,
+				Data:      tok.Name.Local,
+				Namespace: elementNamespace(tok.Name.Space),
+				Attr:      xmlAttrs(tok.Attr),
+			}
+			if n.Namespace == "" {
+				n.DataAtom = atom.Lookup([]byte(n.Data))
+			} else {
+				n.DataAtom = atom.Lookup([]byte(strings.ToLower(n.Data)))
+			}
+			cur.AppendChild(n)
+			cur = n
+		case xml.EndElement:
+			cur = cur.Parent
+		case xml.CharData:
+			cur.AppendChild(&html.Node{Type: html.TextNode, Data: string(tok)})
+		case xml.Comment:
+			cur.AppendChild(&html.Node{Type: html.CommentNode, Data: string(tok)})
+		case xml.ProcInst, xml.Directive:
+			// nothing to do
+		}
+	}
+	return doc, nil
+}
+
+// elementNamespace returns the html.Node namespace for an element in the XML
+// namespace space.  Unknown namespaces are kept as-is.
+func elementNamespace(space string) string {
+	switch space {
+	case "", nsXHTML:
+		return ""
+	case nsSVG:
+		return "svg"
+	case nsMathML:
+		return "math"
+	}
+	return space
+}
+
+// xmlAttrs converts XML attributes to html.Attributes, omitting namespace
+// declarations.
+func xmlAttrs(attrs []xml.Attr) []html.Attribute {
+	var a []html.Attribute
+	for _, attr := range attrs {
+		var ns string
+		switch attr.Name.Space {
+		case "":
+			if attr.Name.Local == "xmlns" {
+				continue
+			}
+		case "xmlns":
+			continue
+		case nsXLink:
+			ns = "xlink"
+		case nsXML:
+			ns = "xml"
+		default:
+			ns = attr.Name.Space
+		}
+		a = append(a, html.Attribute{Namespace: ns, Key: attr.Name.Local, Val: attr.Value})
+	}
+	return a
+}
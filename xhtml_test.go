@@ -0,0 +1,140 @@
+// Copyright (c) 2015 Andy Leap, Google
+// SPDX-License-Identifier: MIT
+
+package microformats
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_WithXHTML(t *testing.T) {
+	tests := []struct {
+		description string
+		doc         string
+		want        []*Microformat
+	}{
+		{
+			"h-card with self-closing elements",
+			`<?xml version="1.0" encoding="UTF-8"?>
+			<!DOCTYPE html>
+			<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="en">
+			<head><base href="/people/"/></head>
+			<body>
+				<div class="h-card">
+					<span class="p-nickname"/>
+					<a class="p-name u-url" href="jane">Jane&nbsp;Doe</a>
+					<img class="u-photo" src="jane.png" alt=""/>
+					<!-- a comment -->
+				</div>
+			</body>
+			</html>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"nickname": {""},
+					"name":     {"Jane Doe"},
+					"url":      {"http://example.com/people/jane"},
+					"photo":    {"http://example.com/people/jane.png"},
+				},
+			}},
+		},
+		{
+			"foreign content with xlink",
+			`<div xmlns="http://www.w3.org/1999/xhtml" xmlns:svg="http://www.w3.org/2000/svg"
+				xmlns:xlink="http://www.w3.org/1999/xlink" class="h-card">
+				<svg:svg><svg:a class="u-url" xlink:href="/jane"><svg:text class="p-name">Jane</svg:text></svg:a></svg:svg>
+			</div>`,
+			[]*Microformat{{
+				Type: []string{"h-card"},
+				Properties: map[string][]any{
+					"name": {"Jane"},
+					"url":  {"http://example.com/jane"},
+				},
+			}},
+		},
+		{
+			"not well-formed falls back to HTML",
+			`<div class="h-card"><span class="p-name">Jane<br></span></div>`,
+			[]*Microformat{{
+				Type:       []string{"h-card"},
+				Properties: map[string][]any{"name": {"Jane"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		got := parseItemsWith(tt.doc, WithXHTML())
+		if diff := cmp.Diff(tt.want, got, ignoreParseState); diff != "" {
+			t.Errorf("%s: Parse mismatch (-want +got):\n%s", tt.description, diff)
+		}
+	}
+}
+
+func Test_WithXHTML_SelfClosing(t *testing.T) {
+	// as HTML, the self-closing span contains the following link
+	doc := `<div class="h-card"><span class="p-name"/><a class="u-url" href="/jane">Jane</a></div>`
+
+	tests := []struct {
+		opts []Option
+		want map[string][]any
+	}{
+		{nil, map[string][]any{"name": {"Jane"}, "url": {"http://example.com/jane"}}},
+		{[]Option{WithXHTML()}, map[string][]any{"name": {""}, "url": {"http://example.com/jane"}}},
+	}
+
+	for _, tt := range tests {
+		items := parseItemsWith(doc, tt.opts...)
+		if diff := cmp.Diff(tt.want, items[0].Properties); diff != "" {
+			t.Errorf("Parse with %d options mismatch (-want +got):\n%s", len(tt.opts), diff)
+		}
+	}
+}
+
+func Test_Parse_XMLBase(t *testing.T) {
+	tests := []struct {
+		html string
+		opts []Option
+		want []any
+	}{
+		{
+			// xml:base on XHTML elements, resolved against <base href>
+			`<html xmlns="http://www.w3.org/1999/xhtml"><head><base href="/site/"/></head><body>
+				<div xml:base="posts/"><div class="h-entry" xml:base="1/">
+					<a class="u-url" href="page">post</a>
+				</div><a class="h-card" href="jane">Jane</a></div>
+				<a class="h-card" href="home">home</a>
+			</body></html>`,
+			[]Option{WithXHTML()},
+			[]any{
+				"http://example.com/site/posts/1/page",
+				"http://example.com/site/posts/jane",
+				"http://example.com/site/home",
+			},
+		},
+		{
+			// xml:base in inline SVG within an HTML document
+			`<svg xml:base="https://img.example/icons/"><a class="h-card" href="jane.svg"><text>Jane</text></a></svg>
+			<a class="h-card" href="jane">Jane</a>`,
+			nil,
+			[]any{"https://img.example/icons/jane.svg", "http://example.com/jane"},
+		},
+		{
+			// xml:base has no effect on HTML elements in HTML documents
+			`<div xml:base="https://other.example/"><a class="h-card" href="jane">Jane</a></div>`,
+			nil,
+			[]any{"http://example.com/jane"},
+		},
+	}
+
+	for _, tt := range tests {
+		var got []any
+		for _, item := range parseItemsWith(tt.html, tt.opts...) {
+			got = append(got, item.Properties["url"]...)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Parse(%q) urls mismatch (-want +got):\n%s", tt.html, diff)
+		}
+	}
+}